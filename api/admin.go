@@ -0,0 +1,142 @@
+package api
+
+import (
+	"singo/serializer"
+	"singo/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminConnections 列出所有WebSocket连接及其诊断信息
+func AdminConnections(c *gin.Context) {
+	connections := service.GetWebSocketManager().Connections()
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"connections": connections,
+			"total":       len(connections),
+		},
+	})
+}
+
+// AdminUpdateUserRole 修改用户角色
+func AdminUpdateUserRole(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		c.JSON(200, serializer.CheckLogin())
+		return
+	}
+
+	var service service.UserRoleService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.Update(user, c.Param("wallet"))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminRoleAudit 查询权限变更记录
+func AdminRoleAudit(c *gin.Context) {
+	var service service.RoleAuditService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.List()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListAPIKeys 列出API密钥
+func AdminListAPIKeys(c *gin.Context) {
+	c.JSON(200, service.ListAPIKeys())
+}
+
+// AdminCreateAPIKey 创建API密钥
+func AdminCreateAPIKey(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		c.JSON(200, serializer.CheckLogin())
+		return
+	}
+
+	var service service.APIKeyCreateService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.Create(user)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminRevokeAPIKey 吊销API密钥
+func AdminRevokeAPIKey(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		c.JSON(200, serializer.CheckLogin())
+		return
+	}
+
+	c.JSON(200, service.RevokeAPIKey(user, c.Param("id")))
+}
+
+// AdminReconcileLedger 核对用户奖励余额与账本
+func AdminReconcileLedger(c *gin.Context) {
+	var service service.LedgerReconcileService
+	c.JSON(200, service.Reconcile())
+}
+
+// AdminTransitionPool 切换奖池状态
+func AdminTransitionPool(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		c.JSON(200, serializer.CheckLogin())
+		return
+	}
+
+	var service service.PoolTransitionService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.Transition(user, c.Param("id"))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminPoolHistory 查询奖池的状态变更记录
+func AdminPoolHistory(c *gin.Context) {
+	c.JSON(200, service.GetPoolService().PoolHistory(c.Param("id")))
+}
+
+// AdminPoolTimeline 查询奖池的事件时间线与重放后的状态
+func AdminPoolTimeline(c *gin.Context) {
+	var service service.PoolTimelineService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.Timeline(c.Param("id"))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListJobs 查看延迟任务队列，默认列出死信
+func AdminListJobs(c *gin.Context) {
+	var service service.JobListService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.List()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminRequeueJob 将死信任务重新排队
+func AdminRequeueJob(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		c.JSON(200, serializer.CheckLogin())
+		return
+	}
+
+	c.JSON(200, service.RequeueJob(user, c.Param("id")))
+}
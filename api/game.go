@@ -2,23 +2,32 @@ package api
 
 import (
 	"net/http"
+	"singo/middleware"
+	"singo/model"
+	"singo/serializer"
 	"singo/service"
 
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"net"
+	"strconv"
 	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/gorilla/websocket"
 )
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
-		return true // 允许所有来源，生产环境应该限制
+		return middleware.WebSocketOriginAllowed(r.Header.Get("Origin"))
 	},
 	HandshakeTimeout: 10 * time.Second,
-	ReadBufferSize:   1024,
-	WriteBufferSize:  1024,
+	ReadBufferSize:   4096,
+	WriteBufferSize:  4096,
 }
 
 // GameActivate 激活青蛙（开始游戏）
@@ -31,6 +40,7 @@ func GameActivate(c *gin.Context) {
 
 	var service service.GameActivateService
 	if err := c.ShouldBind(&service); err == nil {
+		service.IdempotencyKey = c.GetHeader("Idempotency-Key")
 		res := service.Activate(c, user)
 		c.JSON(200, res)
 	} else {
@@ -72,6 +82,25 @@ func CatchBigPrize(c *gin.Context) {
 	}
 }
 
+// WebSocketTicket 用访问令牌换取一次性WebSocket（或SSE）连接票据
+// 票据绑定当前用户与请求来源，约30秒内有效，连接时通过 ?ticket= 传递
+func WebSocketTicket(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		c.JSON(200, ErrorResponse(nil))
+		return
+	}
+
+	sessionID := ""
+	if claims := CurrentClaims(c); claims != nil {
+		sessionID = claims.SessionID
+	}
+
+	var service service.WSTicketService
+	res := service.Issue(user, sessionID, c.GetHeader("Origin"))
+	c.JSON(200, res)
+}
+
 // WebSocketHandler 处理WebSocket连接
 func WebSocketHandler(c *gin.Context) {
 	// 在升级之前不要写入任何响应头或状态码
@@ -91,13 +120,6 @@ func WebSocketHandler(c *gin.Context) {
 		return
 	}
 
-	// 配置upgrader
-	upgrader.CheckOrigin = func(r *http.Request) bool {
-		return true // 在生产环境中应该根据实际情况配置
-	}
-	upgrader.ReadBufferSize = 4096
-	upgrader.WriteBufferSize = 4096
-
 	// 直接升级连接，不要设置任何响应头
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -108,7 +130,9 @@ func WebSocketHandler(c *gin.Context) {
 	log.Printf("用户 %d WebSocket连接升级成功", user.ID)
 
 	// 注册WebSocket连接
-	if err := service.GetWebSocketManager().RegisterClient(user.ID, conn); err != nil {
+	manager := service.GetWebSocketManager()
+	client, err := manager.RegisterClient(user.ID, user.WalletAddress, c.GetString("session_id"), conn)
+	if err != nil {
 		log.Printf("用户 %d 注册WebSocket客户端失败: %v", user.ID, err)
 		conn.Close()
 		return
@@ -116,27 +140,28 @@ func WebSocketHandler(c *gin.Context) {
 
 	log.Printf("用户 %d WebSocket客户端注册成功", user.ID)
 
-	// 处理连接关闭
+	// 处理连接关闭，默认以正常关闭码结束
+	closeCode, closeReason := websocket.CloseNormalClosure, ""
 	defer func() {
 		log.Printf("用户 %d WebSocket连接准备关闭", user.ID)
-		service.GetWebSocketManager().UnregisterClient(user.ID)
-		conn.Close()
+		manager.UnregisterClient(client, closeCode, closeReason)
 	}()
 
-	// 保持连接并处理消息
+	// 保持连接并处理消息，读超时由心跳（pong）与收到的消息延长
 	for {
-		// 设置较长的读取超时时间
-		conn.SetReadDeadline(time.Now().Add(120 * time.Second))
-
 		messageType, message, err := conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				log.Printf("用户 %d WebSocket心跳超时", user.ID)
+				closeCode, closeReason = service.CloseHeartbeatTimeout, "heartbeat timeout"
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("用户 %d WebSocket读取消息错误: %v", user.ID, err)
 			} else {
 				log.Printf("用户 %d WebSocket连接正常关闭", user.ID)
 			}
 			break
 		}
+		client.ExtendReadDeadline()
 
 		// 处理消息
 		if messageType == websocket.TextMessage {
@@ -144,13 +169,20 @@ func WebSocketHandler(c *gin.Context) {
 			if err := json.Unmarshal(message, &msg); err == nil {
 				log.Printf("用户 %d 收到消息类型: %v", user.ID, msg["type"])
 				if msg["type"] == "ping" {
-					// 设置写入超时并发送pong响应
-					conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-					if err := service.GetWebSocketManager().HandlePing(conn); err != nil {
+					// 兼容应用层的ping消息
+					if err := manager.HandlePing(client); err != nil {
 						log.Printf("用户 %d 处理ping消息失败: %v", user.ID, err)
 						break
 					}
 					log.Printf("用户 %d 成功响应ping消息", user.ID)
+				} else if msgType, ok := msg["type"].(string); ok {
+					// 处理游戏指令（feed、catch-prize）
+					requestID, _ := msg["requestId"].(string)
+					res := handleWebSocketCommand(user, msgType, requestID, message)
+					if err := manager.SendCommandResult(client, requestID, res); err != nil {
+						log.Printf("用户 %d 发送指令 %s 结果失败: %v", user.ID, msgType, err)
+						break
+					}
 				}
 			} else {
 				log.Printf("用户 %d 解析消息失败: %v", user.ID, err)
@@ -158,3 +190,104 @@ func WebSocketHandler(c *gin.Context) {
 		}
 	}
 }
+
+// GameEvents 以Server-Sent Events推送游戏事件，作为WebSocket的降级方案
+// 浏览器的EventSource无法携带Authorization头，通过 ?ticket= 传递一次性连接票据；
+// 票据使用后即失效，断线后需换取新票据，并以 ?lastEventId= 续传
+func GameEvents(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		c.JSON(200, ErrorResponse(nil))
+		return
+	}
+
+	// 支持标准的Last-Event-ID请求头，也允许通过查询参数传递
+	lastEventIDStr := c.GetHeader("Last-Event-ID")
+	if lastEventIDStr == "" {
+		lastEventIDStr = c.Query("lastEventId")
+	}
+	lastEventID, _ := strconv.ParseUint(lastEventIDStr, 10, 64)
+
+	manager := service.GetWebSocketManager()
+	stream, backlog, resumed := manager.SubscribeStream(user.ID, lastEventID)
+	defer manager.UnsubscribeStream(stream)
+
+	// 无法续传时先发送完整的初始状态
+	if !resumed {
+		initial, err := manager.InitialStreamEvents(user.ID)
+		if err != nil {
+			log.Printf("用户 %d 获取SSE初始状态失败: %v", user.ID, err)
+		}
+		backlog = append(initial, backlog...)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	for _, e := range backlog {
+		renderStreamEvent(c, e)
+	}
+	c.Writer.Flush()
+
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-stream.Events:
+			if !ok {
+				return false
+			}
+			renderStreamEvent(c, e)
+			return true
+		case <-keepalive.C:
+			// SSE注释行，防止代理因空闲断开连接
+			_, err := io.WriteString(w, ": keepalive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+
+	log.Printf("用户 %d 的SSE连接已关闭", user.ID)
+}
+
+// renderStreamEvent 写入一条SSE事件
+func renderStreamEvent(c *gin.Context, e service.StreamEvent) {
+	event := sse.Event{
+		Event: e.Type,
+		Data:  e.Data,
+	}
+	// 初始状态事件没有ID，不影响客户端记录的Last-Event-ID
+	if e.ID > 0 {
+		event.Id = strconv.FormatUint(e.ID, 10)
+	}
+	c.Render(-1, event)
+}
+
+// handleWebSocketCommand 处理通过WebSocket发送的游戏指令
+// 指令参数与对应HTTP接口使用相同的绑定与校验规则
+func handleWebSocketCommand(user *model.User, msgType string, requestID string, message []byte) serializer.Response {
+	if requestID == "" {
+		return serializer.ParamErr("requestId is required", nil)
+	}
+
+	switch msgType {
+	case "feed":
+		var service service.GameHungerService
+		if err := binding.JSON.BindBody(message, &service); err != nil {
+			return ErrorResponse(err)
+		}
+		return service.UpdateHunger(nil, user)
+	case "catch-prize":
+		var service service.GameCatchPrizeService
+		if err := binding.JSON.BindBody(message, &service); err != nil {
+			return ErrorResponse(err)
+		}
+		return service.CatchBigPrize(nil, user)
+	default:
+		return serializer.ParamErr(fmt.Sprintf("Unknown message type: %s", msgType), nil)
+	}
+}
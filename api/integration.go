@@ -0,0 +1,29 @@
+package api
+
+import (
+	"singo/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntegrationPools 服务端集成查询奖池
+func IntegrationPools(c *gin.Context) {
+	var service service.IntegrationPoolsService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.List()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// IntegrationLeaderboard 服务端集成查询排行榜
+func IntegrationLeaderboard(c *gin.Context) {
+	var service service.IntegrationLeaderboardService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.List()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
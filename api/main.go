@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"singo/auth"
 	"singo/conf"
 	"singo/model"
 	"singo/serializer"
@@ -29,6 +30,26 @@ func CurrentUser(c *gin.Context) *model.User {
 	return nil
 }
 
+// CurrentClaims 获取当前访问令牌的声明
+func CurrentClaims(c *gin.Context) *auth.Claims {
+	if claims, _ := c.Get("claims"); claims != nil {
+		if cl, ok := claims.(*auth.Claims); ok {
+			return cl
+		}
+	}
+	return nil
+}
+
+// CurrentPrincipal 获取通过API密钥认证的服务主体
+func CurrentPrincipal(c *gin.Context) *auth.ServicePrincipal {
+	if principal, _ := c.Get("principal"); principal != nil {
+		if p, ok := principal.(*auth.ServicePrincipal); ok {
+			return p
+		}
+	}
+	return nil
+}
+
 // ErrorResponse 返回错误消息
 func ErrorResponse(err error) serializer.Response {
 	if ve, ok := err.(validator.ValidationErrors); ok {
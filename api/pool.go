@@ -1,10 +1,16 @@
 package api
 
 import (
-	"singo/model"
-	"singo/serializer"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"singo/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
 // GetCurrentPool 获取当前奖池状态
@@ -15,53 +21,101 @@ func GetCurrentPool(c *gin.Context) {
 		return
 	}
 
-	// 获取用户当前参与的奖池
-	var pool model.PrizePool
-
-	// 查找用户最近参与的未完成的奖池
-	result := model.DB.Joins("JOIN pool_participants ON pool_participants.pool_id = prize_pools.id").
-		Where("pool_participants.user_id = ? AND prize_pools.status != ?", user.ID, model.PoolStatusCompleted).
-		Order("prize_pools.created_at DESC").
-		First(&pool)
-
-	if result.Error != nil {
-		c.JSON(200, serializer.Response{
-			Code: 0,
-			Data: gin.H{
-				"pool":         nil,
-				"participants": []interface{}{},
-			},
-		})
+	c.JSON(200, service.GetPoolService().CurrentPool(user))
+}
+
+// PoolSnapshot 获取奖池的公开只读快照（无需登录）
+func PoolSnapshot(c *gin.Context) {
+	var service service.PoolSpectateService
+	if err := c.ShouldBindUri(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	c.JSON(200, service.Snapshot())
+}
+
+// SpectatePool 以只读WebSocket观战奖池（无需登录）
+func SpectatePool(c *gin.Context) {
+	var spectate service.PoolSpectateService
+	if err := c.ShouldBindUri(&spectate); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
 
-	// 获取奖池所有参与者
-	participants, err := model.GetParticipantsByPoolID(pool.ID)
+	// 检查是否是WebSocket升级请求
+	if c.GetHeader("Upgrade") != "websocket" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	pool, err := spectate.LivePool()
 	if err != nil {
-		c.JSON(200, serializer.DBErr("Failed to get participants", err))
+		if errors.Is(err, service.ErrPoolNotLive) {
+			c.AbortWithStatus(http.StatusNotFound)
+		} else {
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}
 		return
 	}
 
-	// 构建参与者信息
-	var participantsData []gin.H
-	for _, p := range participants {
-		participantsData = append(participantsData, gin.H{
-			"walletAddress":  p.WalletAddress,
-			"serialNumber":   p.SerialNumber,
-			"canSeeBigPrize": p.WalletAddress == pool.CurrentBigPrizeHolder,
-		})
+	manager := service.GetWebSocketManager()
+
+	// 升级前先检查人数，避免无谓的握手
+	if manager.SpectatorCount(pool.ID) >= service.SpectatorLimit() {
+		c.AbortWithStatus(http.StatusTooManyRequests)
+		return
 	}
 
-	c.JSON(200, serializer.Response{
-		Code: 0,
-		Data: gin.H{
-			"pool": gin.H{
-				"id":             pool.ID,
-				"status":         pool.Status,
-				"currentPlayers": pool.CurrentPlayers,
-				"prizeAmount":    pool.PrizeAmount,
-			},
-			"participants": participantsData,
-		},
-	})
+	snapshot, err := spectate.SnapshotMessages(pool)
+	if err != nil {
+		log.Printf("获取奖池 %d 观战快照失败: %v", pool.ID, err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("奖池 %d 观战WebSocket升级失败: %v", pool.ID, err)
+		return
+	}
+
+	client, err := manager.RegisterSpectator(pool.ID, conn, snapshot)
+	if err != nil {
+		if errors.Is(err, service.ErrSpectatorLimit) {
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "spectator limit reached"),
+				time.Now().Add(time.Second))
+			conn.Close()
+			return
+		}
+		manager.UnregisterSpectator(client, websocket.CloseInternalServerErr, "failed to send snapshot")
+		return
+	}
+
+	closeCode, closeReason := websocket.CloseNormalClosure, ""
+	defer func() {
+		manager.UnregisterSpectator(client, closeCode, closeReason)
+	}()
+
+	// 观战连接只读，仅响应ping，其余消息忽略
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				closeCode, closeReason = service.CloseHeartbeatTimeout, "heartbeat timeout"
+			}
+			break
+		}
+		client.ExtendReadDeadline()
+
+		if messageType == websocket.TextMessage {
+			var msg map[string]interface{}
+			if err := json.Unmarshal(message, &msg); err == nil && msg["type"] == "ping" {
+				if err := manager.HandlePing(client); err != nil {
+					break
+				}
+			}
+		}
+	}
 }
@@ -1,7 +1,7 @@
 package api
 
 import (
-	"singo/model"
+	"singo/auth"
 	"singo/serializer"
 	"singo/service"
 
@@ -19,6 +19,57 @@ func UserLogin(c *gin.Context) {
 	}
 }
 
+// UserMemoLogin 通过签名的备忘录交易登录（硬件钱包）
+func UserMemoLogin(c *gin.Context) {
+	var service service.MemoLoginService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.Login(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AuthNonce 获取Sign-In With Solana登录所需的一次性nonce
+func AuthNonce(c *gin.Context) {
+	var service service.AuthNonceService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.Issue(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// RefreshToken 刷新访问令牌
+func RefreshToken(c *gin.Context) {
+	var service service.TokenRefreshService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.Refresh()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserLogout 用户登出
+func UserLogout(c *gin.Context) {
+	user := CurrentUser(c)
+	claims := CurrentClaims(c)
+	if user == nil || claims == nil {
+		c.JSON(200, serializer.CheckLogin())
+		return
+	}
+
+	var service service.UserLogoutService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.Logout(user, claims)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // UserMe 用户详情
 func UserMe(c *gin.Context) {
 	user := CurrentUser(c)
@@ -31,15 +82,12 @@ func UserMe(c *gin.Context) {
 	}
 
 	// 获取用户的青蛙状态
-	frog, err := model.GetFrogByUserID(user.ID)
-	if err != nil && !model.IsRecordNotFoundError(err) {
+	isActive, err := service.GetGameService().HasActiveFrog(user.ID)
+	if err != nil {
 		c.JSON(200, serializer.DBErr("Failed to get frog status", err))
 		return
 	}
 
-	// 判断是否有激活的青蛙
-	isActive := frog != nil && frog.IsActive
-
 	c.JSON(200, serializer.Response{
 		Code: 0,
 		Data: gin.H{
@@ -48,6 +96,8 @@ func UserMe(c *gin.Context) {
 				"unclaimedRewards": user.UnclaimedRewards,
 				"historyRewards":   user.HistoryRewards,
 				"isActive":         isActive,
+				"role":             user.Role,
+				"permissions":      auth.Permissions(user.Role),
 			},
 		},
 	})
@@ -95,3 +145,54 @@ func SubmitRewardTx(c *gin.Context) {
 	res := service.Submit(user)
 	c.JSON(200, res)
 }
+
+// currentSessionID 获取当前请求所属的登录会话ID
+func currentSessionID(c *gin.Context) string {
+	if claims := CurrentClaims(c); claims != nil {
+		return claims.SessionID
+	}
+	return ""
+}
+
+// UserSessions 列出当前用户的登录会话
+func UserSessions(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		c.JSON(200, serializer.CheckLogin())
+		return
+	}
+
+	var service service.UserSessionService
+	res := service.List(user, currentSessionID(c))
+	c.JSON(200, res)
+}
+
+// RevokeUserSession 吊销指定的登录会话
+func RevokeUserSession(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		c.JSON(200, serializer.CheckLogin())
+		return
+	}
+
+	var service service.UserSessionService
+	res := service.Revoke(user, c.Param("id"))
+	c.JSON(200, res)
+}
+
+// RevokeAllUserSessions 吊销全部登录会话
+func RevokeAllUserSessions(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		c.JSON(200, serializer.CheckLogin())
+		return
+	}
+
+	var service service.UserSessionService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.RevokeAll(user, currentSessionID(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import "strings"
+
+const (
+	// APIKeyPrefix API密钥的固定前缀，便于识别与泄露扫描
+	APIKeyPrefix = "pmk_"
+	// APIKeyDisplayLength 保存明文前若干位用于在列表中辨认密钥
+	APIKeyDisplayLength = 12
+	// DefaultAPIKeyRateLimit API密钥默认每分钟请求数
+	DefaultAPIKeyRateLimit = 60
+)
+
+// API密钥的授权范围，格式为 "操作:资源"
+const (
+	ScopePoolsRead        = "read:pools"        // 查询奖池
+	ScopeLeaderboardsRead = "read:leaderboards" // 查询排行榜
+)
+
+// validScopes 可分配给API密钥的授权范围
+var validScopes = map[string]bool{
+	ScopePoolsRead:        true,
+	ScopeLeaderboardsRead: true,
+}
+
+// ValidScope 判断授权范围是否存在
+func ValidScope(scope string) bool {
+	return validScopes[scope]
+}
+
+// ServicePrincipal 通过API密钥认证的服务主体（机器人、统计任务、合作方站点等）
+type ServicePrincipal struct {
+	KeyID     uint     `json:"keyId"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	RateLimit int      `json:"rateLimit"` // 每分钟请求数
+}
+
+// HasScope 判断服务主体是否拥有指定授权范围
+func (p *ServicePrincipal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAPIKey 生成新的API密钥，返回明文（只展示一次）、展示前缀与哈希
+func GenerateAPIKey() (key string, display string, hash string, err error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + token
+	return key, key[:APIKeyDisplayLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey API密钥只以哈希形式保存
+func HashAPIKey(key string) string {
+	return hashToken(strings.TrimSpace(key))
+}
@@ -0,0 +1,84 @@
+package auth
+
+// Role 用户角色
+type Role string
+
+const (
+	RolePlayer    Role = "player"    // 普通玩家
+	RoleModerator Role = "moderator" // 运营/管理奖池
+	RoleAdmin     Role = "admin"     // 管理员，拥有全部权限
+	RoleFinance   Role = "finance"   // 财务，管理奖励与账目
+)
+
+// 权限标识，格式为 "资源:操作"
+const (
+	PermGamePlay        = "game:play"        // 参与游戏
+	PermPoolsRead       = "pools:read"       // 查看所有奖池
+	PermPoolsManage     = "pools:manage"     // 管理奖池
+	PermUsersRead       = "users:read"       // 查看用户信息
+	PermRolesManage     = "roles:manage"     // 分配角色、查看权限变更记录
+	PermRewardsRead     = "rewards:read"     // 查看奖励数据
+	PermRewardsManage   = "rewards:manage"   // 管理奖励发放
+	PermConnectionsRead = "connections:read" // 查看实时连接诊断
+	PermMetricsRead     = "metrics:read"     // 查看运行指标
+	PermAPIKeysManage   = "apikeys:manage"   // 创建、吊销API密钥
+	PermJobsManage      = "jobs:manage"      // 查看延迟任务与死信，重新排队
+)
+
+// rolePermissions 角色拥有的权限
+var rolePermissions = map[Role][]string{
+	RolePlayer: {
+		PermGamePlay,
+	},
+	RoleModerator: {
+		PermGamePlay,
+		PermPoolsRead,
+		PermPoolsManage,
+		PermUsersRead,
+		PermConnectionsRead,
+		PermMetricsRead,
+	},
+	RoleFinance: {
+		PermGamePlay,
+		PermUsersRead,
+		PermRewardsRead,
+		PermRewardsManage,
+	},
+	RoleAdmin: {
+		PermGamePlay,
+		PermPoolsRead,
+		PermPoolsManage,
+		PermUsersRead,
+		PermRolesManage,
+		PermRewardsRead,
+		PermRewardsManage,
+		PermConnectionsRead,
+		PermMetricsRead,
+		PermAPIKeysManage,
+		PermJobsManage,
+	},
+}
+
+// ValidRole 判断角色是否存在
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[Role(role)]
+	return ok
+}
+
+// Permissions 获取角色拥有的全部权限，未知角色按普通玩家处理
+func Permissions(role string) []string {
+	if perms, ok := rolePermissions[Role(role)]; ok {
+		return perms
+	}
+	return rolePermissions[RolePlayer]
+}
+
+// HasPermission 判断角色是否拥有指定权限
+func HasPermission(role string, permission string) bool {
+	for _, p := range Permissions(role) {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
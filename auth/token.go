@@ -0,0 +1,332 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"singo/cache"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultAccessTokenTTL 访问令牌默认有效期
+	defaultAccessTokenTTL = 15 * time.Minute
+	// defaultRefreshTokenTTL 刷新令牌默认有效期
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	refreshKeyPrefix        = "auth:refresh:"
+	revokedKeyPrefix        = "auth:revoked:"
+	revokedSessionKeyPrefix = "auth:revoked-session:"
+	sessionSeenKeyPrefix    = "auth:session-seen:"
+
+	// sessionTouchInterval 会话最近活跃时间的最小更新间隔
+	sessionTouchInterval = time.Minute
+)
+
+var (
+	// ErrInvalidToken 令牌无效、已过期或已吊销
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrNoSigningKey 未配置签名密钥
+	ErrNoSigningKey = errors.New("no jwt signing key configured")
+)
+
+// Claims 访问令牌的声明
+type Claims struct {
+	WalletAddress string `json:"wallet_address"`
+	SessionID     string `json:"sid,omitempty"` // 登录会话ID
+	jwt.RegisteredClaims
+}
+
+// TokenPair 登录或刷新后签发的令牌对
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"` // 访问令牌剩余秒数
+	SessionID    string `json:"sessionId"`
+}
+
+// refreshRecord 服务端保存的刷新令牌记录
+type refreshRecord struct {
+	WalletAddress string    `json:"walletAddress"`
+	SessionID     string    `json:"sessionId"`
+	IssuedAt      time.Time `json:"issuedAt"`
+}
+
+// keyRing 签名密钥集合，kid -> secret
+type keyRing struct {
+	keys       map[string][]byte
+	activeKid  string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+var (
+	ring    *keyRing
+	ringMux sync.RWMutex
+)
+
+// LoadKeys 从环境变量加载JWT签名密钥
+//
+// JWT_SIGNING_KEYS 格式为 "kid1:secret1,kid2:secret2"，JWT_ACTIVE_KID 指定签发新令牌所用的kid。
+// 轮换密钥时先添加新密钥并切换JWT_ACTIVE_KID，旧密钥保留至其签发的令牌全部过期后再移除。
+func LoadKeys() error {
+	r := &keyRing{
+		keys:       make(map[string][]byte),
+		activeKid:  os.Getenv("JWT_ACTIVE_KID"),
+		accessTTL:  durationEnv("JWT_ACCESS_TTL", defaultAccessTokenTTL),
+		refreshTTL: durationEnv("JWT_REFRESH_TTL", defaultRefreshTokenTTL),
+	}
+
+	for _, entry := range strings.Split(os.Getenv("JWT_SIGNING_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid JWT_SIGNING_KEYS entry %q", entry)
+		}
+		r.keys[parts[0]] = []byte(parts[1])
+	}
+
+	if len(r.keys) == 0 {
+		return ErrNoSigningKey
+	}
+	if r.activeKid == "" && len(r.keys) == 1 {
+		for kid := range r.keys {
+			r.activeKid = kid
+		}
+	}
+	if _, ok := r.keys[r.activeKid]; !ok {
+		return fmt.Errorf("active kid %q not found in JWT_SIGNING_KEYS", r.activeKid)
+	}
+
+	ringMux.Lock()
+	ring = r
+	ringMux.Unlock()
+	return nil
+}
+
+// currentRing 获取当前密钥集合
+func currentRing() (*keyRing, error) {
+	ringMux.RLock()
+	defer ringMux.RUnlock()
+	if ring == nil {
+		return nil, ErrNoSigningKey
+	}
+	return ring, nil
+}
+
+// durationEnv 读取时长类型的环境变量
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// NewSessionID 生成登录会话ID
+func NewSessionID() (string, error) {
+	return randomToken()
+}
+
+// IssueTokens 为登录会话签发访问令牌与刷新令牌
+func IssueTokens(walletAddress string, sessionID string) (*TokenPair, error) {
+	r, err := currentRing()
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := signAccessToken(r, walletAddress, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	record, err := json.Marshal(refreshRecord{WalletAddress: walletAddress, SessionID: sessionID, IssuedAt: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.RedisClient.Set(context.Background(), refreshKeyPrefix+hashToken(refreshToken), record, r.refreshTTL).Err(); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(r.accessTTL.Seconds()),
+		SessionID:    sessionID,
+	}, nil
+}
+
+// signAccessToken 使用当前kid签发访问令牌
+func signAccessToken(r *keyRing, walletAddress string, sessionID string) (string, error) {
+	jti, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		WalletAddress: walletAddress,
+		SessionID:     sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(r.accessTTL)),
+		},
+	})
+	token.Header["kid"] = r.activeKid
+	return token.SignedString(r.keys[r.activeKid])
+}
+
+// ParseAccessToken 校验访问令牌的签名、有效期与吊销状态
+func ParseAccessToken(tokenString string) (*Claims, error) {
+	r, err := currentRing()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := r.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		return key, nil
+	})
+	if err != nil || !token.Valid || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := IsRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.SessionID != "" {
+		revoked, err := IsSessionRevoked(claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	return claims, nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧刷新令牌立即失效
+func Refresh(refreshToken string) (*TokenPair, error) {
+	data, err := cache.RedisClient.GetDel(context.Background(), refreshKeyPrefix+hashToken(refreshToken)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	var record refreshRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if record.SessionID != "" {
+		revoked, err := IsSessionRevoked(record.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	return IssueTokens(record.WalletAddress, record.SessionID)
+}
+
+// RevokeRefreshToken 删除刷新令牌
+func RevokeRefreshToken(refreshToken string) error {
+	return cache.RedisClient.Del(context.Background(), refreshKeyPrefix+hashToken(refreshToken)).Err()
+}
+
+// Revoke 将访问令牌加入吊销列表，记录保留至令牌过期
+func Revoke(claims *Claims) error {
+	ttl := time.Minute
+	if claims.ExpiresAt != nil {
+		ttl = time.Until(claims.ExpiresAt.Time)
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	return cache.RedisClient.Set(context.Background(), revokedKeyPrefix+claims.ID, 1, ttl).Err()
+}
+
+// IsRevoked 检查访问令牌是否已吊销
+func IsRevoked(jti string) (bool, error) {
+	n, err := cache.RedisClient.Exists(context.Background(), revokedKeyPrefix+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RevokeSession 吊销登录会话，该会话签发的访问令牌与刷新令牌全部失效
+// 记录保留至刷新令牌的最长有效期
+func RevokeSession(sessionID string) error {
+	r, err := currentRing()
+	if err != nil {
+		return err
+	}
+	return cache.RedisClient.Set(context.Background(), revokedSessionKeyPrefix+sessionID, 1, r.refreshTTL).Err()
+}
+
+// IsSessionRevoked 检查登录会话是否已吊销
+func IsSessionRevoked(sessionID string) (bool, error) {
+	n, err := cache.RedisClient.Exists(context.Background(), revokedSessionKeyPrefix+sessionID).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ShouldTouchSession 判断是否需要更新会话的最近活跃时间，避免每个请求都写库
+func ShouldTouchSession(sessionID string) bool {
+	ok, err := cache.RedisClient.SetNX(context.Background(), sessionSeenKeyPrefix+sessionID, 1, sessionTouchInterval).Result()
+	return err == nil && ok
+}
+
+// randomToken 生成随机令牌
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken 刷新令牌只以哈希形式保存
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
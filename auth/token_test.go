@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"errors"
+	"singo/cache"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// useMemoryRedis 令牌吊销与刷新记录写入测试专用的内存Redis
+func useMemoryRedis(t *testing.T) *miniredis.Miniredis {
+	server := miniredis.RunT(t)
+	previous := cache.RedisClient
+	cache.RedisClient = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { cache.RedisClient = previous })
+	return server
+}
+
+// loadKeys 以指定配置加载签名密钥
+func loadKeys(t *testing.T, keys string, activeKid string) {
+	t.Helper()
+	t.Setenv("JWT_SIGNING_KEYS", keys)
+	t.Setenv("JWT_ACTIVE_KID", activeKid)
+	if err := LoadKeys(); err != nil {
+		t.Fatalf("load %q (active %q): %v", keys, activeKid, err)
+	}
+}
+
+// tokenKid 读取令牌头部的kid
+func tokenKid(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+// 配置无效时拒绝加载，只有一个密钥时默认使用它
+func TestLoadKeys(t *testing.T) {
+	for name, c := range map[string]struct{ keys, active string }{
+		"no keys":        {"", ""},
+		"missing secret": {"k1:", "k1"},
+		"missing kid":    {":secret", ""},
+		"unknown active": {"k1:secret", "k2"},
+		"ambiguous":      {"k1:secret,k2:other", ""},
+	} {
+		t.Setenv("JWT_SIGNING_KEYS", c.keys)
+		t.Setenv("JWT_ACTIVE_KID", c.active)
+		if err := LoadKeys(); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+
+	loadKeys(t, " k1:secret ,", "")
+	if r, _ := currentRing(); r.activeKid != "k1" {
+		t.Fatalf("active kid = %q", r.activeKid)
+	}
+}
+
+// 轮换后新令牌使用新kid，旧kid签发的令牌在旧密钥移除前仍然有效
+func TestKeyRotation(t *testing.T) {
+	useMemoryRedis(t)
+
+	loadKeys(t, "k1:first-secret", "k1")
+	old, err := IssueTokens("rotation-wallet", "rotation-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := tokenKid(t, old.AccessToken); kid != "k1" {
+		t.Fatalf("kid = %q", kid)
+	}
+
+	loadKeys(t, "k1:first-secret,k2:second-secret", "k2")
+	fresh, err := IssueTokens("rotation-wallet", "rotation-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := tokenKid(t, fresh.AccessToken); kid != "k2" {
+		t.Fatalf("kid after rotation = %q", kid)
+	}
+	for _, token := range []string{old.AccessToken, fresh.AccessToken} {
+		claims, err := ParseAccessToken(token)
+		if err != nil || claims.WalletAddress != "rotation-wallet" || claims.SessionID != "rotation-session" {
+			t.Fatalf("parse during rotation: %+v (%v)", claims, err)
+		}
+	}
+
+	// 移除旧密钥后其签发的令牌失效
+	loadKeys(t, "k2:second-secret", "k2")
+	if _, err := ParseAccessToken(old.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token of removed kid: %v", err)
+	}
+	if _, err := ParseAccessToken(fresh.AccessToken); err != nil {
+		t.Fatalf("token of active kid: %v", err)
+	}
+
+	// 同名kid更换密钥后签名不再匹配
+	loadKeys(t, "k2:replaced-secret", "k2")
+	if _, err := ParseAccessToken(fresh.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token signed with replaced secret: %v", err)
+	}
+}
+
+// 缺少kid、未知kid、非HMAC算法、过期或缺少jti的令牌均无效
+func TestParseAccessTokenRejects(t *testing.T) {
+	useMemoryRedis(t)
+	loadKeys(t, "k1:secret", "k1")
+
+	sign := func(kid string, claims Claims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	valid := Claims{
+		WalletAddress: "parse-wallet",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	if _, err := ParseAccessToken(sign("k1", valid)); err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noJTI := valid
+	noJTI.ID = ""
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, valid).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{
+		"no kid":      sign("", valid),
+		"unknown kid": sign("k9", valid),
+		"expired":     sign("k1", expired),
+		"no jti":      sign("k1", noJTI),
+		"alg none":    none,
+		"garbage":     "not-a-token",
+	} {
+		if _, err := ParseAccessToken(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+// 吊销的访问令牌与会话立即失效，会话吊销后刷新令牌也无法使用
+func TestRevocation(t *testing.T) {
+	redisServer := useMemoryRedis(t)
+	loadKeys(t, "k1:secret", "k1")
+
+	pair, err := IssueTokens("revoke-wallet", "revoke-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseAccessToken(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Revoke(claims); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseAccessToken(pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("revoked token: %v", err)
+	}
+	// 吊销记录保留至令牌过期
+	if ttl := redisServer.TTL(revokedKeyPrefix + claims.ID); ttl <= 0 || ttl > defaultAccessTokenTTL {
+		t.Fatalf("revocation ttl = %v", ttl)
+	}
+
+	// 刷新令牌只能使用一次，刷新后的令牌属于同一会话
+	refreshed, err := Refresh(pair.RefreshToken)
+	if err != nil || refreshed.SessionID != "revoke-session" {
+		t.Fatalf("refresh: %+v (%v)", refreshed, err)
+	}
+	if _, err := Refresh(pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("reused refresh token: %v", err)
+	}
+
+	if err := RevokeSession("revoke-session"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseAccessToken(refreshed.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token of revoked session: %v", err)
+	}
+	if _, err := Refresh(refreshed.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("refresh of revoked session: %v", err)
+	}
+
+	// 其他会话不受影响
+	other, err := IssueTokens("revoke-wallet", "other-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseAccessToken(other.AccessToken); err != nil {
+		t.Fatalf("other session: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"singo/cache"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// WSTicketTTL WebSocket连接票据有效期
+	WSTicketTTL = 30 * time.Second
+
+	wsTicketKeyPrefix = "auth:ws-ticket:"
+)
+
+// WSTicket 一次性WebSocket连接票据，绑定用户与请求来源
+type WSTicket struct {
+	UserID        uint   `json:"userId"`
+	WalletAddress string `json:"walletAddress"`
+	SessionID     string `json:"sessionId"`
+	Origin        string `json:"origin"`
+}
+
+// IssueWSTicket 签发WebSocket连接票据
+func IssueWSTicket(userID uint, walletAddress string, sessionID string, origin string) (string, error) {
+	ticket, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(WSTicket{
+		UserID:        userID,
+		WalletAddress: walletAddress,
+		SessionID:     sessionID,
+		Origin:        origin,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := cache.RedisClient.Set(context.Background(), wsTicketKeyPrefix+hashToken(ticket), data, WSTicketTTL).Err(); err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// RedeemWSTicket 兑换WebSocket连接票据，票据只能使用一次，来源必须一致且会话未被吊销
+func RedeemWSTicket(ticket string, origin string) (*WSTicket, error) {
+	return redeemTicket(ticket, func(t *WSTicket) bool { return t.Origin == origin })
+}
+
+// RedeemStreamTicket 兑换SSE事件流的连接票据，规则与WebSocket相同
+// 浏览器的跨源请求总会携带Origin，同源的EventSource请求不携带，此时不校验来源
+func RedeemStreamTicket(ticket string, origin string) (*WSTicket, error) {
+	return redeemTicket(ticket, func(t *WSTicket) bool { return origin == "" || t.Origin == origin })
+}
+
+// redeemTicket 取出并删除票据，originOK校验请求来源
+func redeemTicket(ticket string, originOK func(t *WSTicket) bool) (*WSTicket, error) {
+	data, err := cache.RedisClient.GetDel(context.Background(), wsTicketKeyPrefix+hashToken(ticket)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	var t WSTicket
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !originOK(&t) {
+		return nil, ErrInvalidToken
+	}
+
+	// 签发后会话被吊销的票据同样失效
+	if t.SessionID != "" {
+		revoked, err := IsSessionRevoked(t.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrInvalidToken
+		}
+	}
+	return &t, nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// 票据只能兑换一次，来源不一致或过期时无效，来源不一致的尝试同样使票据作废
+func TestRedeemWSTicket(t *testing.T) {
+	redisServer := useMemoryRedis(t)
+	const origin = "http://localhost:3000"
+
+	issue := func(sessionID string) string {
+		ticket, err := IssueWSTicket(7, "ticket-wallet", sessionID, origin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ticket
+	}
+
+	ticket := issue("ticket-session")
+	redeemed, err := RedeemWSTicket(ticket, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *redeemed != (WSTicket{UserID: 7, WalletAddress: "ticket-wallet", SessionID: "ticket-session", Origin: origin}) {
+		t.Fatalf("redeemed = %+v", redeemed)
+	}
+	if _, err := RedeemWSTicket(ticket, origin); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("second redeem: %v", err)
+	}
+
+	ticket = issue("ticket-session")
+	if _, err := RedeemWSTicket(ticket, "https://evil.example"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("other origin: %v", err)
+	}
+	if _, err := RedeemWSTicket(ticket, origin); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("ticket survived a foreign origin: %v", err)
+	}
+
+	ticket = issue("ticket-session")
+	if _, err := RedeemWSTicket(ticket, ""); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("missing origin: %v", err)
+	}
+
+	ticket = issue("ticket-session")
+	redisServer.FastForward(WSTicketTTL + time.Second)
+	if _, err := RedeemWSTicket(ticket, origin); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expired ticket: %v", err)
+	}
+
+	if _, err := RedeemWSTicket("unknown", origin); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("unknown ticket: %v", err)
+	}
+}
+
+// 会话吊销后，吊销前签发的票据不能再建立连接
+func TestRedeemWSTicketRevokedSession(t *testing.T) {
+	useMemoryRedis(t)
+	t.Setenv("JWT_SIGNING_KEYS", "k1:secret")
+	t.Setenv("JWT_ACTIVE_KID", "")
+	if err := LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	ticket, err := IssueWSTicket(7, "ticket-wallet", "revoked-ticket-session", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RevokeSession("revoked-ticket-session"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RedeemWSTicket(ticket, ""); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("ticket of revoked session: %v", err)
+	}
+}
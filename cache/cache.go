@@ -12,11 +12,30 @@ import (
 // RedisClient Redis缓存客户端单例
 var RedisClient *redis.Client
 
+// memoryAddr 使用进程内Redis（miniredis）的地址，仅用于本地开发
+const memoryAddr = "memory://"
+
+// startMemoryRedis 启动进程内Redis并返回其地址，只在以 devredis 标签构建时可用
+var startMemoryRedis func() (string, error)
+
 // Redis 在中间件中初始化redis链接
 func Redis() {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == memoryAddr {
+		if startMemoryRedis == nil {
+			util.Log().Panic("REDIS_ADDR=%s 需要以 -tags devredis 构建", memoryAddr)
+		}
+		memory, err := startMemoryRedis()
+		if err != nil {
+			util.Log().Panic("启动内存Redis失败", err)
+		}
+		util.Log().Warning("使用进程内Redis，数据不会持久化，仅限本地开发")
+		addr = memory
+	}
+
 	db, _ := strconv.ParseUint(os.Getenv("REDIS_DB"), 10, 64)
 	client := redis.NewClient(&redis.Options{
-		Addr:       os.Getenv("REDIS_ADDR"),
+		Addr:       addr,
 		Password:   os.Getenv("REDIS_PW"),
 		DB:         int(db),
 		MaxRetries: 1,
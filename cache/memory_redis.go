@@ -0,0 +1,16 @@
+//go:build devredis
+
+package cache
+
+import "github.com/alicebob/miniredis/v2"
+
+// 以 -tags devredis 构建时支持 REDIS_ADDR=memory://，生产构建不包含miniredis
+func init() {
+	startMemoryRedis = func() (string, error) {
+		server, err := miniredis.Run()
+		if err != nil {
+			return "", err
+		}
+		return server.Addr(), nil
+	}
+}
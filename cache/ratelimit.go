@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript 令牌桶限流脚本，在Redis中原子地补充并取出令牌
+// KEYS[1] 桶的键；ARGV[1] 容量；ARGV[2] 每毫秒补充的令牌数；ARGV[3] 当前毫秒时间戳
+// 返回 {是否允许, 剩余令牌数}
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate))
+
+return {allowed, tostring(tokens)}
+`)
+
+// BucketResult 令牌桶取令牌的结果
+type BucketResult struct {
+	Allowed    bool          // 是否允许本次请求
+	Limit      int           // 桶容量
+	Remaining  int           // 剩余令牌数
+	RetryAfter time.Duration // 被拒绝时距离下一个令牌的时间
+	ResetAfter time.Duration // 距离令牌桶补满的时间
+}
+
+// TakeToken 从令牌桶中取出一个令牌
+// capacity为桶容量，refill为补满整个桶所需的时间
+func TakeToken(key string, capacity int, refill time.Duration) (*BucketResult, error) {
+	rate := float64(capacity) / float64(refill.Milliseconds())
+	now := time.Now().UnixMilli()
+
+	res, err := tokenBucketScript.Run(context.Background(), RedisClient, []string{key}, capacity, rate, now).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BucketResult{
+		Allowed:    allowed == 1,
+		Limit:      capacity,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(capacity)-tokens)/rate) * time.Millisecond,
+	}
+	if !result.Allowed {
+		result.RetryAfter = time.Duration(math.Ceil((1-tokens)/rate)) * time.Millisecond
+	}
+	return result, nil
+}
@@ -2,6 +2,7 @@ package conf
 
 import (
 	"os"
+	"singo/auth"
 	"singo/cache"
 	"singo/model"
 	"singo/util"
@@ -25,9 +26,17 @@ func Init() {
 	// 连接数据库
 	model.Database(DatabaseConfig())
 	cache.Redis()
+
+	// 加载JWT签名密钥
+	if err := auth.LoadKeys(); err != nil {
+		util.Log().Panic("JWT签名密钥加载失败", err)
+	}
 }
 
-// DatabaseConfig 获取数据库配置
+// DatabaseConfig 获取数据库配置，优先使用DATABASE_DSN，兼容旧的MYSQL_DSN
 func DatabaseConfig() string {
+	if dsn := os.Getenv("DATABASE_DSN"); dsn != "" {
+		return dsn
+	}
 	return os.Getenv("MYSQL_DSN")
 }
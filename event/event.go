@@ -1,54 +1,208 @@
+// Package event 进程内的领域事件总线
+//
+// 事件按顺序键分配到固定数量的工作协程，相同键（如同一奖池）的事件按发布顺序依次处理；
+// 每个工作协程的队列有界，队列满时发布方阻塞等待。处理器中不应同步发布事件。
 package event
 
-import "sync"
-
-// PoolEventType 奖池事件类型
-type PoolEventType string
+import (
+	"hash/fnv"
+	"log"
+	"runtime/debug"
+	"singo/metrics"
+	"sync"
+)
 
 const (
-	// PoolBecameActive 奖池变为活跃状态
-	PoolBecameActive PoolEventType = "pool_became_active"
-	// PoolParticipantsChanged 奖池参与者发生变化
-	PoolParticipantsChanged PoolEventType = "pool_participants_changed"
+	// defaultWorkers 工作协程数量
+	defaultWorkers = 8
+	// defaultQueueSize 每个工作协程的队列长度
+	defaultQueueSize = 256
+	// dedupWindow 记录最近发布过的事件ID数量
+	dedupWindow = 4096
 )
 
-// PoolEvent 奖池事件
-type PoolEvent struct {
-	Type         PoolEventType
-	PoolID       uint
-	Participants []map[string]interface{} // 参与者数据，仅在PoolParticipantsChanged事件中使用
+// Event 领域事件
+type Event interface {
+	// EventName 事件名称，订阅与发件箱解码按名称区分
+	EventName() string
+	// Pool 事件所属的奖池，不属于奖池时为0
+	Pool() uint
+	// OrderingKey 相同键的事件按发布顺序依次处理
+	OrderingKey() string
 }
 
-// PoolEventHandler 奖池事件处理函数类型
-type PoolEventHandler func(event PoolEvent)
-
-// EventManager 事件管理器
-type EventManager struct {
-	handlers map[PoolEventType][]PoolEventHandler
+// Bus 事件总线
+type Bus struct {
 	mu       sync.RWMutex
+	handlers map[string][]*Subscription
+	nextID   uint64
+
+	queues []chan delivery
+
+	// 最近发布过的事件ID，按发布顺序循环覆盖
+	seen     map[string]struct{}
+	seenRing []string
+	seenNext int
+	seenMu   sync.Mutex
+}
+
+// delivery 队列中的一次投递，done非nil时为Flush的屏障
+type delivery struct {
+	event Event
+	done  chan struct{}
+}
+
+// Subscription 订阅句柄
+type Subscription struct {
+	bus     *Bus
+	name    string
+	id      uint64
+	handler func(Event)
 }
 
-var (
-	defaultManager = &EventManager{
-		handlers: make(map[PoolEventType][]PoolEventHandler),
+// NewBus 创建事件总线并启动工作协程
+func NewBus(workers int, queueSize int) *Bus {
+	bus := &Bus{
+		handlers: make(map[string][]*Subscription),
+		queues:   make([]chan delivery, workers),
+		seen:     make(map[string]struct{}),
+		seenRing: make([]string, dedupWindow),
 	}
-)
+	for i := range bus.queues {
+		bus.queues[i] = make(chan delivery, queueSize)
+		go bus.work(bus.queues[i])
+	}
+	return bus
+}
 
-// Subscribe 订阅事件
-func Subscribe(eventType PoolEventType, handler PoolEventHandler) {
-	defaultManager.mu.Lock()
-	defer defaultManager.mu.Unlock()
+var defaultBus = NewBus(defaultWorkers, defaultQueueSize)
 
-	defaultManager.handlers[eventType] = append(defaultManager.handlers[eventType], handler)
+// Subscribe 在默认总线上订阅T类型的事件
+func Subscribe[T Event](handler func(T)) *Subscription {
+	return SubscribeOn(defaultBus, handler)
 }
 
-// Publish 发布事件
-func Publish(event PoolEvent) {
-	defaultManager.mu.RLock()
-	handlers := defaultManager.handlers[event.Type]
-	defaultManager.mu.RUnlock()
+// SubscribeOn 在指定总线上订阅T类型的事件
+func SubscribeOn[T Event](bus *Bus, handler func(T)) *Subscription {
+	var zero T
+	return bus.subscribe(zero.EventName(), func(e Event) {
+		handler(e.(T))
+	})
+}
+
+// Publish 在默认总线上发布事件
+func Publish(e Event) {
+	defaultBus.Publish(e)
+}
 
-	for _, handler := range handlers {
-		go handler(event)
+// PublishOnce 在默认总线上发布事件，最近发布过相同ID时忽略
+func PublishOnce(id string, e Event) {
+	defaultBus.PublishOnce(id, e)
+}
+
+// Flush 等待默认总线上已发布的事件处理完毕
+func Flush() {
+	defaultBus.Flush()
+}
+
+func (b *Bus) subscribe(name string, handler func(Event)) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	sub := &Subscription{bus: b, name: name, id: b.nextID, handler: handler}
+	b.handlers[name] = append(b.handlers[name], sub)
+	return sub
+}
+
+// Unsubscribe 取消订阅，队列中尚未处理的事件不再投递给该处理器；可重复调用
+func (s *Subscription) Unsubscribe() {
+	if s == nil {
+		return
+	}
+	b := s.bus
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := b.handlers[s.name]
+	for i, sub := range subs {
+		if sub.id == s.id {
+			// 复制后删除，避免影响正在遍历旧切片的工作协程
+			b.handlers[s.name] = append(append([]*Subscription(nil), subs[:i]...), subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Publish 将事件放入其顺序键对应的队列，队列满时阻塞
+func (b *Bus) Publish(e Event) {
+	h := fnv.New32a()
+	h.Write([]byte(e.OrderingKey()))
+	b.queues[h.Sum32()%uint32(len(b.queues))] <- delivery{event: e}
+}
+
+// PublishOnce 发布带去重ID的事件，最近发布过相同ID时忽略
+func (b *Bus) PublishOnce(id string, e Event) {
+	if id != "" && !b.markSeen(id) {
+		return
+	}
+	b.Publish(e)
+}
+
+// Flush 等待调用前已发布的事件全部处理完毕
+func (b *Bus) Flush() {
+	done := make([]chan struct{}, len(b.queues))
+	for i, queue := range b.queues {
+		done[i] = make(chan struct{})
+		queue <- delivery{done: done[i]}
+	}
+	for _, ch := range done {
+		<-ch
+	}
+}
+
+// work 按顺序处理一个队列中的事件
+func (b *Bus) work(queue chan delivery) {
+	for d := range queue {
+		if d.done != nil {
+			close(d.done)
+			continue
+		}
+
+		b.mu.RLock()
+		subs := b.handlers[d.event.EventName()]
+		b.mu.RUnlock()
+
+		for _, sub := range subs {
+			b.invoke(sub, d.event)
+		}
+	}
+}
+
+// invoke 执行处理器，恢复并统计处理器中的panic
+func (b *Bus) invoke(sub *Subscription, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			metrics.EventHandlerPanics.Add(e.EventName(), 1)
+			log.Printf("事件 %s 的处理器发生panic: %v\n%s", e.EventName(), r, debug.Stack())
+		}
+	}()
+	sub.handler(e)
+}
+
+// markSeen 记录事件ID，已记录过时返回false
+func (b *Bus) markSeen(id string) bool {
+	b.seenMu.Lock()
+	defer b.seenMu.Unlock()
+
+	if _, ok := b.seen[id]; ok {
+		return false
+	}
+	if old := b.seenRing[b.seenNext]; old != "" {
+		delete(b.seen, old)
 	}
+	b.seenRing[b.seenNext] = id
+	b.seenNext = (b.seenNext + 1) % len(b.seenRing)
+	b.seen[id] = struct{}{}
+	return true
 }
@@ -0,0 +1,87 @@
+package event
+
+import (
+	"singo/metrics"
+	"sync"
+	"testing"
+)
+
+// 同一奖池的事件按发布顺序处理
+func TestBusOrderedPerPool(t *testing.T) {
+	bus := NewBus(4, 8)
+
+	var mu sync.Mutex
+	got := make(map[uint][]string)
+	SubscribeOn(bus, func(e PrizeMoved) {
+		mu.Lock()
+		got[e.PoolID] = append(got[e.PoolID], e.Holder)
+		mu.Unlock()
+	})
+
+	const pools, moves = 5, 50
+	for i := 0; i < moves; i++ {
+		for poolID := uint(1); poolID <= pools; poolID++ {
+			bus.Publish(PrizeMoved{PoolID: poolID, Holder: string(rune('a' + i%26))})
+		}
+	}
+	bus.Flush()
+
+	for poolID := uint(1); poolID <= pools; poolID++ {
+		if len(got[poolID]) != moves {
+			t.Fatalf("pool %d received %d events, want %d", poolID, len(got[poolID]), moves)
+		}
+		for i, holder := range got[poolID] {
+			if want := string(rune('a' + i%26)); holder != want {
+				t.Fatalf("pool %d event %d = %s, want %s", poolID, i, holder, want)
+			}
+		}
+	}
+}
+
+// 取消订阅后不再收到事件，处理器的panic被恢复并计数
+func TestBusUnsubscribeAndPanic(t *testing.T) {
+	bus := NewBus(2, 8)
+
+	calls := 0
+	sub := SubscribeOn(bus, func(e PoolActivated) { calls++ })
+	SubscribeOn(bus, func(e PoolActivated) { panic("boom") })
+
+	before := panics(NamePoolActivated)
+	bus.Publish(PoolActivated{PoolID: 1})
+	bus.Flush()
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+	if after := panics(NamePoolActivated); after != before+1 {
+		t.Fatalf("panics = %d, want %d", after, before+1)
+	}
+
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	bus.Publish(PoolActivated{PoolID: 1})
+	bus.Flush()
+	if calls != 1 {
+		t.Fatalf("calls after unsubscribe = %d, want 1", calls)
+	}
+}
+
+// 发件箱的事件可按名称解码
+func TestDecode(t *testing.T) {
+	e, err := Decode(NamePoolCompleted, []byte(`{"poolId":3,"winner":"w","prizeAmount":1.5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := e.(PoolCompleted); !ok || got.PoolID != 3 || got.Winner != "w" || got.PrizeAmount != 1.5 {
+		t.Fatalf("decoded %#v", e)
+	}
+	if _, err := Decode("unknown", nil); err == nil {
+		t.Fatal("expected unknown event to fail")
+	}
+}
+
+func panics(name string) int64 {
+	if v, ok := metrics.EventHandlerPanics.Get(name).(interface{ Value() int64 }); ok {
+		return v.Value()
+	}
+	return 0
+}
@@ -0,0 +1,204 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// 事件名称，同时用作发件箱消息的类型
+const (
+	NameFrogActivated           = "frog_activated"
+	NameFrogFed                 = "frog_fed"
+	NameHungerTicked            = "hunger_ticked"
+	NameFrogStarved             = "frog_starved"
+	NamePrizeMoved              = "prize_moved"
+	NamePoolActivated           = "pool_activated"
+	NamePoolParticipantsChanged = "pool_participants_changed"
+	NamePoolCompleted           = "pool_completed"
+	NamePoolStatusChanged       = "pool_status_changed"
+	NameRewardClaimed           = "reward_claimed"
+	NameRewardPaid              = "reward_paid"
+)
+
+// poolKey 奖池事件的顺序键
+func poolKey(poolID uint) string {
+	return "pool:" + strconv.FormatUint(uint64(poolID), 10)
+}
+
+// userKey 用户事件的顺序键
+func userKey(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// FrogActivated 青蛙激活并加入奖池
+type FrogActivated struct {
+	UserID        uint   `json:"userId"`
+	FrogID        uint   `json:"frogId"`
+	PoolID        uint   `json:"poolId"`
+	SerialNumber  int    `json:"serialNumber"`
+	WalletAddress string `json:"walletAddress,omitempty"`
+	HungerLevel   int    `json:"hungerLevel"`
+}
+
+func (e FrogActivated) EventName() string   { return NameFrogActivated }
+func (e FrogActivated) Pool() uint          { return e.PoolID }
+func (e FrogActivated) OrderingKey() string { return poolKey(e.PoolID) }
+
+// FrogFed 青蛙被投喂
+type FrogFed struct {
+	UserID      uint `json:"userId"`
+	FrogID      uint `json:"frogId"`
+	PoolID      uint `json:"poolId"` // 不在奖池中时为0
+	HungerLevel int  `json:"hungerLevel"`
+}
+
+func (e FrogFed) EventName() string { return NameFrogFed }
+func (e FrogFed) Pool() uint        { return e.PoolID }
+func (e FrogFed) OrderingKey() string {
+	if e.PoolID == 0 {
+		return userKey(e.UserID)
+	}
+	return poolKey(e.PoolID)
+}
+
+// HungerTicked 青蛙的饥饿值随时间降低（仍为活跃状态）
+type HungerTicked struct {
+	UserID      uint `json:"userId"`
+	FrogID      uint `json:"frogId"`
+	PoolID      uint `json:"poolId"` // 不在奖池中时为0
+	HungerLevel int  `json:"hungerLevel"`
+}
+
+func (e HungerTicked) EventName() string { return NameHungerTicked }
+func (e HungerTicked) Pool() uint        { return e.PoolID }
+func (e HungerTicked) OrderingKey() string {
+	if e.PoolID == 0 {
+		return userKey(e.UserID)
+	}
+	return poolKey(e.PoolID)
+}
+
+// FrogStarved 青蛙饥饿值降至0而停用
+type FrogStarved struct {
+	UserID uint `json:"userId"`
+	FrogID uint `json:"frogId"`
+	PoolID uint `json:"poolId"` // 不在奖池中时为0
+}
+
+func (e FrogStarved) EventName() string { return NameFrogStarved }
+func (e FrogStarved) Pool() uint        { return e.PoolID }
+func (e FrogStarved) OrderingKey() string {
+	if e.PoolID == 0 {
+		return userKey(e.UserID)
+	}
+	return poolKey(e.PoolID)
+}
+
+// PrizeMoved 大奖移动到另一只青蛙
+type PrizeMoved struct {
+	PoolID uint   `json:"poolId"`
+	Holder string `json:"holder"` // 持有大奖的钱包地址
+}
+
+func (e PrizeMoved) EventName() string   { return NamePrizeMoved }
+func (e PrizeMoved) Pool() uint          { return e.PoolID }
+func (e PrizeMoved) OrderingKey() string { return poolKey(e.PoolID) }
+
+// PoolActivated 奖池满员变为活跃
+type PoolActivated struct {
+	PoolID uint `json:"poolId"`
+}
+
+func (e PoolActivated) EventName() string   { return NamePoolActivated }
+func (e PoolActivated) Pool() uint          { return e.PoolID }
+func (e PoolActivated) OrderingKey() string { return poolKey(e.PoolID) }
+
+// PoolParticipantsChanged 奖池参与者发生变化
+type PoolParticipantsChanged struct {
+	PoolID       uint                     `json:"poolId"`
+	Participants []map[string]interface{} `json:"participants,omitempty"` // 为空时由处理器按提交后的状态查询
+}
+
+func (e PoolParticipantsChanged) EventName() string   { return NamePoolParticipantsChanged }
+func (e PoolParticipantsChanged) Pool() uint          { return e.PoolID }
+func (e PoolParticipantsChanged) OrderingKey() string { return poolKey(e.PoolID) }
+
+// PoolCompleted 奖池的游戏结束（有人抓到大奖、没有活跃的青蛙或被取消）
+type PoolCompleted struct {
+	PoolID      uint    `json:"poolId"`
+	Winner      string  `json:"winner,omitempty"` // 获胜者钱包地址，无人获胜时为空
+	PrizeAmount float64 `json:"prizeAmount,omitempty"`
+}
+
+func (e PoolCompleted) EventName() string   { return NamePoolCompleted }
+func (e PoolCompleted) Pool() uint          { return e.PoolID }
+func (e PoolCompleted) OrderingKey() string { return poolKey(e.PoolID) }
+
+// PoolStatusChanged 奖池状态按状态机切换
+type PoolStatusChanged struct {
+	PoolID uint   `json:"poolId"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (e PoolStatusChanged) EventName() string   { return NamePoolStatusChanged }
+func (e PoolStatusChanged) Pool() uint          { return e.PoolID }
+func (e PoolStatusChanged) OrderingKey() string { return poolKey(e.PoolID) }
+
+// RewardClaimed 玩家发起提取奖励
+type RewardClaimed struct {
+	UserID uint    `json:"userId"`
+	Amount float64 `json:"amount"`
+}
+
+func (e RewardClaimed) EventName() string   { return NameRewardClaimed }
+func (e RewardClaimed) Pool() uint          { return 0 }
+func (e RewardClaimed) OrderingKey() string { return userKey(e.UserID) }
+
+// RewardPaid 奖励已支付给玩家
+type RewardPaid struct {
+	UserID uint    `json:"userId"`
+	Amount float64 `json:"amount"`
+}
+
+func (e RewardPaid) EventName() string   { return NameRewardPaid }
+func (e RewardPaid) Pool() uint          { return 0 }
+func (e RewardPaid) OrderingKey() string { return userKey(e.UserID) }
+
+// decoders 事件名称 -> JSON解码函数
+var decoders = map[string]func(data []byte) (Event, error){}
+
+// register 注册可从发件箱解码的事件类型
+func register[T Event]() {
+	var zero T
+	decoders[zero.EventName()] = func(data []byte) (Event, error) {
+		var e T
+		err := json.Unmarshal(data, &e)
+		return e, err
+	}
+}
+
+func init() {
+	register[FrogActivated]()
+	register[FrogFed]()
+	register[HungerTicked]()
+	register[FrogStarved]()
+	register[PrizeMoved]()
+	register[PoolActivated]()
+	register[PoolParticipantsChanged]()
+	register[PoolCompleted]()
+	register[PoolStatusChanged]()
+	register[RewardClaimed]()
+	register[RewardPaid]()
+}
+
+// Decode 按事件名称解码JSON
+func Decode(name string, data []byte) (Event, error) {
+	decode, ok := decoders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown event %q", name)
+	}
+	return decode(data)
+}
@@ -1,31 +1,59 @@
 package main
 
 import (
+	"os"
 	"singo/conf"
 	"singo/model"
+	"singo/repository"
 	"singo/server"
 	"singo/service"
+	"singo/util"
 )
 
 func main() {
-	// 从配置文件读取配置
+	// 数据库迁移命令：go run main.go migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	// 从配置文件读取配置（同时初始化数据库与Redis）
 	conf.Init()
 
-	// 初始化数据库
-	model.Database(conf.DatabaseConfig())
+	// 未配置SIWS域名与URI时拒绝启动，避免接受任意来源的登录消息
+	if err := service.CheckSIWSConfig(); err != nil {
+		util.Log().Panic("SIWS登录配置无效", err)
+	}
+
+	// 启动时不再自动迁移，只提示未执行的迁移
+	warnPendingMigrations()
 
-	// 执行数据库迁移
-	model.Migration()
+	// 构造服务实例，注入基于GORM的数据访问实现
+	service.Init(repository.NewGorm(model.DB))
 
-	// 初始化所有活跃奖池的大奖更新器
-	service.GetPrizeUpdaterService().InitializeUpdaters()
+	// 根据配置引导管理员钱包
+	service.BootstrapAdminWallets()
+
+	// 为升级前已活跃的奖池补安排大奖移动任务
+	service.GetPrizeUpdaterService().ScheduleActivePools()
 
 	// 装载路由
 	r := server.NewRouter()
 
-	// 启动饥饿值更新工作器
+	// 启动发件箱投递器，将已提交的领域事件发布到事件总线
+	service.GetOutboxRelay().Start()
+
+	// 启动延迟任务执行器
+	service.GetJobQueue().Start()
+
+	// 启动饥饿值推送工作器（只推送计算出的饥饿值，衰减由饿死任务完成）
 	service.GetWebSocketManager().StartHungerUpdateWorker()
 
+	// 启动在线状态（AFK）检查工作器
+	service.GetWebSocketManager().StartPresenceWorker()
+
+	// 启动奖励账本对账工作器
+	service.GetRewardService().StartReconcileWorker()
+
 	// 运行服务器
 	r.Run(":3001")
 }
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"expvar"
+)
+
+var (
+	// RateLimitBlocked 被限流拦截的请求数，按 "路由组:路由" 统计
+	RateLimitBlocked = expvar.NewMap("rate_limit_blocked")
+	// RateLimitErrors 限流检查因Redis不可用而失败的请求数，按 "路由组:路由" 统计
+	RateLimitErrors = expvar.NewMap("rate_limit_errors")
+
+	// LedgerImbalance 最近一次对账时全部记账金额之和（lamports），正常为0
+	LedgerImbalance = expvar.NewInt("ledger_imbalance_lamports")
+	// LedgerUnbalancedEntries 最近一次对账发现的记账之和不为0的分录数
+	LedgerUnbalancedEntries = expvar.NewInt("ledger_unbalanced_entries")
+	// LedgerDrifts 最近一次对账发现的用户余额偏差数
+	LedgerDrifts = expvar.NewInt("ledger_drifts")
+	// LedgerPoolDrifts 最近一次对账发现的奖池余额异常数
+	LedgerPoolDrifts = expvar.NewInt("ledger_pool_drifts")
+	// LedgerReconcileFailures 未通过的对账次数，可据此告警
+	LedgerReconcileFailures = expvar.NewInt("ledger_reconcile_failures")
+
+	// OutboxPending 发件箱中未投递的事件数
+	OutboxPending = expvar.NewInt("outbox_pending")
+	// OutboxDispatched 已发布到事件总线的发件箱事件数
+	OutboxDispatched = expvar.NewInt("outbox_dispatched")
+	// EventHandlerPanics 事件处理器发生并被恢复的panic数，按事件名称统计
+	EventHandlerPanics = expvar.NewMap("event_handler_panics")
+
+	// OutboxFailures 投递失败的发件箱事件数
+	OutboxFailures = expvar.NewInt("outbox_failures")
+
+	// PoolActors 正在运行的奖池执行协程数
+	PoolActors = expvar.NewInt("pool_actors")
+
+	// JobsSucceeded 执行成功的延迟任务数，按任务类型统计
+	JobsSucceeded = expvar.NewMap("jobs_succeeded")
+	// JobsRetried 执行失败等待重试的次数，按任务类型统计
+	JobsRetried = expvar.NewMap("jobs_retried")
+	// JobsDead 重试次数用尽进入死信列表的任务数，按任务类型统计
+	JobsDead = expvar.NewMap("jobs_dead")
+	// JobsLeaseLost 执行完成时租约已失效、结果未记录的次数，按任务类型统计
+	JobsLeaseLost = expvar.NewMap("jobs_lease_lost")
+)
+
+// Handler 以JSON输出所有指标
+var Handler = expvar.Handler
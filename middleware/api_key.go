@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"singo/auth"
+	"singo/cache"
+	"singo/metrics"
+	"singo/model"
+	"singo/util"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader 服务端集成携带API密钥的请求头
+const APIKeyHeader = "X-API-Key"
+
+// authenticateAPIKey 校验API密钥并挂载服务主体，按密钥单独限流
+// 返回false表示请求已被中止
+func authenticateAPIKey(c *gin.Context, rawKey string) bool {
+	key, err := model.GetActiveAPIKeyByHash(auth.HashAPIKey(rawKey))
+	if err != nil {
+		return true
+	}
+
+	principal := &auth.ServicePrincipal{
+		KeyID:     key.ID,
+		Name:      key.Name,
+		Scopes:    key.ScopeList(),
+		RateLimit: key.RateLimit,
+	}
+	if principal.RateLimit <= 0 {
+		principal.RateLimit = auth.DefaultAPIKeyRateLimit
+	}
+
+	result, err := cache.TakeToken(fmt.Sprintf("ratelimit:apikey:%d", key.ID), principal.RateLimit, time.Minute)
+	if err != nil {
+		util.Log().Error("API密钥限流检查失败: %v", err)
+	} else {
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))))
+		if !result.Allowed {
+			metrics.RateLimitBlocked.Add(fmt.Sprintf("apikey:%d", key.ID), 1)
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			c.AbortWithStatusJSON(429, gin.H{
+				"code": 429,
+				"msg":  "Too many requests",
+			})
+			return false
+		}
+	}
+
+	if err := key.MarkUsed(); err != nil {
+		util.Log().Warning("记录API密钥 %d 使用时间失败: %v", key.ID, err)
+	}
+
+	c.Set("principal", principal)
+	return true
+}
+
+// RequireScope 需要通过API密钥认证且拥有指定授权范围
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, ok := c.Get("principal"); ok {
+			if p, ok := principal.(*auth.ServicePrincipal); ok {
+				if p.HasScope(scope) {
+					c.Next()
+					return
+				}
+				c.JSON(200, gin.H{
+					"code": 403,
+					"msg":  "API key lacks scope " + scope,
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.JSON(200, gin.H{
+			"code": 401,
+			"msg":  "API key required",
+		})
+		c.Abort()
+	}
+}
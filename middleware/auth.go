@@ -1,54 +1,83 @@
 package middleware
 
 import (
+	"singo/auth"
 	"singo/model"
+	"singo/service"
+	"singo/util"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 )
 
 // CurrentUser 获取登录用户
 func CurrentUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var token string
-
-		// 检查是否是WebSocket请求
+		// WebSocket请求只接受一次性连接票据，不再从URL读取JWT
 		if c.GetHeader("Upgrade") == "websocket" {
-			// 从URL参数获取token
-			token = c.Query("token")
-		} else {
-			// 从Authorization header获取token
-			authHeader := c.GetHeader("Authorization")
-			if authHeader != "" {
-				parts := strings.Split(authHeader, " ")
-				if len(parts) == 2 && parts[0] == "Bearer" {
-					token = parts[1]
+			if ticket := c.Query("ticket"); ticket != "" {
+				if t, err := auth.RedeemWSTicket(ticket, c.GetHeader("Origin")); err == nil {
+					if user, err := service.GetUserService().Get(t.UserID); err == nil {
+						c.Set("user", user)
+						c.Set("session_id", t.SessionID)
+					}
 				}
 			}
+			c.Next()
+			return
 		}
 
-		if token == "" {
+		// 浏览器的EventSource无法设置Authorization头，SSE请求同样可以使用一次性连接票据
+		if ticket := c.Query("ticket"); ticket != "" && strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+			if t, err := auth.RedeemStreamTicket(ticket, c.GetHeader("Origin")); err == nil {
+				if user, err := service.GetUserService().Get(t.UserID); err == nil {
+					c.Set("user", user)
+					c.Set("session_id", t.SessionID)
+				}
+			}
 			c.Next()
 			return
 		}
 
-		// 解析token
-		parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-			return []byte("your-secret-key"), nil // TODO: 使用配置中的密钥
-		})
+		// 服务端集成通过API密钥认证，挂载服务主体而不是用户
+		if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" {
+			if authenticateAPIKey(c, apiKey) {
+				c.Next()
+			}
+			return
+		}
+
+		// 从Authorization header获取token
+		var token string
+		authHeader := c.GetHeader("Authorization")
+		if authHeader != "" {
+			parts := strings.Split(authHeader, " ")
+			if len(parts) == 2 && parts[0] == "Bearer" {
+				token = parts[1]
+			}
+		}
+
+		if token == "" {
+			c.Next()
+			return
+		}
 
-		if err != nil || !parsedToken.Valid {
+		// 解析token（校验kid对应的签名密钥、有效期与吊销列表）
+		claims, err := auth.ParseAccessToken(token)
+		if err != nil {
 			c.Next()
 			return
 		}
 
-		// 获取claims
-		if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok {
-			if walletAddress, ok := claims["wallet_address"].(string); ok {
-				user, err := model.GetUserByWallet(walletAddress)
-				if err == nil {
-					c.Set("user", &user)
+		user, err := service.GetUserService().GetByWallet(claims.WalletAddress)
+		if err == nil {
+			c.Set("user", user)
+			c.Set("claims", claims)
+
+			// 定期更新会话的最近活跃时间
+			if claims.SessionID != "" && auth.ShouldTouchSession(claims.SessionID) {
+				if err := model.TouchUserSession(claims.SessionID, c.ClientIP()); err != nil {
+					util.Log().Warning("更新会话 %s 活跃时间失败: %v", claims.SessionID, err)
 				}
 			}
 		}
@@ -78,3 +107,27 @@ func AuthRequired() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequirePermission 需要当前用户的角色拥有指定权限
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user, ok := c.Get("user"); ok {
+			if u, ok := user.(*model.User); ok && auth.HasPermission(u.Role, permission) {
+				c.Next()
+				return
+			}
+		}
+
+		// 对于WebSocket请求，返回403状态码
+		if c.GetHeader("Upgrade") == "websocket" {
+			c.AbortWithStatus(403)
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"code": 403,
+			"msg":  "Permission denied",
+		})
+		c.Abort()
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"os"
 	"regexp"
 	"strings"
 
@@ -38,19 +39,7 @@ func Cors() gin.HandlerFunc {
 			if origin == "" {
 				return true
 			}
-			if strings.HasPrefix(origin, "ws://") {
-				origin = "http://" + strings.TrimPrefix(origin, "ws://")
-			} else if strings.HasPrefix(origin, "wss://") {
-				origin = "https://" + strings.TrimPrefix(origin, "wss://")
-			}
-
-			if regexp.MustCompile(`^http://127\.0\.0\.1:\d+$`).MatchString(origin) {
-				return true
-			}
-			if regexp.MustCompile(`^http://localhost:\d+$`).MatchString(origin) {
-				return true
-			}
-			return false
+			return isLocalOrigin(origin)
 		}
 	}
 	config.AllowCredentials = true
@@ -69,3 +58,35 @@ func Cors() gin.HandlerFunc {
 		corsHandler(c)
 	}
 }
+
+// isLocalOrigin 判断是否为本地开发环境的来源
+func isLocalOrigin(origin string) bool {
+	if strings.HasPrefix(origin, "ws://") {
+		origin = "http://" + strings.TrimPrefix(origin, "ws://")
+	} else if strings.HasPrefix(origin, "wss://") {
+		origin = "https://" + strings.TrimPrefix(origin, "wss://")
+	}
+
+	if regexp.MustCompile(`^http://127\.0\.0\.1:\d+$`).MatchString(origin) {
+		return true
+	}
+	if regexp.MustCompile(`^http://localhost:\d+$`).MatchString(origin) {
+		return true
+	}
+	return false
+}
+
+// WebSocketOriginAllowed 检查WebSocket握手的来源是否在白名单中
+// 白名单通过环境变量WS_ALLOWED_ORIGINS配置，多个来源以逗号分隔；非生产环境额外允许本地来源。
+// 不携带Origin的非浏览器客户端不受跨站劫持影响，予以放行。
+func WebSocketOriginAllowed(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range strings.Split(os.Getenv("WS_ALLOWED_ORIGINS"), ",") {
+		if strings.TrimSpace(allowed) == origin {
+			return true
+		}
+	}
+	return gin.Mode() != gin.ReleaseMode && isLocalOrigin(origin)
+}
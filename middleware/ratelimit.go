@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"singo/cache"
+	"singo/metrics"
+	"singo/model"
+	"singo/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitRule 限流规则：每个键在Period内最多Burst次请求，令牌匀速补充
+type RateLimitRule struct {
+	Burst  int
+	Period time.Duration
+	// FailOpen Redis不可用时是否放行；为false时返回503
+	FailOpen bool
+}
+
+// defaultRateLimitRules 各路由组的默认限流规则，可通过 RATE_LIMIT_<GROUP>="次数/时长" 覆盖
+// 登录与提取奖励等敏感路由默认在Redis不可用时拒绝请求，可通过 RATE_LIMIT_<GROUP>_FAIL_OPEN 覆盖
+var defaultRateLimitRules = map[string]RateLimitRule{
+	"auth":    {Burst: 10, Period: time.Minute},
+	"game":    {Burst: 5, Period: time.Minute, FailOpen: true},
+	"rewards": {Burst: 3, Period: time.Minute},
+}
+
+// loadRateLimitRule 读取路由组的限流规则
+func loadRateLimitRule(group string) RateLimitRule {
+	rule, ok := defaultRateLimitRules[group]
+	if !ok {
+		rule = RateLimitRule{Burst: 60, Period: time.Minute, FailOpen: true}
+	}
+
+	key := "RATE_LIMIT_" + strings.ToUpper(group)
+	if value := os.Getenv(key + "_FAIL_OPEN"); value != "" {
+		if failOpen, err := strconv.ParseBool(value); err == nil {
+			rule.FailOpen = failOpen
+		} else {
+			util.Log().Warning("限流配置 %s_FAIL_OPEN 格式错误: %s", key, value)
+		}
+	}
+
+	value := os.Getenv(key)
+	if value == "" {
+		return rule
+	}
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		util.Log().Warning("限流配置 %s 格式错误: %s", group, value)
+		return rule
+	}
+	burst, err := strconv.Atoi(parts[0])
+	if err != nil || burst <= 0 {
+		util.Log().Warning("限流配置 %s 格式错误: %s", group, value)
+		return rule
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		util.Log().Warning("限流配置 %s 格式错误: %s", group, value)
+		return rule
+	}
+	rule.Burst, rule.Period = burst, period
+	return rule
+}
+
+// RateLimit 基于Redis令牌桶的限流中间件
+// 每个请求分别按 IP+路由 与 钱包+路由 计数，任一超限即拒绝；Redis不可用时按规则放行或返回503
+func RateLimit(group string) gin.HandlerFunc {
+	rule := loadRateLimitRule(group)
+
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		keys := []string{fmt.Sprintf("ratelimit:%s:%s:ip:%s", group, route, c.ClientIP())}
+		if user, _ := c.Get("user"); user != nil {
+			if u, ok := user.(*model.User); ok {
+				keys = append(keys, fmt.Sprintf("ratelimit:%s:%s:wallet:%s", group, route, u.WalletAddress))
+			}
+		}
+
+		// 取最严格的结果作为响应头
+		var strictest *cache.BucketResult
+		for _, key := range keys {
+			result, err := cache.TakeToken(key, rule.Burst, rule.Period)
+			if err != nil {
+				metrics.RateLimitErrors.Add(group+":"+route, 1)
+				if rule.FailOpen {
+					util.Log().Error("限流检查失败，放行请求: %v", err)
+					c.Next()
+					return
+				}
+				util.Log().Error("限流检查失败，拒绝请求: %v", err)
+				c.AbortWithStatusJSON(503, gin.H{
+					"code": 503,
+					"msg":  "Service temporarily unavailable",
+				})
+				return
+			}
+			if strictest == nil || !result.Allowed || (strictest.Allowed && result.Remaining < strictest.Remaining) {
+				strictest = result
+			}
+			if !result.Allowed {
+				break
+			}
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(strictest.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(strictest.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(strictest.ResetAfter.Seconds()))))
+
+		if !strictest.Allowed {
+			metrics.RateLimitBlocked.Add(group+":"+route, 1)
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(strictest.RetryAfter.Seconds()))))
+			c.AbortWithStatusJSON(429, gin.H{
+				"code": 429,
+				"msg":  "Too many requests",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
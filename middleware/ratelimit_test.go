@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"singo/cache"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// newRateLimitRouter 使用内存Redis的测试路由，每个路由组挂载一个接口
+func newRateLimitRouter(t *testing.T, groups ...string) (*gin.Engine, *miniredis.Miniredis) {
+	gin.SetMode(gin.TestMode)
+	server := miniredis.RunT(t)
+	previous := cache.RedisClient
+	cache.RedisClient = redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { cache.RedisClient = previous })
+
+	r := gin.New()
+	for _, group := range groups {
+		r.GET("/"+group, RateLimit(group), func(c *gin.Context) {
+			c.JSON(200, gin.H{"code": 0})
+		})
+	}
+	return r, server
+}
+
+// request 发送请求并返回响应
+func request(r *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// 超出次数后返回429与重试时间，令牌补充后恢复
+func TestRateLimitTooManyRequests(t *testing.T) {
+	t.Setenv("RATE_LIMIT_AUTH", "2/1s")
+	r, _ := newRateLimitRouter(t, "auth")
+
+	for i := 0; i < 2; i++ {
+		w := request(r, "/auth")
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "2" {
+			t.Fatalf("request %d: %d %v", i, w.Code, w.Header())
+		}
+	}
+	w := request(r, "/auth")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("over limit: %d %v", w.Code, w.Header())
+	}
+
+	time.Sleep(600 * time.Millisecond)
+	if w := request(r, "/auth"); w.Code != http.StatusOK {
+		t.Fatalf("after refill: %d", w.Code)
+	}
+}
+
+// Redis不可用时敏感路由返回503，其他路由放行；可按路由组覆盖
+func TestRateLimitRedisUnavailable(t *testing.T) {
+	t.Setenv("RATE_LIMIT_PUBLIC_FAIL_OPEN", "false")
+	t.Setenv("RATE_LIMIT_REWARDS_FAIL_OPEN", "yes")
+	r, server := newRateLimitRouter(t, "auth", "rewards", "game", "public")
+	server.Close()
+
+	for path, want := range map[string]int{
+		"/auth":    http.StatusServiceUnavailable,
+		"/game":    http.StatusOK,
+		"/public":  http.StatusServiceUnavailable,
+		"/rewards": http.StatusServiceUnavailable, // 无效的覆盖值不改变默认行为
+	} {
+		if w := request(r, path); w.Code != want {
+			t.Errorf("%s: status %d, want %d", path, w.Code, want)
+		}
+	}
+}
+
+// 默认规则与环境变量覆盖
+func TestLoadRateLimitRule(t *testing.T) {
+	t.Setenv("RATE_LIMIT_GAME", "20/30s")
+	t.Setenv("RATE_LIMIT_GAME_FAIL_OPEN", "false")
+	t.Setenv("RATE_LIMIT_AUTH", "ten/1m")
+
+	if rule := loadRateLimitRule("game"); rule != (RateLimitRule{Burst: 20, Period: 30 * time.Second}) {
+		t.Fatalf("game = %+v", rule)
+	}
+	if rule := loadRateLimitRule("auth"); rule != defaultRateLimitRules["auth"] {
+		t.Fatalf("invalid override: %+v", rule)
+	}
+	if rule := loadRateLimitRule("other"); !rule.FailOpen || rule.Burst != 60 {
+		t.Fatalf("unknown group = %+v", rule)
+	}
+}
@@ -0,0 +1,314 @@
+// Package migrate 执行 migrations 目录中按版本编号的SQL迁移
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"singo/migrations"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// schemaTable 记录已执行迁移的表
+const schemaTable = "schema_migrations"
+
+// lockName MySQL迁移锁名称，防止多个实例同时迁移
+const lockName = "singo_schema_migrations"
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrDirty 数据库中存在迁移文件里没有的版本
+var ErrDirty = errors.New("database has migrations unknown to this build")
+
+// Migration 一个版本的迁移
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// appliedMigration schema_migrations 表中的记录
+type appliedMigration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New 根据数据库方言加载内嵌的迁移文件
+func New(db *gorm.DB) (*Migrator, error) {
+	list, err := Load(migrations.FS, db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: list}, nil
+}
+
+// Load 从目录加载迁移文件，按版本号升序排列
+// 每个版本必须同时有up与down文件
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations for %s: %w", dir, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Up 依次执行未执行的迁移，steps<=0时执行全部，返回执行的数量
+func (m *Migrator) Up(steps int) (int, error) {
+	count := 0
+	err := m.withLock(func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if steps > 0 && count >= steps {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(db, migration, true); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down 按版本倒序回滚已执行的迁移，steps<=0时回滚全部，返回回滚的数量
+func (m *Migrator) Down(steps int) (int, error) {
+	count := 0
+	err := m.withLock(func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if steps > 0 && count >= steps {
+				break
+			}
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.run(db, migration, false); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Status 列出所有迁移及其执行状态
+func (m *Migrator) Status() ([]Status, error) {
+	if err := m.ensureTable(m.db); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending 获取未执行的迁移
+func (m *Migrator) Pending() ([]Migration, error) {
+	if err := m.ensureTable(m.db); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// run 执行单个迁移并更新 schema_migrations
+// 注意MySQL的DDL会隐式提交，迁移中途失败时需要人工检查已执行的语句
+func (m *Migrator) run(db *gorm.DB, migration Migration, up bool) error {
+	script := migration.Down
+	if up {
+		script = migration.Up
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range splitStatements(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				direction := "down"
+				if up {
+					direction = "up"
+				}
+				return fmt.Errorf("migration %d_%s %s: %w", migration.Version, migration.Name, direction, err)
+			}
+		}
+
+		if up {
+			return tx.Exec("INSERT INTO "+schemaTable+" (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now()).Error
+		}
+		return tx.Exec("DELETE FROM "+schemaTable+" WHERE version = ?", migration.Version).Error
+	})
+}
+
+// ensureTable 创建 schema_migrations 表
+func (m *Migrator) ensureTable(db *gorm.DB) error {
+	return db.Exec("CREATE TABLE IF NOT EXISTS " + schemaTable + " (" +
+		"version BIGINT NOT NULL PRIMARY KEY, " +
+		"name VARCHAR(255) NOT NULL, " +
+		"applied_at TIMESTAMP NOT NULL)").Error
+}
+
+// applied 读取已执行的迁移，存在未知版本时返回ErrDirty
+func (m *Migrator) applied(db *gorm.DB) (map[int]appliedMigration, error) {
+	var records []appliedMigration
+	if err := db.Table(schemaTable).Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+
+	applied := make(map[int]appliedMigration, len(records))
+	for _, record := range records {
+		if !known[record.Version] {
+			return nil, fmt.Errorf("%w: version %d", ErrDirty, record.Version)
+		}
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// withLock 在同一连接上持有迁移锁执行fn（仅MySQL）
+func (m *Migrator) withLock(fn func(db *gorm.DB) error) error {
+	return m.db.Connection(func(db *gorm.DB) error {
+		if err := m.ensureTable(db); err != nil {
+			return err
+		}
+		if db.Dialector.Name() != "mysql" {
+			return fn(db)
+		}
+
+		var locked int
+		if err := db.Raw("SELECT GET_LOCK(?, 30)", lockName).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if locked != 1 {
+			return errors.New("timeout waiting for migration lock")
+		}
+		defer db.Exec("SELECT RELEASE_LOCK(?)", lockName)
+
+		return fn(db)
+	})
+}
+
+// splitStatements 将SQL脚本拆分为单条语句，忽略 "--" 注释行
+// 语句以行尾的分号结束
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(strings.ReplaceAll(script, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
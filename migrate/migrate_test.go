@@ -0,0 +1,285 @@
+package migrate
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"singo/migrations"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openSQLite 打开临时目录中的SQLite数据库
+func openSQLite(t *testing.T) *gorm.DB {
+	dsn := filepath.Join(t.TempDir(), "migrate.db") + "?_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// schema 列出数据库中的表与索引定义（不含迁移记录表）
+func schema(t *testing.T, db *gorm.DB) map[string]string {
+	var rows []struct {
+		Name string
+		SQL  string
+	}
+	if err := db.Raw("SELECT name, COALESCE(sql, '') AS sql FROM sqlite_master WHERE name NOT LIKE 'sqlite_%' AND name <> ?", schemaTable).Scan(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]string, len(rows))
+	for _, row := range rows {
+		result[row.Name] = row.SQL
+	}
+	return result
+}
+
+// 两种方言的迁移版本一致、连续，且都有up与down
+func TestEmbeddedMigrations(t *testing.T) {
+	mysql, err := Load(migrations.FS, "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := Load(migrations.FS, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mysql) == 0 || len(mysql) != len(sqlite) {
+		t.Fatalf("mysql has %d migrations, sqlite has %d", len(mysql), len(sqlite))
+	}
+	for i := range mysql {
+		if mysql[i].Version != i+1 || sqlite[i].Version != i+1 || mysql[i].Name != sqlite[i].Name {
+			t.Fatalf("migration %d: mysql %d_%s, sqlite %d_%s", i, mysql[i].Version, mysql[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+}
+
+// 逐个执行up后再逐个执行down，每一步回滚后的结构与执行前完全一致
+func TestSQLiteUpDownRoundTrip(t *testing.T) {
+	db := openSQLite(t)
+	m, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := []map[string]string{schema(t, db)}
+	for _, migration := range m.migrations {
+		if n, err := m.Up(1); err != nil || n != 1 {
+			t.Fatalf("up %d_%s: %d (%v)", migration.Version, migration.Name, n, err)
+		}
+		before = append(before, schema(t, db))
+	}
+	if n, err := m.Up(0); err != nil || n != 0 {
+		t.Fatalf("up after all applied: %d (%v)", n, err)
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt == nil {
+			t.Fatalf("status = %+v", status)
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if n, err := m.Down(1); err != nil || n != 1 {
+			t.Fatalf("down %d_%s: %d (%v)", m.migrations[i].Version, m.migrations[i].Name, n, err)
+		}
+		if got := schema(t, db); !reflect.DeepEqual(got, before[i]) {
+			t.Fatalf("schema after down %d_%s differs from before up:\n got %v\nwant %v", m.migrations[i].Version, m.migrations[i].Name, keys(got), keys(before[i]))
+		}
+	}
+	if len(before[0]) != 0 {
+		t.Fatalf("empty database has tables: %v", keys(before[0]))
+	}
+
+	// 全部回滚后可以重新执行
+	if n, err := m.Up(0); err != nil || n != len(m.migrations) {
+		t.Fatalf("up again: %d (%v)", n, err)
+	}
+	if pending, err := m.Pending(); err != nil || len(pending) != 0 {
+		t.Fatalf("pending = %v (%v)", pending, err)
+	}
+}
+
+// 启用账本前已有的用户余额与未结束奖池的奖金记为期初分录，账本保持平衡
+func TestSQLiteOpeningBalances(t *testing.T) {
+	db := openSQLite(t)
+	m, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(1); err != nil {
+		t.Fatal(err)
+	}
+
+	exec := func(sql string, values ...interface{}) {
+		t.Helper()
+		if err := db.Exec(sql, values...).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	exec("INSERT INTO users (id, wallet_address, unclaimed_rewards, history_rewards, role) VALUES (1, 'w1', 0.25, 1.5, 'player')")
+	pools := []struct {
+		id      int
+		status  string
+		players int
+		winner  string
+	}{
+		{1, "active", 10, ""},
+		{2, "collecting", 3, ""},
+		{3, "completed", 10, "w1"},
+		{4, "active", 10, "w1"},
+	}
+	for _, p := range pools {
+		exec("INSERT INTO prize_pools (id, status, current_players, prize_amount, big_prize_winner) VALUES (?, ?, ?, 0.1, ?)", p.id, p.status, p.players, p.winner)
+	}
+	if _, err := m.Up(1); err != nil {
+		t.Fatal(err)
+	}
+
+	balance := func(account string, owner int) int64 {
+		t.Helper()
+		var total int64
+		if err := db.Raw("SELECT COALESCE(SUM(amount), 0) FROM ledger_postings WHERE account = ? AND owner_id = ?", account, owner).Scan(&total).Error; err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+	for _, want := range []struct {
+		account string
+		owner   int
+		amount  int64
+	}{
+		{"user-unclaimed", 1, 250000000},
+		{"user-paid", 1, 1500000000},
+		{"pool-prize", 1, 100000000},
+		{"pool-prize", 2, 30000000},
+		{"pool-prize", 3, 0},
+		{"pool-prize", 4, 0},
+		{"treasury", 0, -1880000000},
+	} {
+		if got := balance(want.account, want.owner); got != want.amount {
+			t.Errorf("%s/%d = %d, want %d", want.account, want.owner, got, want.amount)
+		}
+	}
+
+	var unbalanced int64
+	if err := db.Raw("SELECT COUNT(*) FROM (SELECT entry_id FROM ledger_postings GROUP BY entry_id HAVING SUM(amount) <> 0) u").Scan(&unbalanced).Error; err != nil {
+		t.Fatal(err)
+	}
+	if unbalanced != 0 {
+		t.Fatalf("%d unbalanced entries", unbalanced)
+	}
+}
+
+// 数据库中存在未知版本时拒绝执行
+func TestDirtyDatabase(t *testing.T) {
+	db := openSQLite(t)
+	m, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO " + schemaTable + " (version, name, applied_at) VALUES (9999, 'future', CURRENT_TIMESTAMP)").Error; err != nil {
+		t.Fatal(err)
+	}
+	for name, run := range map[string]func() error{
+		"up":      func() error { _, err := m.Up(0); return err },
+		"down":    func() error { _, err := m.Down(1); return err },
+		"pending": func() error { _, err := m.Pending(); return err },
+	} {
+		if err := run(); !errors.Is(err, ErrDirty) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+// 失败的迁移整体回滚，不记录版本
+func TestFailedMigrationIsNotRecorded(t *testing.T) {
+	db := openSQLite(t)
+	m := &Migrator{db: db, migrations: []Migration{
+		{Version: 1, Name: "ok", Up: "CREATE TABLE a (id INTEGER);", Down: "DROP TABLE a;"},
+		{Version: 2, Name: "broken", Up: "CREATE TABLE b (id INTEGER);\nCREATE TABLE a (id INTEGER);", Down: "DROP TABLE b;"},
+	}}
+	n, err := m.Up(0)
+	if err == nil || n != 1 || !strings.Contains(err.Error(), "migration 2_broken up") {
+		t.Fatalf("up: %d (%v)", n, err)
+	}
+	if got := keys(schema(t, db)); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("tables = %v", got)
+	}
+	if pending, _ := m.Pending(); len(pending) != 1 || pending[0].Version != 2 {
+		t.Fatalf("pending = %+v", pending)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	for name, files := range map[string]fstest.MapFS{
+		"bad name":         {"m/1_Init.up.sql": {}, "m/1_Init.down.sql": {}},
+		"missing down":     {"m/0001_init.up.sql": {Data: []byte("SELECT 1;")}},
+		"conflicting name": {"m/0001_init.up.sql": {Data: []byte("SELECT 1;")}, "m/0001_other.down.sql": {Data: []byte("SELECT 1;")}},
+		"missing dir":      {},
+	} {
+		if _, err := Load(files, "m"); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := "-- comment\r\nCREATE TABLE a (\n  id INTEGER -- trailing\n);\n\n  -- indented comment\nINSERT INTO a VALUES (1);\nSELECT 1"
+	want := []string{"CREATE TABLE a (\n  id INTEGER -- trailing\n)", "INSERT INTO a VALUES (1)", "SELECT 1"}
+	if got := splitStatements(script); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q", got)
+	}
+}
+
+// MySQL初始迁移为已有的 users 表补充 role 列，条件执行的语句逐条拆分
+func TestMySQLInitialSchemaAddsRole(t *testing.T) {
+	mysql, err := Load(migrations.FS, "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := splitStatements(mysql[0].Up)
+
+	var users, alter int
+	for i, stmt := range statements {
+		if strings.HasPrefix(stmt, "CREATE TABLE IF NOT EXISTS `users`") {
+			users = i
+			if strings.Contains(stmt, "`role`") {
+				t.Fatalf("users table creates role inline, existing tables would miss it: %s", stmt)
+			}
+		}
+		if strings.Contains(stmt, "ADD COLUMN `role`") {
+			alter = i
+		}
+	}
+	if alter <= users {
+		t.Fatalf("role column is not added after the users table: %q", statements)
+	}
+	want := []string{"PREPARE add_user_role FROM @add_user_role", "EXECUTE add_user_role", "DEALLOCATE PREPARE add_user_role"}
+	if got := statements[alter+1 : alter+4]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q", got)
+	}
+}
+
+// keys 排序后的键列表，用于输出
+func keys(m map[string]string) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"singo/conf"
+	"singo/migrate"
+	"singo/model"
+	"singo/util"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+)
+
+const migrateUsage = `用法: go run main.go migrate <命令> [步数]
+
+命令:
+  up [N]      执行未执行的迁移，省略N时执行全部
+  down [N]    回滚最近执行的N个迁移，省略N时回滚1个
+  status      查看所有迁移的执行状态`
+
+// runMigrate 执行迁移命令，返回进程退出码
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Println(migrateUsage)
+		return 2
+	}
+
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			fmt.Println(migrateUsage)
+			return 2
+		}
+		steps = n
+	}
+
+	// 迁移只需要数据库连接
+	godotenv.Load()
+	util.BuildLogger(os.Getenv("LOG_LEVEL"))
+	model.Database(conf.DatabaseConfig())
+
+	migrator, err := migrate.New(model.DB)
+	if err != nil {
+		util.Log().Error("加载迁移文件失败: %v", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		n, err := migrator.Up(steps)
+		if err != nil {
+			util.Log().Error("执行迁移失败: %v", err)
+			return 1
+		}
+		util.Log().Info("已执行 %d 个迁移", n)
+	case "down":
+		if steps == 0 {
+			steps = 1
+		}
+		n, err := migrator.Down(steps)
+		if err != nil {
+			util.Log().Error("回滚迁移失败: %v", err)
+			return 1
+		}
+		util.Log().Info("已回滚 %d 个迁移", n)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			util.Log().Error("获取迁移状态失败: %v", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+	default:
+		fmt.Println(migrateUsage)
+		return 2
+	}
+	return 0
+}
+
+// warnPendingMigrations 启动时检查未执行的迁移并打印警告
+func warnPendingMigrations() {
+	migrator, err := migrate.New(model.DB)
+	if err != nil {
+		util.Log().Error("加载迁移文件失败: %v", err)
+		return
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		util.Log().Error("检查迁移状态失败: %v", err)
+		return
+	}
+	for _, m := range pending {
+		util.Log().Warning("迁移 %04d_%s 尚未执行，请运行 go run main.go migrate up", m.Version, m.Name)
+	}
+}
@@ -0,0 +1,12 @@
+// Package migrations 存放按版本编号的SQL迁移文件，按数据库方言分目录
+//
+// 文件命名为 "<版本>_<名称>.up.sql" 与 "<版本>_<名称>.down.sql"，
+// 版本号只增不改，已发布的迁移文件不要再修改，需要变更时新增一个版本。
+package migrations
+
+import "embed"
+
+// FS 内嵌的迁移文件
+//
+//go:embed mysql/*.sql sqlite/*.sql
+var FS embed.FS
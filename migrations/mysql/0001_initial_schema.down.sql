@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS `user_sessions`;
+DROP TABLE IF EXISTS `api_keys`;
+DROP TABLE IF EXISTS `role_audit_logs`;
+DROP TABLE IF EXISTS `pool_participants`;
+DROP TABLE IF EXISTS `prize_pools`;
+DROP TABLE IF EXISTS `frogs`;
+DROP TABLE IF EXISTS `users`;
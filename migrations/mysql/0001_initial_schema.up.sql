@@ -0,0 +1,129 @@
+-- 初始表结构，与原先AutoMigrate生成的结构一致
+-- 使用 IF NOT EXISTS，已由AutoMigrate建表的数据库不会重建已有的表；
+-- 已有的 users 表可能没有 role 列（角色功能之前的版本），在建表后按需补充
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `wallet_address` varchar(44) NULL,
+  `unclaimed_rewards` double DEFAULT 0,
+  `history_rewards` double DEFAULT 0,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_users_wallet_address` (`wallet_address`),
+  INDEX `idx_users_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 新建的表与已有但没有 role 列的表都需要补充该列，已有该列时跳过
+SET @add_user_role = IF(
+  (SELECT COUNT(*) FROM information_schema.columns
+   WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'role') = 0,
+  'ALTER TABLE `users` ADD COLUMN `role` varchar(20) DEFAULT ''player''',
+  'DO 0'
+);
+PREPARE add_user_role FROM @add_user_role;
+EXECUTE add_user_role;
+DEALLOCATE PREPARE add_user_role;
+
+CREATE TABLE IF NOT EXISTS `frogs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `hunger_level` bigint DEFAULT 100,
+  `is_active` boolean DEFAULT true,
+  `last_feed_time` timestamp NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_frogs_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_frogs_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `prize_pools` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `status` varchar(20) NOT NULL,
+  `current_players` bigint DEFAULT 0,
+  `prize_amount` decimal(10,4) NULL,
+  `big_prize_winner` varchar(44) NULL,
+  `current_big_prize_holder` varchar(44) NULL,
+  `completed_at` timestamp NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_prize_pools_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `pool_participants` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `pool_id` bigint unsigned NOT NULL,
+  `frog_id` bigint unsigned NOT NULL,
+  `wallet_address` varchar(44) NULL,
+  `serial_number` bigint NOT NULL,
+  `joined_at` timestamp NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_pool_participants_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_prize_pools_participants` FOREIGN KEY (`pool_id`) REFERENCES `prize_pools` (`id`),
+  CONSTRAINT `fk_pool_participants_frog` FOREIGN KEY (`frog_id`) REFERENCES `frogs` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `role_audit_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `target_user_id` bigint unsigned NOT NULL,
+  `target_wallet` varchar(44) NULL,
+  `old_role` varchar(20) NULL,
+  `new_role` varchar(20) NULL,
+  `actor_user_id` bigint unsigned NULL,
+  `actor_wallet` varchar(44) NULL,
+  `reason` varchar(255) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_role_audit_logs_deleted_at` (`deleted_at`),
+  INDEX `idx_role_audit_logs_target_user_id` (`target_user_id`),
+  INDEX `idx_role_audit_logs_actor_user_id` (`actor_user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `name` varchar(64) NOT NULL,
+  `prefix` varchar(16) NULL,
+  `key_hash` varchar(64) NULL,
+  `scopes` varchar(255) NULL,
+  `rate_limit` bigint DEFAULT 60,
+  `created_by_user_id` bigint unsigned NULL,
+  `last_used_at` timestamp NULL,
+  `revoked_at` timestamp NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_api_keys_key_hash` (`key_hash`),
+  INDEX `idx_api_keys_deleted_at` (`deleted_at`),
+  INDEX `idx_api_keys_created_by_user_id` (`created_by_user_id`),
+  INDEX `idx_api_keys_revoked_at` (`revoked_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_sessions` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `session_id` varchar(64) NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `device` varchar(64) NULL,
+  `user_agent` varchar(255) NULL,
+  `ip` varchar(64) NULL,
+  `last_seen_at` timestamp NULL,
+  `revoked_at` timestamp NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_user_sessions_session_id` (`session_id`),
+  INDEX `idx_user_sessions_deleted_at` (`deleted_at`),
+  INDEX `idx_user_sessions_user_id` (`user_id`),
+  INDEX `idx_user_sessions_revoked_at` (`revoked_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,60 @@
+-- 复式记账的奖励账本
+
+CREATE TABLE IF NOT EXISTS `ledger_entries` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `kind` varchar(32) NOT NULL,
+  `reference` varchar(64) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_ledger_entries_kind` (`kind`),
+  INDEX `idx_ledger_entries_reference` (`reference`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `ledger_postings` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `entry_id` bigint unsigned NOT NULL,
+  `account` varchar(32) NOT NULL,
+  `owner_id` bigint unsigned NOT NULL DEFAULT 0,
+  `amount` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_ledger_postings_entry_id` (`entry_id`),
+  INDEX `idx_ledger_postings_account` (`account`, `owner_id`),
+  CONSTRAINT `fk_ledger_entries_postings` FOREIGN KEY (`entry_id`) REFERENCES `ledger_entries` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 启用账本前用户已有的余额与未结束奖池中的奖金记为一笔期初分录，对应的资金视为从金库流入
+-- 尚未结束且没有获胜者的奖池持有参与者支付的激活费（每人 0.01 SOL，最多为奖池金额），
+-- 记账后发奖或没收时奖池余额不会变为负数
+INSERT INTO `ledger_entries` (`created_at`, `kind`, `reference`)
+SELECT NOW(3), 'opening-balance', 'migration:0002' FROM DUAL
+WHERE EXISTS (
+  SELECT 1 FROM `users`
+  WHERE `deleted_at` IS NULL AND (`unclaimed_rewards` <> 0 OR `history_rewards` <> 0)
+) OR EXISTS (
+  SELECT 1 FROM `prize_pools`
+  WHERE `deleted_at` IS NULL AND `status` IN ('collecting', 'active')
+    AND COALESCE(`big_prize_winner`, '') = '' AND `current_players` > 0 AND `prize_amount` > 0
+);
+
+INSERT INTO `ledger_postings` (`created_at`, `entry_id`, `account`, `owner_id`, `amount`)
+SELECT NOW(3), e.`id`, 'user-unclaimed', u.`id`, ROUND(u.`unclaimed_rewards` * 1000000000)
+FROM `users` u JOIN `ledger_entries` e ON e.`reference` = 'migration:0002'
+WHERE u.`deleted_at` IS NULL AND u.`unclaimed_rewards` <> 0;
+
+INSERT INTO `ledger_postings` (`created_at`, `entry_id`, `account`, `owner_id`, `amount`)
+SELECT NOW(3), e.`id`, 'user-paid', u.`id`, ROUND(u.`history_rewards` * 1000000000)
+FROM `users` u JOIN `ledger_entries` e ON e.`reference` = 'migration:0002'
+WHERE u.`deleted_at` IS NULL AND u.`history_rewards` <> 0;
+
+INSERT INTO `ledger_postings` (`created_at`, `entry_id`, `account`, `owner_id`, `amount`)
+SELECT NOW(3), e.`id`, 'pool-prize', p.`id`, LEAST(p.`current_players` * 10000000, ROUND(p.`prize_amount` * 1000000000))
+FROM `prize_pools` p JOIN `ledger_entries` e ON e.`reference` = 'migration:0002'
+WHERE p.`deleted_at` IS NULL AND p.`status` IN ('collecting', 'active')
+  AND COALESCE(p.`big_prize_winner`, '') = '' AND p.`current_players` > 0 AND p.`prize_amount` > 0;
+
+INSERT INTO `ledger_postings` (`created_at`, `entry_id`, `account`, `owner_id`, `amount`)
+SELECT NOW(3), e.`id`, 'treasury', 0, -SUM(p.`amount`)
+FROM `ledger_entries` e JOIN `ledger_postings` p ON p.`entry_id` = e.`id`
+WHERE e.`reference` = 'migration:0002'
+GROUP BY e.`id`;
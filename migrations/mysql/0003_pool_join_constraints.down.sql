@@ -0,0 +1,3 @@
+ALTER TABLE `prize_pools` DROP INDEX `idx_prize_pools_collecting_slot`;
+ALTER TABLE `prize_pools` DROP COLUMN `collecting_slot`;
+ALTER TABLE `pool_participants` DROP INDEX `idx_pool_participants_serial`;
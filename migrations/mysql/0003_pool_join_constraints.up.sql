@@ -0,0 +1,31 @@
+-- 加入奖池的并发约束：同一奖池内序号唯一，同时只有一个收集中的奖池
+
+-- 并发加入可能已产生重复序号，按加入顺序重新编号
+UPDATE `pool_participants` p
+JOIN (
+  SELECT a.`id`, COUNT(*) AS `serial`
+  FROM `pool_participants` a
+  JOIN `pool_participants` b ON b.`pool_id` = a.`pool_id` AND b.`id` <= a.`id`
+  GROUP BY a.`id`
+) r ON r.`id` = p.`id`
+SET p.`serial_number` = r.`serial`
+WHERE p.`pool_id` IN (
+  SELECT `pool_id` FROM (
+    SELECT `pool_id` FROM `pool_participants`
+    GROUP BY `pool_id`, `serial_number` HAVING COUNT(*) > 1
+  ) d
+);
+
+ALTER TABLE `pool_participants` ADD UNIQUE INDEX `idx_pool_participants_serial` (`pool_id`, `serial_number`);
+
+ALTER TABLE `prize_pools` ADD COLUMN `collecting_slot` boolean NULL;
+ALTER TABLE `prize_pools` ADD UNIQUE INDEX `idx_prize_pools_collecting_slot` (`collecting_slot`);
+
+-- 已有多个收集中的奖池时只标记最早的一个，其余照常加满
+UPDATE `prize_pools` SET `collecting_slot` = true
+WHERE `id` = (
+  SELECT `id` FROM (
+    SELECT MIN(`id`) AS `id` FROM `prize_pools`
+    WHERE `status` = 'collecting' AND `deleted_at` IS NULL
+  ) m
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `frog_activations`;
+ALTER TABLE `frogs` DROP INDEX `idx_frogs_active_user_id`;
+ALTER TABLE `frogs` DROP COLUMN `active_user_id`;
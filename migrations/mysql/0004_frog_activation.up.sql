@@ -0,0 +1,31 @@
+-- 每个用户最多一只激活的青蛙，激活请求幂等
+
+-- 并发激活可能已产生多只激活的青蛙，只保留每个用户最新的一只
+UPDATE `frogs` f
+JOIN (
+  SELECT `user_id`, MAX(`id`) AS `keep_id` FROM `frogs`
+  WHERE `is_active` = true AND `deleted_at` IS NULL
+  GROUP BY `user_id`
+) k ON k.`user_id` = f.`user_id`
+SET f.`is_active` = false
+WHERE f.`is_active` = true AND f.`deleted_at` IS NULL AND f.`id` < k.`keep_id`;
+
+ALTER TABLE `frogs` ADD COLUMN `active_user_id` bigint unsigned NULL;
+UPDATE `frogs` SET `active_user_id` = `user_id` WHERE `is_active` = true AND `deleted_at` IS NULL;
+ALTER TABLE `frogs` ADD UNIQUE INDEX `idx_frogs_active_user_id` (`active_user_id`);
+
+CREATE TABLE IF NOT EXISTS `frog_activations` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `idempotency_key` varchar(64) NULL,
+  `transaction_hash` varchar(88) NOT NULL,
+  `status` varchar(20) NOT NULL,
+  `frog_id` bigint unsigned NULL,
+  `pool_id` bigint unsigned NULL,
+  `serial_number` bigint NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_frog_activations_key` (`user_id`, `idempotency_key`),
+  UNIQUE INDEX `idx_frog_activations_transaction_hash` (`transaction_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
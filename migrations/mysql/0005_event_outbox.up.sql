@@ -0,0 +1,18 @@
+-- 领域事件发件箱：与状态变更在同一事务中写入，提交后由投递器发布
+
+CREATE TABLE IF NOT EXISTS `outbox_messages` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `event_id` varchar(32) NOT NULL,
+  `type` varchar(64) NOT NULL,
+  `pool_id` bigint unsigned NOT NULL DEFAULT 0,
+  `payload` text NOT NULL,
+  `attempts` bigint NOT NULL DEFAULT 0,
+  `last_error` varchar(255) NULL,
+  `locked_until` datetime(3) NULL,
+  `dispatched_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_outbox_messages_event_id` (`event_id`),
+  INDEX `idx_outbox_messages_pool_id` (`pool_id`),
+  INDEX `idx_outbox_messages_dispatched_at` (`dispatched_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,16 @@
+-- 奖池状态机：记录每次状态变更，并为已有奖池补一条当前状态的记录
+
+CREATE TABLE IF NOT EXISTS `pool_transitions` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `pool_id` bigint unsigned NOT NULL,
+  `from_status` varchar(20) NULL,
+  `to_status` varchar(20) NOT NULL,
+  `reason` varchar(255) NULL,
+  `actor_user_id` bigint unsigned NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  INDEX `idx_pool_transitions_pool_id` (`pool_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT INTO `pool_transitions` (`created_at`, `pool_id`, `from_status`, `to_status`, `reason`, `actor_user_id`)
+SELECT NOW(3), `id`, '', `status`, 'migration:0006', 0 FROM `prize_pools`;
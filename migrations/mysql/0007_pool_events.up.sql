@@ -0,0 +1,12 @@
+-- 奖池事件存储：按奖池内序号追加，用于重放奖池的时间线
+
+CREATE TABLE IF NOT EXISTS `pool_events` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `pool_id` bigint unsigned NOT NULL,
+  `seq` bigint unsigned NOT NULL,
+  `type` varchar(64) NOT NULL,
+  `payload` text NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_pool_events_pool_seq` (`pool_id`, `seq`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,21 @@
+-- 延迟任务队列：到期执行、失败退避重试，重试次数用尽后留在死信列表
+
+CREATE TABLE IF NOT EXISTS `jobs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `type` varchar(64) NOT NULL,
+  `payload` text NOT NULL,
+  `dedup_key` varchar(128) NULL,
+  `status` varchar(16) NOT NULL,
+  `run_at` datetime(3) NOT NULL,
+  `attempts` bigint NOT NULL DEFAULT 0,
+  `max_attempts` bigint NOT NULL,
+  `last_error` varchar(255) NULL,
+  `locked_until` datetime(3) NULL,
+  `finished_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_jobs_dedup_key` (`dedup_key`),
+  INDEX `idx_jobs_type` (`type`),
+  INDEX `idx_jobs_status_run_at` (`status`, `run_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
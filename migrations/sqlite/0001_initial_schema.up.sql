@@ -0,0 +1,111 @@
+-- 初始表结构（SQLite），与 mysql/0001_initial_schema.up.sql 保持一致
+-- SQLite 数据库只由迁移创建，不存在缺少 role 列的旧表，建表时直接包含该列
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `deleted_at` datetime NULL,
+  `wallet_address` varchar(44) NULL,
+  `unclaimed_rewards` real DEFAULT 0,
+  `history_rewards` real DEFAULT 0,
+  `role` varchar(20) DEFAULT 'player'
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_wallet_address` ON `users` (`wallet_address`);
+CREATE INDEX IF NOT EXISTS `idx_users_deleted_at` ON `users` (`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `frogs` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `deleted_at` datetime NULL,
+  `user_id` integer NOT NULL,
+  `hunger_level` integer DEFAULT 100,
+  `is_active` numeric DEFAULT true,
+  `last_feed_time` datetime NULL,
+  CONSTRAINT `fk_frogs_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_frogs_deleted_at` ON `frogs` (`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `prize_pools` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `deleted_at` datetime NULL,
+  `status` varchar(20) NOT NULL,
+  `current_players` integer DEFAULT 0,
+  `prize_amount` decimal(10,4) NULL,
+  `big_prize_winner` varchar(44) NULL,
+  `current_big_prize_holder` varchar(44) NULL,
+  `completed_at` datetime NULL
+);
+CREATE INDEX IF NOT EXISTS `idx_prize_pools_deleted_at` ON `prize_pools` (`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `pool_participants` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `deleted_at` datetime NULL,
+  `pool_id` integer NOT NULL,
+  `frog_id` integer NOT NULL,
+  `wallet_address` varchar(44) NULL,
+  `serial_number` integer NOT NULL,
+  `joined_at` datetime NULL,
+  CONSTRAINT `fk_prize_pools_participants` FOREIGN KEY (`pool_id`) REFERENCES `prize_pools` (`id`),
+  CONSTRAINT `fk_pool_participants_frog` FOREIGN KEY (`frog_id`) REFERENCES `frogs` (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_pool_participants_deleted_at` ON `pool_participants` (`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `role_audit_logs` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `deleted_at` datetime NULL,
+  `target_user_id` integer NOT NULL,
+  `target_wallet` varchar(44) NULL,
+  `old_role` varchar(20) NULL,
+  `new_role` varchar(20) NULL,
+  `actor_user_id` integer NULL,
+  `actor_wallet` varchar(44) NULL,
+  `reason` varchar(255) NULL
+);
+CREATE INDEX IF NOT EXISTS `idx_role_audit_logs_deleted_at` ON `role_audit_logs` (`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_role_audit_logs_target_user_id` ON `role_audit_logs` (`target_user_id`);
+CREATE INDEX IF NOT EXISTS `idx_role_audit_logs_actor_user_id` ON `role_audit_logs` (`actor_user_id`);
+
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `deleted_at` datetime NULL,
+  `name` varchar(64) NOT NULL,
+  `prefix` varchar(16) NULL,
+  `key_hash` varchar(64) NULL,
+  `scopes` varchar(255) NULL,
+  `rate_limit` integer DEFAULT 60,
+  `created_by_user_id` integer NULL,
+  `last_used_at` datetime NULL,
+  `revoked_at` datetime NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_api_keys_key_hash` ON `api_keys` (`key_hash`);
+CREATE INDEX IF NOT EXISTS `idx_api_keys_deleted_at` ON `api_keys` (`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_api_keys_created_by_user_id` ON `api_keys` (`created_by_user_id`);
+CREATE INDEX IF NOT EXISTS `idx_api_keys_revoked_at` ON `api_keys` (`revoked_at`);
+
+CREATE TABLE IF NOT EXISTS `user_sessions` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `deleted_at` datetime NULL,
+  `session_id` varchar(64) NULL,
+  `user_id` integer NOT NULL,
+  `device` varchar(64) NULL,
+  `user_agent` varchar(255) NULL,
+  `ip` varchar(64) NULL,
+  `last_seen_at` datetime NULL,
+  `revoked_at` datetime NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_user_sessions_session_id` ON `user_sessions` (`session_id`);
+CREATE INDEX IF NOT EXISTS `idx_user_sessions_deleted_at` ON `user_sessions` (`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_user_sessions_user_id` ON `user_sessions` (`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_user_sessions_revoked_at` ON `user_sessions` (`revoked_at`);
@@ -0,0 +1,58 @@
+-- 复式记账的奖励账本（SQLite），与 mysql/0002_reward_ledger.up.sql 保持一致
+
+CREATE TABLE IF NOT EXISTS `ledger_entries` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `kind` varchar(32) NOT NULL,
+  `reference` varchar(64) NULL
+);
+CREATE INDEX IF NOT EXISTS `idx_ledger_entries_kind` ON `ledger_entries` (`kind`);
+CREATE INDEX IF NOT EXISTS `idx_ledger_entries_reference` ON `ledger_entries` (`reference`);
+
+CREATE TABLE IF NOT EXISTS `ledger_postings` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `entry_id` integer NOT NULL,
+  `account` varchar(32) NOT NULL,
+  `owner_id` integer NOT NULL DEFAULT 0,
+  `amount` integer NOT NULL,
+  CONSTRAINT `fk_ledger_entries_postings` FOREIGN KEY (`entry_id`) REFERENCES `ledger_entries` (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_ledger_postings_entry_id` ON `ledger_postings` (`entry_id`);
+CREATE INDEX IF NOT EXISTS `idx_ledger_postings_account` ON `ledger_postings` (`account`, `owner_id`);
+
+-- 启用账本前用户已有的余额与未结束奖池中的奖金记为一笔期初分录，对应的资金视为从金库流入
+-- 尚未结束且没有获胜者的奖池持有参与者支付的激活费（每人 0.01 SOL，最多为奖池金额），
+-- 记账后发奖或没收时奖池余额不会变为负数
+INSERT INTO `ledger_entries` (`created_at`, `kind`, `reference`)
+SELECT CURRENT_TIMESTAMP, 'opening-balance', 'migration:0002'
+WHERE EXISTS (
+  SELECT 1 FROM `users`
+  WHERE `deleted_at` IS NULL AND (`unclaimed_rewards` <> 0 OR `history_rewards` <> 0)
+) OR EXISTS (
+  SELECT 1 FROM `prize_pools`
+  WHERE `deleted_at` IS NULL AND `status` IN ('collecting', 'active')
+    AND COALESCE(`big_prize_winner`, '') = '' AND `current_players` > 0 AND `prize_amount` > 0
+);
+
+INSERT INTO `ledger_postings` (`created_at`, `entry_id`, `account`, `owner_id`, `amount`)
+SELECT CURRENT_TIMESTAMP, e.`id`, 'user-unclaimed', u.`id`, CAST(ROUND(u.`unclaimed_rewards` * 1000000000) AS INTEGER)
+FROM `users` u JOIN `ledger_entries` e ON e.`reference` = 'migration:0002'
+WHERE u.`deleted_at` IS NULL AND u.`unclaimed_rewards` <> 0;
+
+INSERT INTO `ledger_postings` (`created_at`, `entry_id`, `account`, `owner_id`, `amount`)
+SELECT CURRENT_TIMESTAMP, e.`id`, 'user-paid', u.`id`, CAST(ROUND(u.`history_rewards` * 1000000000) AS INTEGER)
+FROM `users` u JOIN `ledger_entries` e ON e.`reference` = 'migration:0002'
+WHERE u.`deleted_at` IS NULL AND u.`history_rewards` <> 0;
+
+INSERT INTO `ledger_postings` (`created_at`, `entry_id`, `account`, `owner_id`, `amount`)
+SELECT CURRENT_TIMESTAMP, e.`id`, 'pool-prize', p.`id`, MIN(p.`current_players` * 10000000, CAST(ROUND(p.`prize_amount` * 1000000000) AS INTEGER))
+FROM `prize_pools` p JOIN `ledger_entries` e ON e.`reference` = 'migration:0002'
+WHERE p.`deleted_at` IS NULL AND p.`status` IN ('collecting', 'active')
+  AND COALESCE(p.`big_prize_winner`, '') = '' AND p.`current_players` > 0 AND p.`prize_amount` > 0;
+
+INSERT INTO `ledger_postings` (`created_at`, `entry_id`, `account`, `owner_id`, `amount`)
+SELECT CURRENT_TIMESTAMP, e.`id`, 'treasury', 0, -SUM(p.`amount`)
+FROM `ledger_entries` e JOIN `ledger_postings` p ON p.`entry_id` = e.`id`
+WHERE e.`reference` = 'migration:0002'
+GROUP BY e.`id`;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS `idx_prize_pools_collecting_slot`;
+ALTER TABLE `prize_pools` DROP COLUMN `collecting_slot`;
+DROP INDEX IF EXISTS `idx_pool_participants_serial`;
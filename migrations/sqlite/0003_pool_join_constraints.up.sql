@@ -0,0 +1,24 @@
+-- 加入奖池的并发约束（SQLite），与 mysql/0003_pool_join_constraints.up.sql 保持一致
+
+-- 并发加入可能已产生重复序号，按加入顺序重新编号
+UPDATE `pool_participants`
+SET `serial_number` = (
+  SELECT COUNT(*) FROM `pool_participants` b
+  WHERE b.`pool_id` = `pool_participants`.`pool_id` AND b.`id` <= `pool_participants`.`id`
+)
+WHERE `pool_id` IN (
+  SELECT `pool_id` FROM `pool_participants`
+  GROUP BY `pool_id`, `serial_number` HAVING COUNT(*) > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_pool_participants_serial` ON `pool_participants` (`pool_id`, `serial_number`);
+
+ALTER TABLE `prize_pools` ADD COLUMN `collecting_slot` numeric NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_prize_pools_collecting_slot` ON `prize_pools` (`collecting_slot`);
+
+-- 已有多个收集中的奖池时只标记最早的一个，其余照常加满
+UPDATE `prize_pools` SET `collecting_slot` = true
+WHERE `id` = (
+  SELECT MIN(`id`) FROM `prize_pools`
+  WHERE `status` = 'collecting' AND `deleted_at` IS NULL
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `frog_activations`;
+DROP INDEX IF EXISTS `idx_frogs_active_user_id`;
+ALTER TABLE `frogs` DROP COLUMN `active_user_id`;
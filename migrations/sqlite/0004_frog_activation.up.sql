@@ -0,0 +1,28 @@
+-- 每个用户最多一只激活的青蛙，激活请求幂等（SQLite），与 mysql/0004_frog_activation.up.sql 保持一致
+
+-- 并发激活可能已产生多只激活的青蛙，只保留每个用户最新的一只
+UPDATE `frogs` SET `is_active` = false
+WHERE `is_active` = true AND `deleted_at` IS NULL
+  AND `id` < (
+    SELECT MAX(f.`id`) FROM `frogs` f
+    WHERE f.`user_id` = `frogs`.`user_id` AND f.`is_active` = true AND f.`deleted_at` IS NULL
+  );
+
+ALTER TABLE `frogs` ADD COLUMN `active_user_id` integer NULL;
+UPDATE `frogs` SET `active_user_id` = `user_id` WHERE `is_active` = true AND `deleted_at` IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_frogs_active_user_id` ON `frogs` (`active_user_id`);
+
+CREATE TABLE IF NOT EXISTS `frog_activations` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `user_id` integer NOT NULL,
+  `idempotency_key` varchar(64) NULL,
+  `transaction_hash` varchar(88) NOT NULL,
+  `status` varchar(20) NOT NULL,
+  `frog_id` integer NULL,
+  `pool_id` integer NULL,
+  `serial_number` integer NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_frog_activations_key` ON `frog_activations` (`user_id`, `idempotency_key`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_frog_activations_transaction_hash` ON `frog_activations` (`transaction_hash`);
@@ -0,0 +1,17 @@
+-- 领域事件发件箱（SQLite），与 mysql/0005_event_outbox.up.sql 保持一致
+
+CREATE TABLE IF NOT EXISTS `outbox_messages` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `event_id` varchar(32) NOT NULL,
+  `type` varchar(64) NOT NULL,
+  `pool_id` integer NOT NULL DEFAULT 0,
+  `payload` text NOT NULL,
+  `attempts` integer NOT NULL DEFAULT 0,
+  `last_error` varchar(255) NULL,
+  `locked_until` datetime NULL,
+  `dispatched_at` datetime NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_outbox_messages_event_id` ON `outbox_messages` (`event_id`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_messages_pool_id` ON `outbox_messages` (`pool_id`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_messages_dispatched_at` ON `outbox_messages` (`dispatched_at`);
@@ -0,0 +1,15 @@
+-- 奖池状态机（SQLite），与 mysql/0006_pool_state_machine.up.sql 保持一致
+
+CREATE TABLE IF NOT EXISTS `pool_transitions` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `pool_id` integer NOT NULL,
+  `from_status` varchar(20) NULL,
+  `to_status` varchar(20) NOT NULL,
+  `reason` varchar(255) NULL,
+  `actor_user_id` integer NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS `idx_pool_transitions_pool_id` ON `pool_transitions` (`pool_id`);
+
+INSERT INTO `pool_transitions` (`created_at`, `pool_id`, `from_status`, `to_status`, `reason`, `actor_user_id`)
+SELECT CURRENT_TIMESTAMP, `id`, '', `status`, 'migration:0006', 0 FROM `prize_pools`;
@@ -0,0 +1,11 @@
+-- 奖池事件存储（SQLite），与 mysql/0007_pool_events.up.sql 保持一致
+
+CREATE TABLE IF NOT EXISTS `pool_events` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `pool_id` integer NOT NULL,
+  `seq` integer NOT NULL,
+  `type` varchar(64) NOT NULL,
+  `payload` text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_pool_events_pool_seq` ON `pool_events` (`pool_id`, `seq`);
@@ -0,0 +1,20 @@
+-- 延迟任务队列（SQLite），与 mysql/0008_job_queue.up.sql 保持一致
+
+CREATE TABLE IF NOT EXISTS `jobs` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime NULL,
+  `updated_at` datetime NULL,
+  `type` varchar(64) NOT NULL,
+  `payload` text NOT NULL,
+  `dedup_key` varchar(128) NULL,
+  `status` varchar(16) NOT NULL,
+  `run_at` datetime NOT NULL,
+  `attempts` integer NOT NULL DEFAULT 0,
+  `max_attempts` integer NOT NULL,
+  `last_error` varchar(255) NULL,
+  `locked_until` datetime NULL,
+  `finished_at` datetime NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_jobs_dedup_key` ON `jobs` (`dedup_key`);
+CREATE INDEX IF NOT EXISTS `idx_jobs_type` ON `jobs` (`type`);
+CREATE INDEX IF NOT EXISTS `idx_jobs_status_run_at` ON `jobs` (`status`, `run_at`);
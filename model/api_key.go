@@ -0,0 +1,89 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey 服务端集成使用的API密钥，只保存哈希
+type APIKey struct {
+	gorm.Model
+	Name            string     `gorm:"size:64;not null"`    // 集成名称
+	Prefix          string     `gorm:"size:16"`             // 明文前缀，用于辨认密钥
+	KeyHash         string     `gorm:"size:64;uniqueIndex"` // 密钥SHA-256哈希
+	Scopes          string     `gorm:"size:255"`            // 授权范围，逗号分隔
+	RateLimit       int        `gorm:"default:60"`          // 每分钟请求数
+	CreatedByUserID uint       `gorm:"index"`               // 创建者用户ID
+	LastUsedAt      *time.Time // 最近使用时间
+	RevokedAt       *time.Time `gorm:"index"` // 吊销时间
+}
+
+// ScopeList 授权范围列表
+func (key *APIKey) ScopeList() []string {
+	if key.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(key.Scopes, ",")
+}
+
+// IsRevoked 是否已吊销
+func (key *APIKey) IsRevoked() bool {
+	return key.RevokedAt != nil
+}
+
+// CreateAPIKey 创建API密钥
+func CreateAPIKey(name, prefix, keyHash string, scopes []string, rateLimit int, createdBy uint) (APIKey, error) {
+	key := APIKey{
+		Name:            name,
+		Prefix:          prefix,
+		KeyHash:         keyHash,
+		Scopes:          strings.Join(scopes, ","),
+		RateLimit:       rateLimit,
+		CreatedByUserID: createdBy,
+	}
+	result := DB.Create(&key)
+	return key, result.Error
+}
+
+// GetActiveAPIKeyByHash 通过哈希获取未吊销的API密钥
+func GetActiveAPIKeyByHash(keyHash string) (APIKey, error) {
+	var key APIKey
+	result := DB.Where("key_hash = ? AND revoked_at IS NULL", keyHash).First(&key)
+	return key, result.Error
+}
+
+// GetAPIKey 用ID获取API密钥
+func GetAPIKey(ID interface{}) (APIKey, error) {
+	var key APIKey
+	result := DB.First(&key, ID)
+	return key, result.Error
+}
+
+// ListAPIKeys 列出所有API密钥
+func ListAPIKeys() ([]APIKey, error) {
+	var keys []APIKey
+	result := DB.Order("id DESC").Find(&keys)
+	return keys, result.Error
+}
+
+// Revoke 吊销API密钥
+func (key *APIKey) Revoke() error {
+	now := time.Now()
+	if err := DB.Model(key).Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	key.RevokedAt = &now
+	return nil
+}
+
+// MarkUsed 记录API密钥的使用时间，一分钟内只写一次
+func (key *APIKey) MarkUsed() error {
+	now := time.Now()
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < time.Minute {
+		return nil
+	}
+	key.LastUsedAt = &now
+	return DB.Model(key).Update("last_used_at", now).Error
+}
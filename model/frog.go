@@ -9,7 +9,8 @@ import (
 )
 
 const (
-	MaxHungerLevel = 100 // 最大饥饿值
+	MaxHungerLevel      = 100             // 最大饥饿值
+	HungerDecayInterval = 3 * time.Second // 饥饿值每降低1所需的时间
 )
 
 // Frog 青蛙模型
@@ -19,36 +20,38 @@ type Frog struct {
 	User         User      `gorm:"foreignKey:UserID"` // 关联用户
 	HungerLevel  int       `gorm:"default:100"`       // 饥饿值 0-100
 	IsActive     bool      `gorm:"default:true"`      // 是否激活
-	LastFeedTime time.Time `gorm:"type:timestamp"`    // 上次投喂时间
+	ActiveUserID *uint     `gorm:"uniqueIndex"`       // 激活时等于UserID，停用为NULL；唯一索引保证每个用户最多一只激活的青蛙
+	LastFeedTime time.Time // 上次投喂时间
 }
 
-// CreateFrog 创建青蛙
-func CreateFrog(userID uint) (Frog, error) {
-	frog := Frog{
+// NewFrog 新建满饥饿值的激活青蛙（未保存）
+func NewFrog(userID uint) Frog {
+	return Frog{
 		UserID:       userID,
-		HungerLevel:  100,
+		HungerLevel:  MaxHungerLevel,
 		IsActive:     true,
 		LastFeedTime: time.Now(),
 	}
-	result := DB.Create(&frog)
-	return frog, result.Error
 }
 
-// GetFrogByUserID 通过用户ID获取青蛙
-func GetFrogByUserID(userID uint) (*Frog, error) {
-	var frog Frog
-	result := DB.Where("user_id = ? AND is_active = ?", userID, true).First(&frog)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, nil
-		}
-		return nil, result.Error
+// BeforeSave 保存前根据激活状态同步ActiveUserID
+func (frog *Frog) BeforeSave(tx *gorm.DB) error {
+	frog.SyncActiveUser()
+	return nil
+}
+
+// SyncActiveUser 根据IsActive设置ActiveUserID
+func (frog *Frog) SyncActiveUser() {
+	if frog.IsActive {
+		userID := frog.UserID
+		frog.ActiveUserID = &userID
+	} else {
+		frog.ActiveUserID = nil
 	}
-	return &frog, nil
 }
 
-// UpdateHungerLevel 更新饥饿值
-func (frog *Frog) UpdateHungerLevel(newLevel int) error {
+// SetHungerLevel 设置饥饿值（限制在0-100之间）并记录投喂时间，饥饿值为0时停用
+func (frog *Frog) SetHungerLevel(newLevel int) {
 	if newLevel < 0 {
 		newLevel = 0
 	} else if newLevel > MaxHungerLevel {
@@ -63,37 +66,27 @@ func (frog *Frog) UpdateHungerLevel(newLevel int) error {
 		frog.IsActive = false
 		log.Printf("Frog %d has been deactivated due to hunger level reaching 0", frog.ID)
 	}
-
-	return DB.Save(frog).Error
-}
-
-// IsRecordNotFoundError 检查是否是记录未找到错误
-func IsRecordNotFoundError(err error) bool {
-	return errors.Is(err, gorm.ErrRecordNotFound)
 }
 
-// GetCurrentActivePool 获取用户当前活跃的奖池
-func GetCurrentActivePool(userID uint) (*PrizePool, error) {
-	// 先获取用户的青蛙
-	frog, err := GetFrogByUserID(userID)
-	if err != nil {
-		return nil, err
+// CurrentHunger 按距上次投喂的时间计算now时的饥饿值
+// 保存的饥饿值只在投喂与饿死时更新，显示与投喂时以此为准；已停用的青蛙返回保存的值
+func (frog *Frog) CurrentHunger(now time.Time) int {
+	if !frog.IsActive {
+		return frog.HungerLevel
 	}
-	if frog == nil {
-		return nil, nil
+	level := frog.HungerLevel - int(now.Sub(frog.LastFeedTime)/HungerDecayInterval)
+	if level < 0 {
+		level = 0
 	}
+	return level
+}
 
-	var pool PrizePool
-	result := DB.Joins("JOIN pool_participants ON pool_participants.pool_id = prize_pools.id").
-		Where("pool_participants.frog_id = ? AND prize_pools.status != ?", frog.ID, PoolStatusCompleted).
-		Order("prize_pools.created_at DESC").
-		First(&pool)
+// StarvesAt 不再投喂时饥饿值降至0的时间
+func (frog *Frog) StarvesAt() time.Time {
+	return frog.LastFeedTime.Add(time.Duration(frog.HungerLevel) * HungerDecayInterval)
+}
 
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, nil
-		}
-		return nil, result.Error
-	}
-	return &pool, nil
+// IsRecordNotFoundError 检查是否是记录未找到错误
+func IsRecordNotFoundError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
 }
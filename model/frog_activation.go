@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ActivationStatus 激活请求的处理状态
+type ActivationStatus string
+
+const (
+	ActivationPending    ActivationStatus = "pending"    // 已校验支付，正在处理
+	ActivationJoined     ActivationStatus = "joined"     // 已创建青蛙并加入奖池
+	ActivationRefundable ActivationStatus = "refundable" // 支付时已有激活的青蛙，待退款
+)
+
+// FrogActivation 激活请求记录
+//
+// 每笔支付只能激活一次（TransactionHash唯一），客户端提供的幂等键在同一用户下唯一，
+// 重复的请求直接返回首次处理的结果。
+type FrogActivation struct {
+	ID              uint `gorm:"primarykey"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	UserID          uint             `gorm:"not null;uniqueIndex:idx_frog_activations_key"`
+	IdempotencyKey  *string          `gorm:"size:64;uniqueIndex:idx_frog_activations_key"` // 未提供时为NULL
+	TransactionHash string           `gorm:"size:88;not null;uniqueIndex"`
+	Status          ActivationStatus `gorm:"size:20;not null"`
+	FrogID          *uint            // 创建的青蛙
+	PoolID          *uint            // 加入的奖池
+	SerialNumber    int              // 在奖池中的序号
+}
@@ -6,7 +6,6 @@ import (
 	"singo/util"
 	"time"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -14,7 +13,7 @@ import (
 // DB 数据库链接单例
 var DB *gorm.DB
 
-// Database 在中间件中初始化mysql链接
+// Database 在中间件中初始化数据库链接，驱动由DSN前缀决定（MySQL或SQLite）
 func Database(connString string) {
 	// 初始化GORM日志配置
 	newLogger := logger.New(
@@ -27,17 +26,25 @@ func Database(connString string) {
 		},
 	)
 
-	db, err := gorm.Open(mysql.Open(connString), &gorm.Config{
+	dialector, err := openDialector(connString)
+	if err != nil {
+		util.Log().Error("database lost: %v", err)
+		panic(err)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: newLogger,
+		// 将唯一约束冲突统一转换为 gorm.ErrDuplicatedKey
+		TranslateError: true,
 	})
 	// Error
-	if connString == "" || err != nil {
-		util.Log().Error("mysql lost: %v", err)
+	if err != nil {
+		util.Log().Error("database lost: %v", err)
 		panic(err)
 	}
 	sqlDB, err := db.DB()
 	if err != nil {
-		util.Log().Error("mysql lost: %v", err)
+		util.Log().Error("database lost: %v", err)
 		panic(err)
 	}
 
@@ -46,7 +53,8 @@ func Database(connString string) {
 	sqlDB.SetMaxIdleConns(10)
 	//打开
 	sqlDB.SetMaxOpenConns(20)
+	if isMemorySQLite(connString) {
+		sqlDB.SetMaxOpenConns(1)
+	}
 	DB = db
-
-	Migration()
 }
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobStatus 延迟任务状态
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending" // 等待到期执行
+	JobRunning JobStatus = "running" // 已被执行器认领
+	JobDone    JobStatus = "done"    // 执行成功
+	JobDead    JobStatus = "dead"    // 重试次数用尽，进入死信列表
+)
+
+// Job 持久化的延迟任务
+//
+// 执行器按 RunAt 认领到期的任务，失败时按退避时间重试，重试次数用尽后标记为死信。
+type Job struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Type        string     `gorm:"size:64;not null;index"`
+	Payload     string     `gorm:"type:text;not null"`   // JSON编码的任务参数
+	DedupKey    *string    `gorm:"size:128;uniqueIndex"` // 未结束的任务中唯一，结束后清空
+	Status      JobStatus  `gorm:"size:16;not null;index:idx_jobs_status_run_at"`
+	RunAt       time.Time  `gorm:"not null;index:idx_jobs_status_run_at"`
+	Attempts    int        `gorm:"not null;default:0"`
+	MaxAttempts int        `gorm:"not null"`
+	LastError   string     `gorm:"size:255"`
+	LockedUntil *time.Time // 执行器认领的租约，到期未完成时可被重新认领
+	FinishedAt  *time.Time
+}
+
+// NewJob 新建待执行的任务（未保存），dedupKey为空时不去重
+func NewJob(jobType string, payload interface{}, runAt time.Time, maxAttempts int, dedupKey string) (Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      JobPending,
+		RunAt:       runAt,
+		MaxAttempts: maxAttempts,
+	}
+	if dedupKey != "" {
+		job.DedupKey = &dedupKey
+	}
+	return job, nil
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// LamportsPerSOL 1 SOL = 10^9 lamports，账本金额以lamports整数记录，避免浮点累计误差
+const LamportsPerSOL = 1000000000
+
+// LedgerAccount 账本科目
+//
+// 余额为正表示该科目持有的资金：
+// treasury 为外部流入的资金（玩家支付时记为负），user-paid 为已付给玩家的资金，
+// 两者与其余科目之和恒为0。
+type LedgerAccount string
+
+const (
+	AccountTreasury      LedgerAccount = "treasury"       // 金库，玩家支付的激活费从这里流入
+	AccountPoolPrize     LedgerAccount = "pool-prize"     // 奖池奖金，按奖池ID区分
+	AccountHouseRake     LedgerAccount = "house-rake"     // 平台抽成及无人获胜奖池的奖金
+	AccountUserUnclaimed LedgerAccount = "user-unclaimed" // 玩家未领取的奖励，按用户ID区分
+	AccountUserPaid      LedgerAccount = "user-paid"      // 已付给玩家的奖励，按用户ID区分
+	AccountUserRefund    LedgerAccount = "user-refund"    // 待退还给玩家的激活费，按用户ID区分
+)
+
+// 分录类型
+const (
+	EntryActivation = "activation"      // 玩家支付激活费进入奖池
+	EntryRefundable = "refundable"      // 玩家已有激活的青蛙，激活费待退还
+	EntryPrize      = "prize"           // 奖池奖金发给获胜者
+	EntryForfeit    = "forfeit"         // 奖池无人获胜，奖金归平台
+	EntryPoolRefund = "pool-refund"     // 奖池未开始即取消，激活费退还给玩家
+	EntryClaim      = "claim"           // 玩家提取奖励
+	EntryOpening    = "opening-balance" // 启用账本前已有的余额
+)
+
+// ErrUnbalanced 分录借贷不平衡
+var ErrUnbalanced = errors.New("ledger entry is not balanced")
+
+// LedgerEntry 账本分录，同一分录的所有记账金额之和必须为0
+type LedgerEntry struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	Kind      string          `gorm:"size:32;not null;index"` // 分录类型
+	Reference string          `gorm:"size:64;index"`          // 关联对象，如 pool:12
+	Postings  []LedgerPosting `gorm:"foreignKey:EntryID"`
+}
+
+// LedgerPosting 分录中的一笔记账
+type LedgerPosting struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	EntryID   uint          `gorm:"not null;index"`
+	Account   LedgerAccount `gorm:"size:32;not null;index:idx_ledger_postings_account"`
+	OwnerID   uint          `gorm:"not null;default:0;index:idx_ledger_postings_account"` // 用户或奖池ID，全局科目为0
+	Amount    int64         `gorm:"not null"`                                             // lamports，可为负
+}
+
+// NewLedgerEntry 新建分录（未保存）
+func NewLedgerEntry(kind string, reference string) *LedgerEntry {
+	return &LedgerEntry{Kind: kind, Reference: reference}
+}
+
+// Post 追加一笔记账，金额为0时忽略
+func (entry *LedgerEntry) Post(account LedgerAccount, ownerID uint, amount int64) *LedgerEntry {
+	if amount != 0 {
+		entry.Postings = append(entry.Postings, LedgerPosting{
+			Account: account,
+			OwnerID: ownerID,
+			Amount:  amount,
+		})
+	}
+	return entry
+}
+
+// Validate 检查分录至少包含两笔记账且金额之和为0
+func (entry *LedgerEntry) Validate() error {
+	if len(entry.Postings) < 2 {
+		return fmt.Errorf("%w: %s has %d postings", ErrUnbalanced, entry.Kind, len(entry.Postings))
+	}
+	var sum int64
+	for _, p := range entry.Postings {
+		sum += p.Amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("%w: %s sums to %d", ErrUnbalanced, entry.Kind, sum)
+	}
+	return nil
+}
+
+// PoolReference 奖池的分录关联标识
+func PoolReference(poolID uint) string {
+	return fmt.Sprintf("pool:%d", poolID)
+}
+
+// PayoutReference 奖励提取的分录关联标识，以链上交易签名区分每次提取
+func PayoutReference(txHash string) string {
+	return "payout:" + txHash
+}
+
+// ToLamports SOL转换为lamports（四舍五入）
+func ToLamports(sol float64) int64 {
+	return int64(math.Round(sol * LamportsPerSOL))
+}
+
+// ToSOL lamports转换为SOL
+func ToSOL(lamports int64) float64 {
+	return float64(lamports) / LamportsPerSOL
+}
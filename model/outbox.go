@@ -0,0 +1,44 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// OutboxMessage 发件箱中待投递的领域事件
+//
+// 与产生事件的状态变更在同一事务中写入，提交后由投递器发布到事件总线；
+// 投递至少一次，消费方按EventID去重。
+type OutboxMessage struct {
+	ID           uint `gorm:"primarykey"`
+	CreatedAt    time.Time
+	EventID      string     `gorm:"size:32;not null;uniqueIndex"` // 去重ID
+	Type         string     `gorm:"size:64;not null"`
+	PoolID       uint       `gorm:"not null;default:0;index"`
+	Payload      string     `gorm:"type:text;not null"` // JSON编码的事件
+	Attempts     int        `gorm:"not null;default:0"`
+	LastError    string     `gorm:"size:255"`
+	LockedUntil  *time.Time // 投递器认领的租约，到期未完成投递时可被重新认领
+	DispatchedAt *time.Time `gorm:"index"` // 已发布到事件总线的时间，未投递时为NULL
+}
+
+// NewOutboxMessage 将事件编码为发件箱消息，生成新的去重ID
+func NewOutboxMessage(eventType string, poolID uint, payload interface{}) (OutboxMessage, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return OutboxMessage{}, err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+
+	return OutboxMessage{
+		EventID: hex.EncodeToString(b),
+		Type:    eventType,
+		PoolID:  poolID,
+		Payload: string(data),
+	}, nil
+}
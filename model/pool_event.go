@@ -0,0 +1,31 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PoolEvent 奖池事件存储中的一条事件，只追加不修改
+//
+// 与产生事件的状态变更在同一事务中写入，按 Seq 重放可还原奖池在任意时刻的状态。
+type PoolEvent struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	PoolID    uint   `gorm:"not null;uniqueIndex:idx_pool_events_pool_seq"`
+	Seq       uint   `gorm:"not null;uniqueIndex:idx_pool_events_pool_seq"` // 奖池内从1开始的序号
+	Type      string `gorm:"size:64;not null"`
+	Payload   string `gorm:"type:text;not null"` // JSON编码的事件
+}
+
+// NewPoolEvent 将事件编码为奖池事件，序号由仓库在追加时分配
+func NewPoolEvent(eventType string, poolID uint, payload interface{}) (PoolEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return PoolEvent{}, err
+	}
+	return PoolEvent{
+		PoolID:  poolID,
+		Type:    eventType,
+		Payload: string(data),
+	}, nil
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidTransition 奖池状态不允许切换到目标状态
+var ErrInvalidTransition = errors.New("invalid pool status transition")
+
+// TransitionError 奖池状态切换失败，可用 errors.Is(err, ErrInvalidTransition) 判断
+type TransitionError struct {
+	PoolID uint
+	From   PoolStatus
+	To     PoolStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("pool %d cannot transition from %s to %s", e.PoolID, e.From, e.To)
+}
+
+// Is 与 ErrInvalidTransition 匹配
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// poolTransitions 每个状态允许切换到的状态，completed 与 cancelled 为终态
+var poolTransitions = map[PoolStatus][]PoolStatus{
+	PoolStatusCollecting: {PoolStatusActive, PoolStatusCancelled},
+	PoolStatusActive:     {PoolStatusSettling, PoolStatusCompleted, PoolStatusCancelled, PoolStatusDisputed},
+	PoolStatusSettling:   {PoolStatusCompleted, PoolStatusDisputed},
+	PoolStatusDisputed:   {PoolStatusSettling, PoolStatusCompleted, PoolStatusCancelled},
+}
+
+// CanTransitionTo 是否允许切换到目标状态
+func (s PoolStatus) CanTransitionTo(to PoolStatus) bool {
+	for _, next := range poolTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Live 游戏是否仍在进行（收集中或活跃中）
+func (s PoolStatus) Live() bool {
+	return s == PoolStatusCollecting || s == PoolStatusActive
+}
+
+// Valid 是否为已定义的状态
+func (s PoolStatus) Valid() bool {
+	switch s {
+	case PoolStatusCollecting, PoolStatusActive, PoolStatusSettling,
+		PoolStatusCompleted, PoolStatusCancelled, PoolStatusDisputed:
+		return true
+	}
+	return false
+}
+
+// PoolTransition 奖池状态变更记录
+type PoolTransition struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	PoolID      uint       `gorm:"not null;index"`
+	FromStatus  PoolStatus `gorm:"size:20"` // 新建奖池时为空
+	ToStatus    PoolStatus `gorm:"size:20;not null"`
+	Reason      string     `gorm:"size:255"`
+	ActorUserID uint       `gorm:"not null;default:0"` // 操作者用户ID，0表示系统
+}
+
+// Transition 校验并切换状态，返回待保存的变更记录
+func (pool *PrizePool) Transition(to PoolStatus, reason string, actorUserID uint) (*PoolTransition, error) {
+	from := pool.Status
+	if !from.CanTransitionTo(to) {
+		return nil, &TransitionError{PoolID: pool.ID, From: from, To: to}
+	}
+
+	pool.Status = to
+	if from == PoolStatusCollecting {
+		// 离开收集中后释放标记，允许创建下一个奖池
+		pool.CollectingSlot = nil
+	}
+	if to == PoolStatusSettling || to == PoolStatusCompleted || to == PoolStatusCancelled {
+		if pool.CompletedAt == nil {
+			now := time.Now()
+			pool.CompletedAt = &now
+		}
+	}
+
+	return &PoolTransition{
+		PoolID:      pool.ID,
+		FromStatus:  from,
+		ToStatus:    to,
+		Reason:      reason,
+		ActorUserID: actorUserID,
+	}, nil
+}
+
+// CreatedTransition 新建奖池的变更记录
+func (pool *PrizePool) CreatedTransition() *PoolTransition {
+	return &PoolTransition{
+		PoolID:   pool.ID,
+		ToStatus: pool.Status,
+		Reason:   "created",
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// RoleAuditLog 权限变更审计记录
+type RoleAuditLog struct {
+	gorm.Model
+	TargetUserID uint   `gorm:"not null;index"` // 被修改的用户ID
+	TargetWallet string `gorm:"size:44"`        // 被修改的用户钱包地址
+	OldRole      string `gorm:"size:20"`        // 修改前角色
+	NewRole      string `gorm:"size:20"`        // 修改后角色
+	ActorUserID  uint   `gorm:"index"`          // 操作者用户ID，0表示系统
+	ActorWallet  string `gorm:"size:44"`        // 操作者钱包地址
+	Reason       string `gorm:"size:255"`       // 修改原因
+}
+
+// NewRoleAuditLog 记录将user的角色改为newRole
+// actor为nil表示系统操作（如启动时根据配置引导管理员）
+func NewRoleAuditLog(user *User, newRole string, actor *User, reason string) RoleAuditLog {
+	audit := RoleAuditLog{
+		TargetUserID: user.ID,
+		TargetWallet: user.WalletAddress,
+		OldRole:      user.Role,
+		NewRole:      newRole,
+		Reason:       reason,
+	}
+	if actor != nil {
+		audit.ActorUserID = actor.ID
+		audit.ActorWallet = actor.WalletAddress
+	}
+	return audit
+}
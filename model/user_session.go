@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserSession 用户登录会话，每次登录创建一条，刷新令牌沿用同一会话
+type UserSession struct {
+	gorm.Model
+	SessionID  string     `gorm:"size:64;uniqueIndex"` // 会话ID，写入访问令牌的sid声明
+	UserID     uint       `gorm:"not null;index"`      // 关联用户ID
+	Device     string     `gorm:"size:64"`             // 设备描述，如 "Chrome on macOS"
+	UserAgent  string     `gorm:"size:255"`            // 登录时的User-Agent
+	IP         string     `gorm:"size:64"`             // 最近一次请求的IP
+	LastSeenAt time.Time  // 最近活跃时间
+	RevokedAt  *time.Time `gorm:"index"` // 吊销时间
+}
+
+// CreateUserSession 创建登录会话
+func CreateUserSession(userID uint, sessionID, device, userAgent, ip string) (UserSession, error) {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := UserSession{
+		SessionID:  sessionID,
+		UserID:     userID,
+		Device:     device,
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastSeenAt: time.Now(),
+	}
+	result := DB.Create(&session)
+	return session, result.Error
+}
+
+// GetActiveUserSessions 获取用户未吊销的会话，按最近活跃时间排序
+func GetActiveUserSessions(userID uint) ([]UserSession, error) {
+	var sessions []UserSession
+	result := DB.Where("user_id = ? AND revoked_at IS NULL", userID).Order("last_seen_at DESC").Find(&sessions)
+	return sessions, result.Error
+}
+
+// GetUserSession 获取用户的指定会话
+func GetUserSession(userID uint, sessionID string) (UserSession, error) {
+	var session UserSession
+	result := DB.Where("user_id = ? AND session_id = ?", userID, sessionID).First(&session)
+	return session, result.Error
+}
+
+// TouchUserSession 更新会话的最近活跃时间与IP
+func TouchUserSession(sessionID string, ip string) error {
+	return DB.Model(&UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"last_seen_at": time.Now(), "ip": ip}).Error
+}
+
+// Revoke 吊销会话
+func (session *UserSession) Revoke() error {
+	now := time.Now()
+	if err := DB.Model(session).Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	session.RevokedAt = &now
+	return nil
+}
@@ -0,0 +1,692 @@
+package repository
+
+import (
+	"errors"
+	"math/rand"
+	"singo/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NewGorm 基于GORM的实现
+func NewGorm(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users:        &gormUserRepository{db: db},
+		Frogs:        &gormFrogRepository{db: db},
+		Pools:        &gormPoolRepository{db: db},
+		Participants: &gormParticipantRepository{db: db},
+		Rewards:      &gormRewardRepository{db: db},
+		Activations:  &gormActivationRepository{db: db},
+		Outbox:       &gormOutboxRepository{db: db},
+		PoolEvents:   &gormPoolEventRepository{db: db},
+		Jobs:         &gormJobRepository{db: db},
+		RoleAudits:   &gormRoleAuditRepository{db: db},
+		// 已在事务中时GORM使用保存点，嵌套调用随外层事务一起提交
+		transaction: func(fn func(tx *Repositories) error) error {
+			return db.Transaction(func(tx *gorm.DB) error {
+				return fn(NewGorm(tx))
+			})
+		},
+	}
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) Get(id uint) (*model.User, error) {
+	var user model.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) GetByWallet(walletAddress string) (*model.User, error) {
+	var user model.User
+	if err := r.db.Where("wallet_address = ?", walletAddress).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) Create(user *model.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *gormUserRepository) UpdateRole(id uint, role string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+func (r *gormUserRepository) ListWithRewards() ([]model.User, error) {
+	var users []model.User
+	err := r.db.Where("unclaimed_rewards <> 0 OR history_rewards <> 0").Order("id").Find(&users).Error
+	return users, err
+}
+
+func (r *gormUserRepository) Leaderboard(limit int) ([]model.User, error) {
+	var users []model.User
+	err := r.db.Where("history_rewards > 0").Order("history_rewards DESC, id").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+type gormFrogRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormFrogRepository) Get(id uint) (*model.Frog, error) {
+	var frog model.Frog
+	if err := r.db.First(&frog, id).Error; err != nil {
+		return nil, err
+	}
+	return &frog, nil
+}
+
+func (r *gormFrogRepository) GetActiveByUser(userID uint) (*model.Frog, error) {
+	var frog model.Frog
+	if err := r.db.Where("user_id = ? AND is_active = ?", userID, true).First(&frog).Error; err != nil {
+		return nil, err
+	}
+	return &frog, nil
+}
+
+func (r *gormFrogRepository) ListActive() ([]model.Frog, error) {
+	var frogs []model.Frog
+	err := r.db.Where("is_active = ?", true).Find(&frogs).Error
+	return frogs, err
+}
+
+func (r *gormFrogRepository) CountActiveInPool(poolID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Frog{}).
+		Joins("JOIN pool_participants ON pool_participants.frog_id = frogs.id").
+		Where("pool_participants.pool_id = ? AND frogs.is_active = ?", poolID, true).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormFrogRepository) Create(frog *model.Frog) error {
+	return r.db.Create(frog).Error
+}
+
+func (r *gormFrogRepository) Save(frog *model.Frog) error {
+	return r.db.Save(frog).Error
+}
+
+type gormPoolRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormPoolRepository) Get(id uint) (*model.PrizePool, error) {
+	var pool model.PrizePool
+	if err := r.db.First(&pool, id).Error; err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+func (r *gormPoolRepository) GetCurrentByFrog(frogID uint) (*model.PrizePool, error) {
+	var pool model.PrizePool
+	err := r.db.Joins("JOIN pool_participants ON pool_participants.pool_id = prize_pools.id").
+		Where("pool_participants.frog_id = ? AND prize_pools.status IN ?", frogID,
+			[]model.PoolStatus{model.PoolStatusCollecting, model.PoolStatusActive}).
+		Order("prize_pools.created_at DESC").
+		First(&pool).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+func (r *gormPoolRepository) List(status model.PoolStatus, limit int) ([]model.PrizePool, error) {
+	var pools []model.PrizePool
+	query := r.db.Order("id DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&pools).Error
+	return pools, err
+}
+
+func (r *gormPoolRepository) ListByStatus(status model.PoolStatus) ([]model.PrizePool, error) {
+	var pools []model.PrizePool
+	err := r.db.Where("status = ?", status).Find(&pools).Error
+	return pools, err
+}
+
+func (r *gormPoolRepository) Create(pool *model.PrizePool) error {
+	return r.db.Create(pool).Error
+}
+
+func (r *gormPoolRepository) ListWonBy(walletAddress string, status model.PoolStatus) ([]model.PrizePool, error) {
+	var pools []model.PrizePool
+	err := r.db.Where("big_prize_winner = ? AND status = ?", walletAddress, status).Order("id").Find(&pools).Error
+	return pools, err
+}
+
+func (r *gormPoolRepository) Transition(pool *model.PrizePool, to model.PoolStatus, reason string, actorUserID uint) (*model.PoolTransition, error) {
+	next := *pool
+	transition, err := next.Transition(to, reason, actorUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.PrizePool{}).
+			Where("id = ? AND status = ?", pool.ID, pool.Status).
+			Updates(map[string]interface{}{
+				"status":           next.Status,
+				"big_prize_winner": next.BigPrizeWinner,
+				"completed_at":     next.CompletedAt,
+				"collecting_slot":  next.CollectingSlot,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// 状态已被其他操作修改，按当前状态报告
+			var current model.PrizePool
+			if err := tx.Select("id", "status").First(&current, pool.ID).Error; err != nil {
+				return err
+			}
+			return &model.TransitionError{PoolID: pool.ID, From: current.Status, To: to}
+		}
+		return tx.Create(transition).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	*pool = next
+	return transition, nil
+}
+
+func (r *gormPoolRepository) ListTransitions(poolID uint) ([]model.PoolTransition, error) {
+	var transitions []model.PoolTransition
+	err := r.db.Where("pool_id = ?", poolID).Order("id").Find(&transitions).Error
+	return transitions, err
+}
+
+func (r *gormPoolRepository) MoveBigPrize(poolID uint, holderAddress string) error {
+	result := r.db.Model(&model.PrizePool{}).
+		Where("id = ? AND status = ?", poolID, model.PoolStatusActive).
+		Update("current_big_prize_holder", holderAddress)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPoolNotActive
+	}
+	return nil
+}
+
+// maxJoinAttempts 加入奖池冲突时的最大尝试次数
+const maxJoinAttempts = 20
+
+func (r *gormPoolRepository) Join(frogID uint, walletAddress string) (*model.PrizePool, *model.PoolParticipant, error) {
+	for attempt := 1; attempt <= maxJoinAttempts; attempt++ {
+		var pool model.PrizePool
+		var participant model.PoolParticipant
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			return r.join(tx, frogID, walletAddress, &pool, &participant)
+		})
+		if err == nil {
+			return &pool, &participant, nil
+		}
+		if !errors.Is(err, ErrJoinConflict) && !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, nil, err
+		}
+
+		// 随机退避后重试，避免冲突的请求再次同时提交
+		time.Sleep(time.Duration(attempt*(5+rand.Intn(10))) * time.Millisecond)
+	}
+	return nil, nil, ErrJoinConflict
+}
+
+// join 单次加入尝试，任何冲突都使整个事务回滚
+func (r *gormPoolRepository) join(tx *gorm.DB, frogID uint, walletAddress string, pool *model.PrizePool, participant *model.PoolParticipant) error {
+	// 锁定最早的仍有空位的收集中奖池（SQLite以 BEGIN IMMEDIATE 取得写锁）
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status = ? AND current_players < ?", model.PoolStatusCollecting, model.MaxPoolPlayers).
+		Order("id").First(pool).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 没有可用的奖池则创建，collecting_slot 的唯一索引保证并发时只有一个能创建成功
+		*pool = model.NewPool()
+		open := true
+		pool.CollectingSlot = &open
+		if err := tx.Create(pool).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(pool.CreatedTransition()).Error; err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	// 条件更新：只有人数仍是读取时的值才占用下一个序号
+	players := pool.CurrentPlayers
+	serial, transition := pool.Admit()
+	updates := map[string]interface{}{
+		"current_players": pool.CurrentPlayers,
+	}
+	if pool.Status != model.PoolStatusCollecting {
+		// 满员后释放收集中标记，允许创建下一个奖池
+		updates["status"] = pool.Status
+		updates["collecting_slot"] = nil
+	}
+	result := tx.Model(&model.PrizePool{}).
+		Where("id = ? AND status = ? AND current_players = ?", pool.ID, model.PoolStatusCollecting, players).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJoinConflict
+	}
+	if transition != nil {
+		if err := tx.Create(transition).Error; err != nil {
+			return err
+		}
+	}
+
+	// (pool_id, serial_number) 唯一索引兜底，重复序号会使事务回滚并重试
+	*participant = model.PoolParticipant{
+		PoolID:        pool.ID,
+		FrogID:        frogID,
+		WalletAddress: walletAddress,
+		SerialNumber:  serial,
+		JoinedAt:      time.Now(),
+	}
+	return tx.Create(participant).Error
+}
+
+type gormParticipantRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormParticipantRepository) ListByPool(poolID uint) ([]model.PoolParticipant, error) {
+	var participants []model.PoolParticipant
+	err := r.db.Where("pool_id = ?", poolID).Order("serial_number").Find(&participants).Error
+	return participants, err
+}
+
+func (r *gormParticipantRepository) Get(poolID, frogID uint) (*model.PoolParticipant, error) {
+	var participant model.PoolParticipant
+	if err := r.db.Where("pool_id = ? AND frog_id = ?", poolID, frogID).First(&participant).Error; err != nil {
+		return nil, err
+	}
+	return &participant, nil
+}
+
+func (r *gormParticipantRepository) GetLatestByFrog(frogID uint) (*model.PoolParticipant, error) {
+	var participant model.PoolParticipant
+	if err := r.db.Where("frog_id = ?", frogID).Order("id DESC").First(&participant).Error; err != nil {
+		return nil, err
+	}
+	return &participant, nil
+}
+
+type gormRewardRepository struct {
+	db *gorm.DB
+}
+
+// userBalanceColumns 同步到用户缓存余额的科目
+var userBalanceColumns = map[model.LedgerAccount]string{
+	model.AccountUserUnclaimed: "unclaimed_rewards",
+	model.AccountUserPaid:      "history_rewards",
+}
+
+func (r *gormRewardRepository) Post(entry *model.LedgerEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+
+		// 原子更新缓存的余额，避免并发时覆盖其他字段
+		for _, p := range entry.Postings {
+			column, ok := userBalanceColumns[p.Account]
+			if !ok {
+				continue
+			}
+			if err := tx.Model(&model.User{}).Where("id = ?", p.OwnerID).
+				Update(column, gorm.Expr(column+" + ?", model.ToSOL(p.Amount))).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *gormRewardRepository) Balance(account model.LedgerAccount, ownerID uint) (int64, error) {
+	var balance int64
+	err := r.db.Model(&model.LedgerPosting{}).
+		Where("account = ? AND owner_id = ?", account, ownerID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+func (r *gormRewardRepository) Balances(account model.LedgerAccount) (map[uint]int64, error) {
+	var rows []struct {
+		OwnerID uint
+		Balance int64
+	}
+	err := r.db.Model(&model.LedgerPosting{}).
+		Where("account = ?", account).
+		Select("owner_id, SUM(amount) AS balance").
+		Group("owner_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		balances[row.OwnerID] = row.Balance
+	}
+	return balances, nil
+}
+
+func (r *gormRewardRepository) Total() (int64, error) {
+	var total int64
+	err := r.db.Model(&model.LedgerPosting{}).Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	return total, err
+}
+
+func (r *gormRewardRepository) UnbalancedEntries() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.LedgerPosting{}).
+		Select("entry_id").
+		Group("entry_id").
+		Having("SUM(amount) <> 0").
+		Order("entry_id").
+		Pluck("entry_id", &ids).Error
+	return ids, err
+}
+
+func (r *gormRewardRepository) HasEntry(kind string, reference string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.LedgerEntry{}).Where("kind = ? AND reference = ?", kind, reference).Count(&count).Error
+	return count > 0, err
+}
+
+type gormActivationRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormActivationRepository) GetByKey(userID uint, idempotencyKey string) (*model.FrogActivation, error) {
+	var activation model.FrogActivation
+	if err := r.db.Where("user_id = ? AND idempotency_key = ?", userID, idempotencyKey).First(&activation).Error; err != nil {
+		return nil, err
+	}
+	return &activation, nil
+}
+
+func (r *gormActivationRepository) GetByTransaction(transactionHash string) (*model.FrogActivation, error) {
+	var activation model.FrogActivation
+	if err := r.db.Where("transaction_hash = ?", transactionHash).First(&activation).Error; err != nil {
+		return nil, err
+	}
+	return &activation, nil
+}
+
+func (r *gormActivationRepository) Create(activation *model.FrogActivation) error {
+	return r.db.Create(activation).Error
+}
+
+func (r *gormActivationRepository) Save(activation *model.FrogActivation) error {
+	return r.db.Save(activation).Error
+}
+
+type gormOutboxRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormOutboxRepository) Add(messages ...model.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return r.db.Create(&messages).Error
+}
+
+func (r *gormOutboxRepository) Claim(limit int, lease time.Duration) ([]model.OutboxMessage, error) {
+	now := time.Now()
+	var candidates []model.OutboxMessage
+	err := r.db.Where("dispatched_at IS NULL AND (locked_until IS NULL OR locked_until < ?)", now).
+		Order("id").Limit(limit).Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 条件更新认领，其他投递器已认领的消息跳过
+	until := now.Add(lease)
+	claimed := make([]model.OutboxMessage, 0, len(candidates))
+	for _, message := range candidates {
+		result := r.db.Model(&model.OutboxMessage{}).
+			Where("id = ? AND dispatched_at IS NULL AND (locked_until IS NULL OR locked_until < ?)", message.ID, now).
+			Update("locked_until", until)
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			message.LockedUntil = &until
+			claimed = append(claimed, message)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *gormOutboxRepository) MarkDispatched(id uint) error {
+	return r.db.Model(&model.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"dispatched_at": time.Now(),
+		"attempts":      gorm.Expr("attempts + 1"),
+		"locked_until":  nil,
+	}).Error
+}
+
+func (r *gormOutboxRepository) MarkFailed(id uint, reason string) error {
+	return r.db.Model(&model.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":     gorm.Expr("attempts + 1"),
+		"last_error":   truncate(reason, 255),
+		"locked_until": nil,
+	}).Error
+}
+
+func (r *gormOutboxRepository) CountPending() (int64, error) {
+	var count int64
+	err := r.db.Model(&model.OutboxMessage{}).Where("dispatched_at IS NULL").Count(&count).Error
+	return count, err
+}
+
+// truncate 截断过长的字符串以适应列宽
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
+
+type gormPoolEventRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormPoolEventRepository) Append(events ...model.PoolEvent) error {
+	next := make(map[uint]uint)
+	for i := range events {
+		poolID := events[i].PoolID
+		if _, ok := next[poolID]; !ok {
+			// (pool_id, seq) 唯一索引保证并发追加时序号不重复，冲突的事务整体回滚
+			var last uint
+			err := r.db.Model(&model.PoolEvent{}).Where("pool_id = ?", poolID).
+				Select("COALESCE(MAX(seq), 0)").Scan(&last).Error
+			if err != nil {
+				return err
+			}
+			next[poolID] = last
+		}
+		next[poolID]++
+		events[i].Seq = next[poolID]
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return r.db.Create(&events).Error
+}
+
+func (r *gormPoolEventRepository) ListByPool(poolID uint) ([]model.PoolEvent, error) {
+	var events []model.PoolEvent
+	err := r.db.Where("pool_id = ?", poolID).Order("seq").Find(&events).Error
+	return events, err
+}
+
+type gormJobRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormJobRepository) Schedule(job *model.Job) (bool, error) {
+	// 去重键冲突时不写入
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// claimableJobs 到期待执行或租约已过期的任务
+const claimableJobs = "((status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?))"
+
+func (r *gormJobRepository) Claim(limit int, lease time.Duration) ([]model.Job, error) {
+	now := time.Now()
+	var candidates []model.Job
+	err := r.db.Where(claimableJobs, model.JobPending, now, model.JobRunning, now).
+		Order("run_at, id").Limit(limit).Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 条件更新认领，其他执行器已认领的任务跳过
+	// 租约到期时间截断到毫秒，与数据库保存的精度一致，记录结果时按此时间匹配租约
+	until := now.Add(lease).Truncate(time.Millisecond)
+	claimed := make([]model.Job, 0, len(candidates))
+	for _, job := range candidates {
+		result := r.db.Model(&model.Job{}).
+			Where("id = ? AND "+claimableJobs, job.ID, model.JobPending, now, model.JobRunning, now).
+			Updates(map[string]interface{}{
+				"status":       model.JobRunning,
+				"locked_until": until,
+			})
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = model.JobRunning
+			job.LockedUntil = &until
+			claimed = append(claimed, job)
+		}
+	}
+	return claimed, nil
+}
+
+// finishLease 以认领时的租约为条件更新任务，没有更新任何记录时说明租约已丢失
+func (r *gormJobRepository) finishLease(id uint, lockedUntil time.Time, updates map[string]interface{}) error {
+	result := r.db.Model(&model.Job{}).
+		Where("id = ? AND status = ? AND locked_until = ?", id, model.JobRunning, lockedUntil).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (r *gormJobRepository) Finish(id uint, lockedUntil time.Time) error {
+	return r.finishLease(id, lockedUntil, map[string]interface{}{
+		"status":       model.JobDone,
+		"dedup_key":    nil,
+		"locked_until": nil,
+		"finished_at":  time.Now(),
+	})
+}
+
+func (r *gormJobRepository) Release(id uint, lockedUntil time.Time, runAt time.Time, attempts int, lastError string) error {
+	return r.finishLease(id, lockedUntil, map[string]interface{}{
+		"status":       model.JobPending,
+		"run_at":       runAt,
+		"attempts":     attempts,
+		"last_error":   truncate(lastError, 255),
+		"locked_until": nil,
+	})
+}
+
+func (r *gormJobRepository) Bury(id uint, lockedUntil time.Time, attempts int, lastError string) error {
+	return r.finishLease(id, lockedUntil, map[string]interface{}{
+		"status":       model.JobDead,
+		"attempts":     attempts,
+		"last_error":   truncate(lastError, 255),
+		"dedup_key":    nil,
+		"locked_until": nil,
+		"finished_at":  time.Now(),
+	})
+}
+
+func (r *gormJobRepository) Requeue(id uint) error {
+	result := r.db.Model(&model.Job{}).Where("id = ? AND status = ?", id, model.JobDead).Updates(map[string]interface{}{
+		"status":      model.JobPending,
+		"run_at":      time.Now(),
+		"attempts":    0,
+		"finished_at": nil,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormJobRepository) List(status model.JobStatus, limit int) ([]model.Job, error) {
+	var jobs []model.Job
+	err := r.db.Where("status = ?", status).Order("id DESC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *gormJobRepository) CountByStatus() (map[model.JobStatus]int64, error) {
+	var rows []struct {
+		Status model.JobStatus
+		Count  int64
+	}
+	if err := r.db.Model(&model.Job{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[model.JobStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+type gormRoleAuditRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormRoleAuditRepository) Create(log *model.RoleAuditLog) error {
+	return r.db.Create(log).Error
+}
+
+func (r *gormRoleAuditRepository) List(targetUserID uint, limit int) ([]model.RoleAuditLog, error) {
+	var logs []model.RoleAuditLog
+	query := r.db.Order("id DESC").Limit(limit)
+	if targetUserID > 0 {
+		query = query.Where("target_user_id = ?", targetUserID)
+	}
+	err := query.Find(&logs).Error
+	return logs, err
+}
@@ -0,0 +1,969 @@
+package repository
+
+import (
+	"singo/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryStore 内存实现共享的数据，所有仓库使用同一把锁
+// 读写均复制结构体，调用方修改返回值不会影响已保存的数据
+type memoryStore struct {
+	mu           sync.Mutex
+	txMu         sync.Mutex // 事务之间串行执行
+	nextID       uint
+	users        map[uint]model.User
+	frogs        map[uint]model.Frog
+	pools        map[uint]model.PrizePool
+	participants map[uint]model.PoolParticipant
+	entries      []model.LedgerEntry // 不含记账明细
+	postings     []model.LedgerPosting
+	activations  map[uint]model.FrogActivation
+	outbox       map[uint]model.OutboxMessage
+	transitions  []model.PoolTransition
+	poolEvents   []model.PoolEvent
+	jobs         map[uint]model.Job
+	roleAudits   []model.RoleAuditLog
+}
+
+// NewMemory 内存实现，用于单元测试
+func NewMemory() *Repositories {
+	store := &memoryStore{
+		users:        make(map[uint]model.User),
+		frogs:        make(map[uint]model.Frog),
+		pools:        make(map[uint]model.PrizePool),
+		participants: make(map[uint]model.PoolParticipant),
+		activations:  make(map[uint]model.FrogActivation),
+		outbox:       make(map[uint]model.OutboxMessage),
+		jobs:         make(map[uint]model.Job),
+	}
+	repos := &Repositories{
+		Users:        &memoryUserRepository{store},
+		Frogs:        &memoryFrogRepository{store},
+		Pools:        &memoryPoolRepository{store},
+		Participants: &memoryParticipantRepository{store},
+		Rewards:      &memoryRewardRepository{store},
+		Activations:  &memoryActivationRepository{store},
+		Outbox:       &memoryOutboxRepository{store},
+		PoolEvents:   &memoryPoolEventRepository{store},
+		Jobs:         &memoryJobRepository{store},
+		RoleAudits:   &memoryRoleAuditRepository{store},
+	}
+	repos.transaction = store.transaction(repos)
+	return repos
+}
+
+// transaction 串行执行事务，出错时恢复到事务开始前的数据
+// 事务之外的读写不受隔离，仅供测试使用
+func (s *memoryStore) transaction(repos *Repositories) func(fn func(tx *Repositories) error) error {
+	return func(fn func(tx *Repositories) error) error {
+		s.txMu.Lock()
+		defer s.txMu.Unlock()
+
+		// 嵌套的事务直接并入当前事务
+		tx := *repos
+		tx.transaction = func(fn func(tx *Repositories) error) error { return fn(&tx) }
+
+		snapshot := s.snapshot()
+		if err := fn(&tx); err != nil {
+			s.restore(snapshot)
+			return err
+		}
+		return nil
+	}
+}
+
+// snapshot 复制当前全部数据
+func (s *memoryStore) snapshot() *memoryStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &memoryStore{
+		nextID:       s.nextID,
+		users:        copyMap(s.users),
+		frogs:        copyMap(s.frogs),
+		pools:        copyMap(s.pools),
+		participants: copyMap(s.participants),
+		entries:      append([]model.LedgerEntry(nil), s.entries...),
+		postings:     append([]model.LedgerPosting(nil), s.postings...),
+		activations:  copyMap(s.activations),
+		outbox:       copyMap(s.outbox),
+		transitions:  append([]model.PoolTransition(nil), s.transitions...),
+		poolEvents:   append([]model.PoolEvent(nil), s.poolEvents...),
+		jobs:         copyMap(s.jobs),
+		roleAudits:   append([]model.RoleAuditLog(nil), s.roleAudits...),
+	}
+}
+
+// restore 恢复到快照时的数据
+func (s *memoryStore) restore(snapshot *memoryStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID = snapshot.nextID
+	s.users = snapshot.users
+	s.frogs = snapshot.frogs
+	s.pools = snapshot.pools
+	s.participants = snapshot.participants
+	s.entries = snapshot.entries
+	s.postings = snapshot.postings
+	s.activations = snapshot.activations
+	s.outbox = snapshot.outbox
+	s.transitions = snapshot.transitions
+	s.poolEvents = snapshot.poolEvents
+	s.jobs = snapshot.jobs
+	s.roleAudits = snapshot.roleAudits
+}
+
+func copyMap[T any](m map[uint]T) map[uint]T {
+	c := make(map[uint]T, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// newID 分配自增ID并填充时间戳，调用方需持有锁
+func (s *memoryStore) newID() (uint, time.Time) {
+	s.nextID++
+	return s.nextID, time.Now()
+}
+
+// sortedIDs 按ID升序返回，保证遍历顺序稳定
+func sortedIDs[T any](m map[uint]T) []uint {
+	ids := make([]uint, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+type memoryUserRepository struct {
+	*memoryStore
+}
+
+func (r *memoryUserRepository) Get(id uint) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) GetByWallet(walletAddress string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range sortedIDs(r.users) {
+		if user := r.users[id]; user.WalletAddress == walletAddress {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepository) Create(user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.ID, user.CreatedAt = r.newID()
+	user.UpdatedAt = user.CreatedAt
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateRole(id uint, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil
+	}
+	user.Role, user.UpdatedAt = role, time.Now()
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) ListWithRewards() ([]model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var users []model.User
+	for _, id := range sortedIDs(r.users) {
+		if user := r.users[id]; user.UnclaimedRewards != 0 || user.HistoryRewards != 0 {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *memoryUserRepository) Leaderboard(limit int) ([]model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var users []model.User
+	for _, id := range sortedIDs(r.users) {
+		if user := r.users[id]; user.HistoryRewards > 0 {
+			users = append(users, user)
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool { return users[i].HistoryRewards > users[j].HistoryRewards })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+type memoryFrogRepository struct {
+	*memoryStore
+}
+
+func (r *memoryFrogRepository) Get(id uint) (*model.Frog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	frog, ok := r.frogs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &frog, nil
+}
+
+func (r *memoryFrogRepository) GetActiveByUser(userID uint) (*model.Frog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range sortedIDs(r.frogs) {
+		if frog := r.frogs[id]; frog.UserID == userID && frog.IsActive {
+			return &frog, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryFrogRepository) ListActive() ([]model.Frog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var frogs []model.Frog
+	for _, id := range sortedIDs(r.frogs) {
+		if frog := r.frogs[id]; frog.IsActive {
+			frogs = append(frogs, frog)
+		}
+	}
+	return frogs, nil
+}
+
+func (r *memoryFrogRepository) CountActiveInPool(poolID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for _, p := range r.participants {
+		if p.PoolID == poolID && r.frogs[p.FrogID].IsActive {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// hasOtherActive 用户是否已有其他激活的青蛙，调用方需持有锁
+func (r *memoryFrogRepository) hasOtherActive(frog *model.Frog) bool {
+	if !frog.IsActive {
+		return false
+	}
+	for id, other := range r.frogs {
+		if id != frog.ID && other.UserID == frog.UserID && other.IsActive {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryFrogRepository) Create(frog *model.Frog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	frog.SyncActiveUser()
+	if r.hasOtherActive(frog) {
+		return ErrDuplicate
+	}
+	frog.ID, frog.CreatedAt = r.newID()
+	frog.UpdatedAt = frog.CreatedAt
+	r.frogs[frog.ID] = *frog
+	return nil
+}
+
+func (r *memoryFrogRepository) Save(frog *model.Frog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	frog.SyncActiveUser()
+	if r.hasOtherActive(frog) {
+		return ErrDuplicate
+	}
+	frog.UpdatedAt = time.Now()
+	r.frogs[frog.ID] = *frog
+	return nil
+}
+
+type memoryPoolRepository struct {
+	*memoryStore
+}
+
+func (r *memoryPoolRepository) Get(id uint) (*model.PrizePool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pool, ok := r.pools[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &pool, nil
+}
+
+func (r *memoryPoolRepository) GetCurrentByFrog(frogID uint) (*model.PrizePool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var current *model.PrizePool
+	for _, id := range sortedIDs(r.participants) {
+		p := r.participants[id]
+		if p.FrogID != frogID {
+			continue
+		}
+		if pool, ok := r.pools[p.PoolID]; ok && pool.Status.Live() {
+			if current == nil || pool.CreatedAt.After(current.CreatedAt) {
+				current = &pool
+			}
+		}
+	}
+	if current == nil {
+		return nil, ErrNotFound
+	}
+	return current, nil
+}
+
+func (r *memoryPoolRepository) List(status model.PoolStatus, limit int) ([]model.PrizePool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := sortedIDs(r.pools)
+	var pools []model.PrizePool
+	for i := len(ids) - 1; i >= 0 && len(pools) < limit; i-- {
+		if pool := r.pools[ids[i]]; status == "" || pool.Status == status {
+			pools = append(pools, pool)
+		}
+	}
+	return pools, nil
+}
+
+func (r *memoryPoolRepository) ListByStatus(status model.PoolStatus) ([]model.PrizePool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pools []model.PrizePool
+	for _, id := range sortedIDs(r.pools) {
+		if pool := r.pools[id]; pool.Status == status {
+			pools = append(pools, pool)
+		}
+	}
+	return pools, nil
+}
+
+func (r *memoryPoolRepository) Create(pool *model.PrizePool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pool.ID, pool.CreatedAt = r.newID()
+	pool.UpdatedAt = pool.CreatedAt
+	r.pools[pool.ID] = *pool
+	return nil
+}
+
+func (r *memoryPoolRepository) ListWonBy(walletAddress string, status model.PoolStatus) ([]model.PrizePool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pools []model.PrizePool
+	for _, id := range sortedIDs(r.pools) {
+		if pool := r.pools[id]; pool.BigPrizeWinner == walletAddress && pool.Status == status {
+			pools = append(pools, pool)
+		}
+	}
+	return pools, nil
+}
+
+func (r *memoryPoolRepository) Transition(pool *model.PrizePool, to model.PoolStatus, reason string, actorUserID uint) (*model.PoolTransition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.pools[pool.ID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if current.Status != pool.Status {
+		return nil, &model.TransitionError{PoolID: pool.ID, From: current.Status, To: to}
+	}
+
+	next := *pool
+	transition, err := next.Transition(to, reason, actorUserID)
+	if err != nil {
+		return nil, err
+	}
+	// 与GORM实现一致，只更新状态相关的字段
+	current.Status = next.Status
+	current.BigPrizeWinner = next.BigPrizeWinner
+	current.CompletedAt = next.CompletedAt
+	current.CollectingSlot = next.CollectingSlot
+	current.UpdatedAt = time.Now()
+	r.pools[pool.ID] = current
+	r.addTransition(transition)
+
+	*pool = next
+	return transition, nil
+}
+
+// addTransition 保存状态变更记录，调用方需持有锁
+func (s *memoryStore) addTransition(transition *model.PoolTransition) {
+	transition.ID, transition.CreatedAt = s.newID()
+	s.transitions = append(s.transitions, *transition)
+}
+
+func (r *memoryPoolRepository) ListTransitions(poolID uint) ([]model.PoolTransition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var transitions []model.PoolTransition
+	for _, t := range r.transitions {
+		if t.PoolID == poolID {
+			transitions = append(transitions, t)
+		}
+	}
+	return transitions, nil
+}
+
+func (r *memoryPoolRepository) MoveBigPrize(poolID uint, holderAddress string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pool, ok := r.pools[poolID]
+	if !ok || pool.Status != model.PoolStatusActive {
+		return ErrPoolNotActive
+	}
+	pool.CurrentBigPrizeHolder = holderAddress
+	pool.UpdatedAt = time.Now()
+	r.pools[poolID] = pool
+	return nil
+}
+
+// Join 在同一把锁内选择奖池并占用序号
+func (r *memoryPoolRepository) Join(frogID uint, walletAddress string) (*model.PrizePool, *model.PoolParticipant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pool model.PrizePool
+	found := false
+	for _, id := range sortedIDs(r.pools) {
+		if p := r.pools[id]; p.Status == model.PoolStatusCollecting && p.CurrentPlayers < model.MaxPoolPlayers {
+			pool, found = p, true
+			break
+		}
+	}
+	if !found {
+		pool = model.NewPool()
+		open := true
+		pool.CollectingSlot = &open
+		pool.ID, pool.CreatedAt = r.newID()
+		r.addTransition(pool.CreatedTransition())
+	}
+
+	participant := model.PoolParticipant{
+		PoolID:        pool.ID,
+		FrogID:        frogID,
+		WalletAddress: walletAddress,
+		JoinedAt:      time.Now(),
+	}
+	var transition *model.PoolTransition
+	participant.SerialNumber, transition = pool.Admit()
+	if transition != nil {
+		r.addTransition(transition)
+	}
+	participant.ID, participant.CreatedAt = r.newID()
+	participant.UpdatedAt = participant.CreatedAt
+	r.participants[participant.ID] = participant
+
+	pool.UpdatedAt = time.Now()
+	r.pools[pool.ID] = pool
+	return &pool, &participant, nil
+}
+
+type memoryParticipantRepository struct {
+	*memoryStore
+}
+
+func (r *memoryParticipantRepository) ListByPool(poolID uint) ([]model.PoolParticipant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var participants []model.PoolParticipant
+	for _, id := range sortedIDs(r.participants) {
+		if p := r.participants[id]; p.PoolID == poolID {
+			participants = append(participants, p)
+		}
+	}
+	sort.SliceStable(participants, func(i, j int) bool { return participants[i].SerialNumber < participants[j].SerialNumber })
+	return participants, nil
+}
+
+func (r *memoryParticipantRepository) Get(poolID, frogID uint) (*model.PoolParticipant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range sortedIDs(r.participants) {
+		if p := r.participants[id]; p.PoolID == poolID && p.FrogID == frogID {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryParticipantRepository) GetLatestByFrog(frogID uint) (*model.PoolParticipant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := sortedIDs(r.participants)
+	for i := len(ids) - 1; i >= 0; i-- {
+		if p := r.participants[ids[i]]; p.FrogID == frogID {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+type memoryRewardRepository struct {
+	*memoryStore
+}
+
+func (r *memoryRewardRepository) Post(entry *model.LedgerEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range entry.Postings {
+		if _, ok := r.users[p.OwnerID]; !ok && (p.Account == model.AccountUserUnclaimed || p.Account == model.AccountUserPaid) {
+			return ErrNotFound
+		}
+	}
+
+	entry.ID, entry.CreatedAt = r.newID()
+	r.entries = append(r.entries, model.LedgerEntry{ID: entry.ID, CreatedAt: entry.CreatedAt, Kind: entry.Kind, Reference: entry.Reference})
+	for i := range entry.Postings {
+		p := &entry.Postings[i]
+		p.ID, p.CreatedAt = r.newID()
+		p.EntryID = entry.ID
+		r.postings = append(r.postings, *p)
+
+		switch p.Account {
+		case model.AccountUserUnclaimed:
+			user := r.users[p.OwnerID]
+			user.UnclaimedRewards += model.ToSOL(p.Amount)
+			r.users[p.OwnerID] = user
+		case model.AccountUserPaid:
+			user := r.users[p.OwnerID]
+			user.HistoryRewards += model.ToSOL(p.Amount)
+			r.users[p.OwnerID] = user
+		}
+	}
+	return nil
+}
+
+func (r *memoryRewardRepository) Balance(account model.LedgerAccount, ownerID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var balance int64
+	for _, p := range r.postings {
+		if p.Account == account && p.OwnerID == ownerID {
+			balance += p.Amount
+		}
+	}
+	return balance, nil
+}
+
+func (r *memoryRewardRepository) Balances(account model.LedgerAccount) (map[uint]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	balances := make(map[uint]int64)
+	for _, p := range r.postings {
+		if p.Account == account {
+			balances[p.OwnerID] += p.Amount
+		}
+	}
+	return balances, nil
+}
+
+func (r *memoryRewardRepository) Total() (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var total int64
+	for _, p := range r.postings {
+		total += p.Amount
+	}
+	return total, nil
+}
+
+func (r *memoryRewardRepository) UnbalancedEntries() ([]uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sums := make(map[uint]int64)
+	for _, p := range r.postings {
+		sums[p.EntryID] += p.Amount
+	}
+	var ids []uint
+	for _, id := range sortedIDs(sums) {
+		if sums[id] != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *memoryRewardRepository) HasEntry(kind string, reference string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, entry := range r.entries {
+		if entry.Kind == kind && entry.Reference == reference {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memoryActivationRepository struct {
+	*memoryStore
+}
+
+func (r *memoryActivationRepository) GetByKey(userID uint, idempotencyKey string) (*model.FrogActivation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.activations {
+		if a.UserID == userID && a.IdempotencyKey != nil && *a.IdempotencyKey == idempotencyKey {
+			return &a, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryActivationRepository) GetByTransaction(transactionHash string) (*model.FrogActivation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.activations {
+		if a.TransactionHash == transactionHash {
+			return &a, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryActivationRepository) Create(activation *model.FrogActivation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.activations {
+		if a.TransactionHash == activation.TransactionHash {
+			return ErrDuplicate
+		}
+		if a.UserID == activation.UserID && a.IdempotencyKey != nil && activation.IdempotencyKey != nil &&
+			*a.IdempotencyKey == *activation.IdempotencyKey {
+			return ErrDuplicate
+		}
+	}
+	activation.ID, activation.CreatedAt = r.newID()
+	activation.UpdatedAt = activation.CreatedAt
+	r.activations[activation.ID] = *activation
+	return nil
+}
+
+func (r *memoryActivationRepository) Save(activation *model.FrogActivation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	activation.UpdatedAt = time.Now()
+	r.activations[activation.ID] = *activation
+	return nil
+}
+
+type memoryOutboxRepository struct {
+	*memoryStore
+}
+
+func (r *memoryOutboxRepository) Add(messages ...model.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range messages {
+		for _, m := range r.outbox {
+			if m.EventID == message.EventID {
+				return ErrDuplicate
+			}
+		}
+		message.ID, message.CreatedAt = r.newID()
+		r.outbox[message.ID] = message
+	}
+	return nil
+}
+
+func (r *memoryOutboxRepository) Claim(limit int, lease time.Duration) ([]model.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	until := now.Add(lease)
+	var claimed []model.OutboxMessage
+	for _, id := range sortedIDs(r.outbox) {
+		if len(claimed) >= limit {
+			break
+		}
+		m := r.outbox[id]
+		if m.DispatchedAt != nil || (m.LockedUntil != nil && !m.LockedUntil.Before(now)) {
+			continue
+		}
+		m.LockedUntil = &until
+		r.outbox[id] = m
+		claimed = append(claimed, m)
+	}
+	return claimed, nil
+}
+
+func (r *memoryOutboxRepository) MarkDispatched(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.outbox[id]
+	if !ok {
+		return ErrNotFound
+	}
+	now := time.Now()
+	m.DispatchedAt = &now
+	m.Attempts++
+	m.LockedUntil = nil
+	r.outbox[id] = m
+	return nil
+}
+
+func (r *memoryOutboxRepository) MarkFailed(id uint, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.outbox[id]
+	if !ok {
+		return ErrNotFound
+	}
+	m.Attempts++
+	m.LastError = reason
+	m.LockedUntil = nil
+	r.outbox[id] = m
+	return nil
+}
+
+func (r *memoryOutboxRepository) CountPending() (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for _, m := range r.outbox {
+		if m.DispatchedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+type memoryPoolEventRepository struct {
+	*memoryStore
+}
+
+func (r *memoryPoolEventRepository) Append(events ...model.PoolEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range events {
+		var last uint
+		for _, e := range r.poolEvents {
+			if e.PoolID == events[i].PoolID && e.Seq > last {
+				last = e.Seq
+			}
+		}
+		events[i].Seq = last + 1
+		events[i].ID, events[i].CreatedAt = r.newID()
+		r.poolEvents = append(r.poolEvents, events[i])
+	}
+	return nil
+}
+
+func (r *memoryPoolEventRepository) ListByPool(poolID uint) ([]model.PoolEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []model.PoolEvent
+	for _, e := range r.poolEvents {
+		if e.PoolID == poolID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+type memoryJobRepository struct {
+	*memoryStore
+}
+
+func (r *memoryJobRepository) Schedule(job *model.Job) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if job.DedupKey != nil {
+		for _, j := range r.jobs {
+			if j.DedupKey != nil && *j.DedupKey == *job.DedupKey {
+				return false, nil
+			}
+		}
+	}
+	job.ID, job.CreatedAt = r.newID()
+	job.UpdatedAt = job.CreatedAt
+	r.jobs[job.ID] = *job
+	return true, nil
+}
+
+func (r *memoryJobRepository) Claim(limit int, lease time.Duration) ([]model.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var due []model.Job
+	for _, j := range r.jobs {
+		if (j.Status == model.JobPending && !j.RunAt.After(now)) ||
+			(j.Status == model.JobRunning && j.LockedUntil != nil && j.LockedUntil.Before(now)) {
+			due = append(due, j)
+		}
+	}
+	sort.Slice(due, func(i, k int) bool {
+		if !due[i].RunAt.Equal(due[k].RunAt) {
+			return due[i].RunAt.Before(due[k].RunAt)
+		}
+		return due[i].ID < due[k].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	until := now.Add(lease)
+	for i := range due {
+		due[i].Status = model.JobRunning
+		due[i].LockedUntil = &until
+		r.jobs[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+// update 修改任务，调用方需持有锁
+func (r *memoryJobRepository) update(id uint, fn func(job *model.Job)) {
+	if job, ok := r.jobs[id]; ok {
+		fn(&job)
+		job.UpdatedAt = time.Now()
+		r.jobs[id] = job
+	}
+}
+
+// finishLease 任务仍在认领时的租约下运行时修改，否则返回ErrLeaseLost
+func (r *memoryJobRepository) finishLease(id uint, lockedUntil time.Time, fn func(job *model.Job)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok || job.Status != model.JobRunning || job.LockedUntil == nil || !job.LockedUntil.Equal(lockedUntil) {
+		return ErrLeaseLost
+	}
+	r.update(id, fn)
+	return nil
+}
+
+func (r *memoryJobRepository) Finish(id uint, lockedUntil time.Time) error {
+	return r.finishLease(id, lockedUntil, func(job *model.Job) {
+		now := time.Now()
+		job.Status = model.JobDone
+		job.DedupKey = nil
+		job.LockedUntil = nil
+		job.FinishedAt = &now
+	})
+}
+
+func (r *memoryJobRepository) Release(id uint, lockedUntil time.Time, runAt time.Time, attempts int, lastError string) error {
+	return r.finishLease(id, lockedUntil, func(job *model.Job) {
+		job.Status = model.JobPending
+		job.RunAt = runAt
+		job.Attempts = attempts
+		job.LastError = truncate(lastError, 255)
+		job.LockedUntil = nil
+	})
+}
+
+func (r *memoryJobRepository) Bury(id uint, lockedUntil time.Time, attempts int, lastError string) error {
+	return r.finishLease(id, lockedUntil, func(job *model.Job) {
+		now := time.Now()
+		job.Status = model.JobDead
+		job.Attempts = attempts
+		job.LastError = truncate(lastError, 255)
+		job.DedupKey = nil
+		job.LockedUntil = nil
+		job.FinishedAt = &now
+	})
+}
+
+// MoveJob 修改内存实现中待执行任务的执行时间，用于测试中模拟等待时间已过
+func MoveJob(repos *Repositories, id uint, runAt time.Time) bool {
+	r, ok := repos.Jobs.(*memoryJobRepository)
+	if !ok {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if job, ok := r.jobs[id]; !ok || job.Status != model.JobPending {
+		return false
+	}
+	r.update(id, func(job *model.Job) { job.RunAt = runAt })
+	return true
+}
+
+func (r *memoryJobRepository) Requeue(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if job, ok := r.jobs[id]; !ok || job.Status != model.JobDead {
+		return ErrNotFound
+	}
+	r.update(id, func(job *model.Job) {
+		job.Status = model.JobPending
+		job.RunAt = time.Now()
+		job.Attempts = 0
+		job.FinishedAt = nil
+	})
+	return nil
+}
+
+func (r *memoryJobRepository) List(status model.JobStatus, limit int) ([]model.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := sortedIDs(r.jobs)
+	var jobs []model.Job
+	for i := len(ids) - 1; i >= 0 && len(jobs) < limit; i-- {
+		if job := r.jobs[ids[i]]; job.Status == status {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (r *memoryJobRepository) CountByStatus() (map[model.JobStatus]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[model.JobStatus]int64)
+	for _, job := range r.jobs {
+		counts[job.Status]++
+	}
+	return counts, nil
+}
+
+type memoryRoleAuditRepository struct {
+	*memoryStore
+}
+
+func (r *memoryRoleAuditRepository) Create(log *model.RoleAuditLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	log.ID, log.CreatedAt = r.newID()
+	log.UpdatedAt = log.CreatedAt
+	r.roleAudits = append(r.roleAudits, *log)
+	return nil
+}
+
+func (r *memoryRoleAuditRepository) List(targetUserID uint, limit int) ([]model.RoleAuditLog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var logs []model.RoleAuditLog
+	for i := len(r.roleAudits) - 1; i >= 0 && len(logs) < limit; i-- {
+		if targetUserID == 0 || r.roleAudits[i].TargetUserID == targetUserID {
+			logs = append(logs, r.roleAudits[i])
+		}
+	}
+	return logs, nil
+}
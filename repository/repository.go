@@ -0,0 +1,184 @@
+// Package repository 定义用户、青蛙、奖池、参与者与奖励的数据访问接口
+//
+// 服务通过构造函数接收这些接口，而不是直接使用全局的 model.DB：
+// 线上使用 NewGorm 的GORM实现，单元测试使用 NewMemory 的内存实现。
+package repository
+
+import (
+	"errors"
+	"singo/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound 记录不存在，与 gorm.ErrRecordNotFound 相同，便于沿用已有的判断
+	ErrNotFound = gorm.ErrRecordNotFound
+	// ErrDuplicate 违反唯一约束，与 gorm.ErrDuplicatedKey 相同
+	ErrDuplicate = gorm.ErrDuplicatedKey
+	// ErrJoinConflict 并发加入奖池时发生冲突，重试次数用尽后返回
+	ErrJoinConflict = errors.New("pool join conflict")
+	// ErrPoolNotActive 奖池已不是活跃状态
+	ErrPoolNotActive = errors.New("pool is not active")
+	// ErrLeaseLost 任务的租约已过期并被其他执行器重新认领，本次执行结果不再记录
+	ErrLeaseLost = errors.New("job lease lost")
+)
+
+// UserRepository 用户数据访问
+type UserRepository interface {
+	Get(id uint) (*model.User, error)
+	GetByWallet(walletAddress string) (*model.User, error)
+	Create(user *model.User) error
+	// UpdateRole 修改用户角色
+	UpdateRole(id uint, role string) error
+	// Leaderboard 按历史总收益倒序
+	Leaderboard(limit int) ([]model.User, error)
+	// ListWithRewards 获取未领取奖励或历史收益不为0的用户
+	ListWithRewards() ([]model.User, error)
+}
+
+// FrogRepository 青蛙数据访问
+type FrogRepository interface {
+	Get(id uint) (*model.Frog, error)
+	// GetActiveByUser 获取用户的激活青蛙，不存在时返回ErrNotFound
+	GetActiveByUser(userID uint) (*model.Frog, error)
+	ListActive() ([]model.Frog, error)
+	// CountActiveInPool 统计奖池中仍处于激活状态的青蛙数量
+	CountActiveInPool(poolID uint) (int64, error)
+	// Create 与 Save 在用户已有其他激活的青蛙时返回ErrDuplicate
+	Create(frog *model.Frog) error
+	Save(frog *model.Frog) error
+}
+
+// PoolRepository 奖池数据访问
+type PoolRepository interface {
+	Get(id uint) (*model.PrizePool, error)
+	// GetCurrentByFrog 获取青蛙最近参与且未完成的奖池
+	GetCurrentByFrog(frogID uint) (*model.PrizePool, error)
+	List(status model.PoolStatus, limit int) ([]model.PrizePool, error)
+	ListByStatus(status model.PoolStatus) ([]model.PrizePool, error)
+	// ListWonBy 获取指定获胜者处于给定状态的奖池
+	ListWonBy(walletAddress string, status model.PoolStatus) ([]model.PrizePool, error)
+	Create(pool *model.PrizePool) error
+	// Transition 按状态机切换奖池状态并写入变更记录；以读取时的状态为条件更新，
+	// 状态不允许切换或已被其他操作修改时返回 *model.TransitionError
+	Transition(pool *model.PrizePool, to model.PoolStatus, reason string, actorUserID uint) (*model.PoolTransition, error)
+	// ListTransitions 按时间顺序获取奖池的状态变更记录
+	ListTransitions(poolID uint) ([]model.PoolTransition, error)
+	// MoveBigPrize 更新当前可以看到大奖的玩家，奖池不是活跃状态时返回ErrPoolNotActive
+	MoveBigPrize(poolID uint, holderAddress string) error
+	// Join 将青蛙加入收集中的奖池，没有时创建新奖池；满员时奖池转为活跃
+	// 在同一事务中锁定奖池、占用序号并写入参与者，冲突时自动重试
+	Join(frogID uint, walletAddress string) (*model.PrizePool, *model.PoolParticipant, error)
+}
+
+// ParticipantRepository 奖池参与者数据访问
+type ParticipantRepository interface {
+	// ListByPool 按序号获取奖池的所有参与者
+	ListByPool(poolID uint) ([]model.PoolParticipant, error)
+	Get(poolID, frogID uint) (*model.PoolParticipant, error)
+	// GetLatestByFrog 获取青蛙最近一次的参与记录
+	GetLatestByFrog(frogID uint) (*model.PoolParticipant, error)
+}
+
+// RewardRepository 奖励账本
+type RewardRepository interface {
+	// Post 校验并写入分录，同一事务中将 user-unclaimed / user-paid 的变动同步到用户缓存的余额
+	Post(entry *model.LedgerEntry) error
+	// Balance 科目余额（lamports）
+	Balance(account model.LedgerAccount, ownerID uint) (int64, error)
+	// Balances 按所有者汇总科目余额
+	Balances(account model.LedgerAccount) (map[uint]int64, error)
+	// Total 全部记账金额之和，账本平衡时为0
+	Total() (int64, error)
+	// UnbalancedEntries 记账金额之和不为0的分录ID，账本平衡时为空
+	UnbalancedEntries() ([]uint, error)
+	// HasEntry 是否已有指定类型与关联对象的分录
+	HasEntry(kind string, reference string) (bool, error)
+}
+
+// ActivationRepository 激活请求记录
+type ActivationRepository interface {
+	// GetByKey 按用户与幂等键获取
+	GetByKey(userID uint, idempotencyKey string) (*model.FrogActivation, error)
+	// GetByTransaction 按支付交易获取
+	GetByTransaction(transactionHash string) (*model.FrogActivation, error)
+	// Create 幂等键或支付交易已存在时返回ErrDuplicate
+	Create(activation *model.FrogActivation) error
+	Save(activation *model.FrogActivation) error
+}
+
+// OutboxRepository 领域事件发件箱
+type OutboxRepository interface {
+	// Add 写入待投递的事件，应与产生事件的状态变更在同一事务中调用
+	Add(messages ...model.OutboxMessage) error
+	// Claim 按写入顺序认领至多limit条未投递的消息，租约期内其他投递器不会再认领
+	Claim(limit int, lease time.Duration) ([]model.OutboxMessage, error)
+	// MarkDispatched 标记消息已发布到事件总线
+	MarkDispatched(id uint) error
+	// MarkFailed 记录投递失败，释放租约等待重试
+	MarkFailed(id uint, reason string) error
+	// CountPending 未投递的消息数量
+	CountPending() (int64, error)
+}
+
+// PoolEventRepository 奖池事件存储，只追加
+type PoolEventRepository interface {
+	// Append 按顺序追加同一奖池的事件并分配奖池内的序号，应与产生事件的状态变更在同一事务中调用
+	Append(events ...model.PoolEvent) error
+	// ListByPool 按序号获取奖池的全部事件
+	ListByPool(poolID uint) ([]model.PoolEvent, error)
+}
+
+// JobRepository 持久化的延迟任务队列
+type JobRepository interface {
+	// Schedule 写入待执行的任务，已有相同去重键的未结束任务时不写入并返回false
+	Schedule(job *model.Job) (bool, error)
+	// Claim 按到期时间认领至多limit个到期的任务，租约期内其他执行器不会再认领
+	Claim(limit int, lease time.Duration) ([]model.Job, error)
+	// Finish、Release 与 Bury 记录认领后的执行结果，lockedUntil为认领时的租约到期时间；
+	// 任务已不在该租约下运行（租约过期后被重新认领或已结束）时不做修改并返回ErrLeaseLost
+	//
+	// Finish 标记任务执行成功
+	Finish(id uint, lockedUntil time.Time) error
+	// Release 释放任务，等待在runAt再次执行
+	Release(id uint, lockedUntil time.Time, runAt time.Time, attempts int, lastError string) error
+	// Bury 重试次数用尽，将任务移入死信列表
+	Bury(id uint, lockedUntil time.Time, attempts int, lastError string) error
+	// Requeue 将死信任务重新排队并立即执行，任务不存在或不是死信时返回ErrNotFound
+	Requeue(id uint) error
+	// List 按ID倒序列出指定状态的任务
+	List(status model.JobStatus, limit int) ([]model.Job, error)
+	// CountByStatus 各状态的任务数量
+	CountByStatus() (map[model.JobStatus]int64, error)
+}
+
+// RoleAuditRepository 权限变更审计数据访问
+type RoleAuditRepository interface {
+	Create(log *model.RoleAuditLog) error
+	// List 按ID倒序列出权限变更记录，targetUserID大于0时只列出该用户的记录
+	List(targetUserID uint, limit int) ([]model.RoleAuditLog, error)
+}
+
+// Repositories 服务所需的全部数据访问接口
+type Repositories struct {
+	Users        UserRepository
+	Frogs        FrogRepository
+	Pools        PoolRepository
+	Participants ParticipantRepository
+	Rewards      RewardRepository
+	Activations  ActivationRepository
+	Outbox       OutboxRepository
+	PoolEvents   PoolEventRepository
+	Jobs         JobRepository
+	RoleAudits   RoleAuditRepository
+
+	transaction func(fn func(tx *Repositories) error) error
+}
+
+// Transaction 在同一事务中执行fn，fn返回错误时回滚全部修改
+// fn中须使用传入的tx访问数据，而不是外层的仓库
+func (r *Repositories) Transaction(fn func(tx *Repositories) error) error {
+	return r.transaction(fn)
+}
@@ -29,12 +29,22 @@ const (
 	CodeCheckLogin = 401
 	// CodeNoRightErr 未授权访问
 	CodeNoRightErr = 403
+	// CodeServiceUnavailable 服务暂不可用，如缺少必要配置或依赖不可用
+	CodeServiceUnavailable = 503
 	// CodeDBError 数据库操作失败
 	CodeDBError = 50001
 	// CodeEncryptError 加密失败
 	CodeEncryptError = 50002
+	// CodeLedgerMismatch 对账未通过，账本不平衡或余额与账本不一致
+	CodeLedgerMismatch = 50003
 	//CodeParamErr 各种奇奇怪怪的参数错误
 	CodeParamErr = 40001
+	// CodeAlreadyActive 已有激活的青蛙，本次支付记为待退款
+	CodeAlreadyActive = 40002
+	// CodeIdempotencyConflict 幂等键已用于其他请求，或相同请求仍在处理中
+	CodeIdempotencyConflict = 40003
+	// CodeInvalidTransition 奖池当前状态不允许切换到目标状态
+	CodeInvalidTransition = 40004
 )
 
 // CheckLogin 检查登录
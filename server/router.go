@@ -2,6 +2,8 @@ package server
 
 import (
 	"singo/api"
+	rbac "singo/auth"
+	"singo/metrics"
 	"singo/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -15,33 +17,85 @@ func NewRouter() *gin.Engine {
 	r.Use(middleware.Cors())
 	r.Use(middleware.CurrentUser())
 
+	// 限流（按路由组配置）
+	authLimit := middleware.RateLimit("auth")
+	gameLimit := middleware.RateLimit("game")
+	rewardsLimit := middleware.RateLimit("rewards")
+
 	// 路由
 	v1 := r.Group("/api/v1")
 	{
 		v1.POST("ping", api.Ping)
 
 		// 用户登录
-		v1.POST("auth/login", api.UserLogin)
+		v1.GET("auth/nonce", authLimit, api.AuthNonce)
+		v1.POST("auth/login", authLimit, api.UserLogin)
+		v1.POST("auth/login-tx", authLimit, api.UserMemoLogin)
+		v1.POST("auth/refresh", authLimit, api.RefreshToken)
+
+		// 公开观战（只读）
+		v1.GET("pools/:id/snapshot", api.PoolSnapshot)
+		v1.GET("pools/:id/spectate", api.SpectatePool)
+
+		// 服务端集成（X-API-Key认证，按授权范围控制，按密钥限流）
+		integrations := v1.Group("integrations")
+		{
+			integrations.GET("pools", middleware.RequireScope(rbac.ScopePoolsRead), api.IntegrationPools)
+			integrations.GET("leaderboard", middleware.RequireScope(rbac.ScopeLeaderboardsRead), api.IntegrationLeaderboard)
+		}
 
 		// 需要登录保护的
 		auth := v1.Group("")
 		auth.Use(middleware.AuthRequired())
 		{
+			// 登出
+			auth.POST("auth/logout", api.UserLogout)
+
 			// User Routing
 			auth.GET("users/me", api.UserMe)
-			auth.POST("users/claim-rewards", api.ClaimRewards)
-			auth.POST("users/submit-reward-tx", api.SubmitRewardTx)
+			auth.GET("users/sessions", api.UserSessions)
+			auth.DELETE("users/sessions", api.RevokeAllUserSessions)
+			auth.DELETE("users/sessions/:id", api.RevokeUserSession)
+			auth.POST("users/claim-rewards", rewardsLimit, api.ClaimRewards)
+			auth.POST("users/submit-reward-tx", rewardsLimit, api.SubmitRewardTx)
+
+			// 游戏接口需要参与游戏的权限
+			play := auth.Group("")
+			play.Use(middleware.RequirePermission(rbac.PermGamePlay))
+			{
+				// Game Routing
+				play.POST("game/activate", gameLimit, api.GameActivate)
+				play.PUT("game/hunger", api.UpdateHunger)
+				play.POST("game/catch-big-prize", api.CatchBigPrize)
+
+				// Pool Routing
+				play.GET("pools/current", api.GetCurrentPool)
 
-			// Game Routing
-			auth.POST("game/activate", api.GameActivate)
-			auth.PUT("game/hunger", api.UpdateHunger)
-			auth.POST("game/catch-big-prize", api.CatchBigPrize)
+				// WebSocket连接（需先换取一次性连接票据）
+				play.POST("game/ws-ticket", api.WebSocketTicket)
+				play.GET("game/ws", api.WebSocketHandler)
 
-			// Pool Routing
-			auth.GET("pools/current", api.GetCurrentPool)
+				// SSE事件流（WebSocket不可用时的降级方案，EventSource同样以一次性连接票据认证）
+				play.GET("game/events", api.GameEvents)
+			}
 
-			// WebSocket连接
-			auth.GET("game/ws", api.WebSocketHandler)
+			// 管理接口，按权限控制
+			admin := auth.Group("admin")
+			{
+				admin.GET("connections", middleware.RequirePermission(rbac.PermConnectionsRead), api.AdminConnections)
+				admin.PUT("users/:wallet/role", middleware.RequirePermission(rbac.PermRolesManage), api.AdminUpdateUserRole)
+				admin.GET("role-audit", middleware.RequirePermission(rbac.PermRolesManage), api.AdminRoleAudit)
+				admin.GET("api-keys", middleware.RequirePermission(rbac.PermAPIKeysManage), api.AdminListAPIKeys)
+				admin.POST("api-keys", middleware.RequirePermission(rbac.PermAPIKeysManage), api.AdminCreateAPIKey)
+				admin.DELETE("api-keys/:id", middleware.RequirePermission(rbac.PermAPIKeysManage), api.AdminRevokeAPIKey)
+				admin.GET("ledger/reconcile", middleware.RequirePermission(rbac.PermRewardsRead), api.AdminReconcileLedger)
+				admin.POST("pools/:id/status", middleware.RequirePermission(rbac.PermPoolsManage), api.AdminTransitionPool)
+				admin.GET("pools/:id/history", middleware.RequirePermission(rbac.PermPoolsRead), api.AdminPoolHistory)
+				admin.GET("pools/:id/timeline", middleware.RequirePermission(rbac.PermPoolsRead), api.AdminPoolTimeline)
+				admin.GET("jobs", middleware.RequirePermission(rbac.PermJobsManage), api.AdminListJobs)
+				admin.POST("jobs/:id/retry", middleware.RequirePermission(rbac.PermJobsManage), api.AdminRequeueJob)
+				admin.GET("metrics", middleware.RequirePermission(rbac.PermMetricsRead), gin.WrapH(metrics.Handler()))
+			}
 		}
 	}
 	return r
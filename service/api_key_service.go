@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"singo/auth"
+	"singo/model"
+	"singo/serializer"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIKeyCreateService 创建API密钥的服务
+type APIKeyCreateService struct {
+	Name      string   `form:"name" json:"name" binding:"required,max=64"`
+	Scopes    []string `form:"scopes" json:"scopes" binding:"required,min=1"`
+	RateLimit int      `form:"rateLimit" json:"rateLimit" binding:"min=0,max=10000"` // 每分钟请求数，0为默认值
+}
+
+// apiKeyData API密钥的展示数据（不含明文与哈希）
+func apiKeyData(key *model.APIKey) gin.H {
+	data := gin.H{
+		"id":        key.ID,
+		"name":      key.Name,
+		"prefix":    key.Prefix,
+		"scopes":    key.ScopeList(),
+		"rateLimit": key.RateLimit,
+		"createdAt": key.CreatedAt.Unix(),
+		"revoked":   key.IsRevoked(),
+	}
+	if key.LastUsedAt != nil {
+		data["lastUsedAt"] = key.LastUsedAt.Unix()
+	}
+	if key.RevokedAt != nil {
+		data["revokedAt"] = key.RevokedAt.Unix()
+	}
+	return data
+}
+
+// Create 创建API密钥，明文只在此次响应中返回
+func (service *APIKeyCreateService) Create(actor *model.User) serializer.Response {
+	for _, scope := range service.Scopes {
+		if !auth.ValidScope(scope) {
+			return serializer.ParamErr("Invalid scope: "+scope, nil)
+		}
+	}
+	rateLimit := service.RateLimit
+	if rateLimit == 0 {
+		rateLimit = auth.DefaultAPIKeyRateLimit
+	}
+
+	plain, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return serializer.Err(serializer.CodeEncryptError, "Failed to generate API key", err)
+	}
+
+	key, err := model.CreateAPIKey(service.Name, prefix, hash, service.Scopes, rateLimit, actor.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to create API key", err)
+	}
+	log.Printf("用户 %d 创建了API密钥 %d (%s)，授权范围 %v", actor.ID, key.ID, key.Name, service.Scopes)
+
+	data := apiKeyData(&key)
+	data["key"] = plain
+	return serializer.Response{
+		Code: 0,
+		Data: data,
+		Msg:  "Store this key now, it will not be shown again",
+	}
+}
+
+// ListAPIKeys 列出所有API密钥
+func ListAPIKeys() serializer.Response {
+	keys, err := model.ListAPIKeys()
+	if err != nil {
+		return serializer.DBErr("Failed to list API keys", err)
+	}
+
+	data := make([]gin.H, 0, len(keys))
+	for i := range keys {
+		data = append(data, apiKeyData(&keys[i]))
+	}
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"keys": data,
+		},
+	}
+}
+
+// RevokeAPIKey 吊销API密钥，吊销后立即失效
+func RevokeAPIKey(actor *model.User, keyID string) serializer.Response {
+	key, err := model.GetAPIKey(keyID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return serializer.ParamErr("API key not found", err)
+		}
+		return serializer.DBErr("Failed to get API key", err)
+	}
+
+	if !key.IsRevoked() {
+		if err := key.Revoke(); err != nil {
+			return serializer.DBErr("Failed to revoke API key", err)
+		}
+		log.Printf("用户 %d 吊销了API密钥 %d (%s)", actor.ID, key.ID, key.Name)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: apiKeyData(&key),
+	}
+}
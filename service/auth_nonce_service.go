@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"singo/cache"
+	"singo/serializer"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// nonceTTL 登录nonce的有效期，与SIWS消息的最长有效期一致
+	nonceTTL = siwsMaxValidity
+	// nonceKeyPrefix 登录nonce在Redis中的键前缀
+	nonceKeyPrefix = "auth:nonce:"
+)
+
+// ErrInvalidNonce nonce不存在、已使用或不属于该钱包
+var ErrInvalidNonce = errors.New("invalid or used nonce")
+
+// AuthNonceService 获取登录nonce的服务
+type AuthNonceService struct {
+	WalletAddress string `form:"walletAddress" json:"walletAddress" binding:"required"`
+}
+
+// Issue 签发一次性nonce并返回待签名的SIWS消息
+func (service *AuthNonceService) Issue(c *gin.Context) serializer.Response {
+	config := loadSIWSConfig()
+	if err := config.Check(); err != nil {
+		return serializer.Err(serializer.CodeServiceUnavailable, "Sign-in is not configured", err)
+	}
+
+	nonce, err := generateNonce()
+	if err != nil {
+		return serializer.Err(serializer.CodeEncryptError, "Failed to generate nonce", err)
+	}
+
+	if err := cache.RedisClient.Set(context.Background(), nonceKeyPrefix+nonce, service.WalletAddress, nonceTTL).Err(); err != nil {
+		return serializer.Err(serializer.CodeDBError, "Failed to store nonce", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	message := SIWSMessage{
+		Domain:         config.Domain,
+		Address:        service.WalletAddress,
+		Statement:      siwsStatement,
+		URI:            config.URI,
+		Version:        siwsVersion,
+		ChainID:        config.ChainID,
+		Nonce:          nonce,
+		IssuedAt:       now,
+		ExpirationTime: now.Add(nonceTTL),
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"nonce":          nonce,
+			"domain":         message.Domain,
+			"uri":            message.URI,
+			"version":        message.Version,
+			"chainId":        message.ChainID,
+			"statement":      message.Statement,
+			"issuedAt":       message.IssuedAt.Format(time.RFC3339),
+			"expirationTime": message.ExpirationTime.Format(time.RFC3339),
+			"message":        message.String(),
+		},
+	}
+}
+
+// consumeNonce 原子地取出并删除nonce，确保只能使用一次
+func consumeNonce(nonce string, walletAddress string) error {
+	owner, err := cache.RedisClient.GetDel(context.Background(), nonceKeyPrefix+nonce).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrInvalidNonce
+		}
+		return err
+	}
+	if owner != walletAddress {
+		return ErrInvalidNonce
+	}
+	return nil
+}
+
+// generateNonce 生成随机nonce
+func generateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -32,6 +32,11 @@ func (service *ClaimRewardsService) CreateTransaction(user *model.User) serializ
 		return serializer.Err(serializer.CodeDBError, "Failed to create transaction", err)
 	}
 
+	// 记录本次提取：交易过期后仍未提交时通知玩家重新提取，提取事件经发件箱发布
+	if err := GetGameJobs().RecordClaim(user.ID, user.UnclaimedRewards); err != nil {
+		return serializer.DBErr("Failed to record claim", err)
+	}
+
 	return serializer.Response{
 		Code: 0,
 		Data: ClaimRewardsResponse{
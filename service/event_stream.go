@@ -0,0 +1,136 @@
+package service
+
+import "log"
+
+const (
+	// streamHistorySize 为断线续传保留的最近事件数量
+	streamHistorySize = 512
+	// streamBufferSize 每个SSE订阅者的事件缓冲区大小
+	streamBufferSize = 64
+)
+
+// StreamEvent 推送给SSE订阅者的游戏事件
+type StreamEvent struct {
+	ID     uint64                 // 递增的事件ID，对应SSE的id字段；0表示不可续传（初始状态与定时推送）
+	UserID uint                   // 目标用户ID，0表示广播给所有订阅者
+	Type   string                 // 事件类型，与WebSocket消息的type一致
+	Data   map[string]interface{} // 事件内容，与WebSocket消息一致
+}
+
+// EventStream SSE事件订阅
+type EventStream struct {
+	UserID uint
+	Events chan StreamEvent // 订阅者过慢时会被关闭，客户端需携带Last-Event-ID重连
+}
+
+// visibleTo 判断事件是否应推送给指定用户
+func (e StreamEvent) visibleTo(userID uint) bool {
+	return e.UserID == 0 || e.UserID == userID
+}
+
+// publish 记录事件并推送给所有SSE订阅者
+func (m *WebSocketManager) publish(userID uint, message map[string]interface{}) {
+	m.streamsMux.Lock()
+	defer m.streamsMux.Unlock()
+
+	m.eventSeq++
+	e := newStreamEvent(userID, message)
+	e.ID = m.eventSeq
+
+	m.history = append(m.history, e)
+	if len(m.history) > streamHistorySize {
+		m.history = m.history[len(m.history)-streamHistorySize:]
+	}
+
+	m.dispatch(e)
+}
+
+// publishLive 只推送给当前的SSE订阅者，不分配事件ID也不写入续传历史
+// 用于定时推送的状态值，断线期间错过的推送由之后的推送覆盖
+func (m *WebSocketManager) publishLive(userID uint, message map[string]interface{}) {
+	m.streamsMux.Lock()
+	defer m.streamsMux.Unlock()
+
+	m.dispatch(newStreamEvent(userID, message))
+}
+
+// newStreamEvent 由WebSocket消息构造SSE事件
+func newStreamEvent(userID uint, message map[string]interface{}) StreamEvent {
+	eventType, _ := message["type"].(string)
+	return StreamEvent{
+		UserID: userID,
+		Type:   eventType,
+		Data:   message,
+	}
+}
+
+// dispatch 将事件推送给可见的订阅者，调用方需持有streamsMux
+func (m *WebSocketManager) dispatch(e StreamEvent) {
+	for stream := range m.streams {
+		if !e.visibleTo(stream.UserID) {
+			continue
+		}
+		select {
+		case stream.Events <- e:
+		default:
+			// 订阅者消费过慢，断开让其重连续传
+			log.Printf("用户 %d 的SSE订阅缓冲区已满，断开连接", stream.UserID)
+			delete(m.streams, stream)
+			close(stream.Events)
+		}
+	}
+}
+
+// SubscribeStream 订阅SSE事件
+// lastEventID大于0时返回其之后的历史事件；resumed为false表示无法续传，调用方应先发送完整的初始状态
+func (m *WebSocketManager) SubscribeStream(userID uint, lastEventID uint64) (stream *EventStream, backlog []StreamEvent, resumed bool) {
+	m.streamsMux.Lock()
+	defer m.streamsMux.Unlock()
+
+	if lastEventID > 0 && lastEventID <= m.eventSeq {
+		// 历史记录需覆盖lastEventID之后的全部事件才能续传
+		if len(m.history) == 0 || m.history[0].ID <= lastEventID+1 {
+			resumed = true
+			for _, e := range m.history {
+				if e.ID > lastEventID && e.visibleTo(userID) {
+					backlog = append(backlog, e)
+				}
+			}
+		}
+	}
+
+	stream = &EventStream{
+		UserID: userID,
+		Events: make(chan StreamEvent, streamBufferSize),
+	}
+	m.streams[stream] = struct{}{}
+	log.Printf("用户 %d 订阅SSE事件流，lastEventID=%d, 续传=%v", userID, lastEventID, resumed)
+
+	return stream, backlog, resumed
+}
+
+// UnsubscribeStream 取消SSE事件订阅
+func (m *WebSocketManager) UnsubscribeStream(stream *EventStream) {
+	m.streamsMux.Lock()
+	defer m.streamsMux.Unlock()
+
+	if _, exists := m.streams[stream]; exists {
+		delete(m.streams, stream)
+		close(stream.Events)
+		log.Printf("用户 %d 的SSE事件流已取消订阅", stream.UserID)
+	}
+}
+
+// InitialStreamEvents 获取用户的初始状态事件，用于新建或无法续传的SSE连接
+func (m *WebSocketManager) InitialStreamEvents(userID uint) ([]StreamEvent, error) {
+	messages, err := m.buildInitialState(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]StreamEvent, 0, len(messages))
+	for _, message := range messages {
+		events = append(events, newStreamEvent(userID, message))
+	}
+	return events, nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import "testing"
+
+// newStreamManager 独立的WebSocket管理器，事件序号与历史不受其他测试影响
+func newStreamManager() *WebSocketManager {
+	return NewWebSocketManager(testRepos, GetRewardService(), GetPoolEngine())
+}
+
+// 携带Last-Event-ID订阅时补发之后的事件，只包含广播与发给自己的事件
+func TestStreamResumesAfterLastEventID(t *testing.T) {
+	m := newStreamManager()
+
+	m.publish(1, map[string]interface{}{"type": "hunger-update", "n": 1})
+	first := m.eventSeq
+	m.publish(2, map[string]interface{}{"type": "hunger-update", "n": 2})
+	m.publish(0, map[string]interface{}{"type": "game-over", "n": 3})
+	m.publish(1, map[string]interface{}{"type": "hunger-update", "n": 4})
+
+	stream, backlog, resumed := m.SubscribeStream(1, first)
+	defer m.UnsubscribeStream(stream)
+	if !resumed {
+		t.Fatal("stream not resumed")
+	}
+	if len(backlog) != 2 || backlog[0].Data["n"] != 3 || backlog[1].Data["n"] != 4 {
+		t.Fatalf("backlog = %+v", backlog)
+	}
+	if backlog[0].ID != first+2 || backlog[1].ID != first+3 {
+		t.Fatalf("backlog ids = %d, %d", backlog[0].ID, backlog[1].ID)
+	}
+
+	// 订阅后的事件实时推送，其他用户的事件不推送
+	m.publish(2, map[string]interface{}{"type": "hunger-update", "n": 5})
+	m.publish(1, map[string]interface{}{"type": "hunger-update", "n": 6})
+	if e := <-stream.Events; e.Data["n"] != 6 {
+		t.Fatalf("live event = %+v", e)
+	}
+}
+
+// 没有Last-Event-ID、ID未知或历史已被覆盖时无法续传，调用方需发送完整的初始状态
+func TestStreamCannotResume(t *testing.T) {
+	m := newStreamManager()
+	m.publish(0, map[string]interface{}{"type": "game-over"})
+	oldest := m.eventSeq
+
+	for _, lastEventID := range []uint64{0, oldest + 1, 42} {
+		stream, backlog, resumed := m.SubscribeStream(1, lastEventID)
+		m.UnsubscribeStream(stream)
+		if resumed || len(backlog) != 0 {
+			t.Fatalf("lastEventID %d: resumed=%v backlog=%d", lastEventID, resumed, len(backlog))
+		}
+	}
+
+	for i := 0; i < streamHistorySize+1; i++ {
+		m.publish(0, map[string]interface{}{"type": "game-over"})
+	}
+	stream, _, resumed := m.SubscribeStream(1, oldest)
+	m.UnsubscribeStream(stream)
+	if resumed {
+		t.Fatal("resumed past the end of the history")
+	}
+
+	// 刚好在历史范围内时可以续传
+	stream, backlog, resumed := m.SubscribeStream(1, m.history[0].ID-1)
+	m.UnsubscribeStream(stream)
+	if !resumed || len(backlog) != streamHistorySize {
+		t.Fatalf("resumed=%v backlog=%d", resumed, len(backlog))
+	}
+}
+
+// 消费过慢的订阅者被断开，需要携带Last-Event-ID重连
+func TestStreamDropsSlowSubscriber(t *testing.T) {
+	m := newStreamManager()
+	stream, _, _ := m.SubscribeStream(1, 0)
+
+	for i := 0; i < streamBufferSize+1; i++ {
+		m.publish(1, map[string]interface{}{"type": "hunger-update"})
+	}
+
+	received := 0
+	for range stream.Events {
+		received++
+	}
+	if received != streamBufferSize {
+		t.Fatalf("received %d events before disconnect, want %d", received, streamBufferSize)
+	}
+	// 已断开的订阅可以重复取消
+	m.UnsubscribeStream(stream)
+}
+
+// 定时推送的事件只推送给当前订阅者，不占用续传历史
+func TestStreamLiveEventsNotRecorded(t *testing.T) {
+	m := newStreamManager()
+
+	m.publish(1, map[string]interface{}{"type": "game-over", "n": 1})
+	first := m.eventSeq
+
+	stream, _, _ := m.SubscribeStream(1, 0)
+	for i := 0; i < streamHistorySize; i++ {
+		m.publishLive(1, map[string]interface{}{"type": "hunger-update"})
+		if e := <-stream.Events; e.ID != 0 || e.Type != "hunger-update" {
+			t.Fatalf("live event = %+v", e)
+		}
+	}
+	m.UnsubscribeStream(stream)
+	m.publish(1, map[string]interface{}{"type": "game-over", "n": 2})
+
+	resumed, backlog, ok := m.SubscribeStream(1, first)
+	defer m.UnsubscribeStream(resumed)
+	if !ok || len(backlog) != 1 || backlog[0].Data["n"] != 2 {
+		t.Fatalf("resumed = %v, backlog = %+v", ok, backlog)
+	}
+}
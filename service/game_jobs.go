@@ -0,0 +1,266 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"os"
+	"singo/event"
+	"singo/model"
+	"singo/repository"
+	"strconv"
+	"time"
+)
+
+// 游戏相关的延迟任务类型
+const (
+	JobLobbyTimeout = "pool.lobby_timeout"  // 收集中的奖池超时未满员时取消并退还激活费
+	JobStarvation   = "frog.starvation"     // 青蛙饥饿值降至0的时间到期时停用
+	JobClaimExpiry  = "reward.claim_expiry" // 提取奖励的交易过期后通知玩家重新提取
+	JobPayoutRetry  = "reward.payout_retry" // 链上已转账但记账失败时重试结算
+)
+
+const (
+	// defaultLobbyTimeout 默认的奖池等待满员时间
+	defaultLobbyTimeout = 10 * time.Minute
+	// defaultClaimExpiry 默认的提取交易有效期，与交易的最近区块哈希有效期相当
+	defaultClaimExpiry = 2 * time.Minute
+	// payoutMaxAttempts 结算重试的最大次数，玩家已收到转账，尽量重试到成功
+	payoutMaxAttempts = 10
+)
+
+// poolJob 奖池任务参数
+type poolJob struct {
+	PoolID uint `json:"poolId"`
+}
+
+// frogJob 青蛙任务参数
+type frogJob struct {
+	FrogID uint `json:"frogId"`
+}
+
+// claimJob 提取奖励任务参数
+type claimJob struct {
+	UserID uint    `json:"userId"`
+	Amount float64 `json:"amount"`
+}
+
+// payoutJob 结算重试任务参数
+type payoutJob struct {
+	UserID          uint    `json:"userId"`
+	Amount          float64 `json:"amount"`
+	TransactionHash string  `json:"transactionHash"`
+}
+
+// GameJobs 游戏中的定时任务：奖池等待超时、青蛙饿死、提取过期与结算重试
+type GameJobs struct {
+	repos   *repository.Repositories
+	ws      *WebSocketManager
+	rewards *RewardService
+	engine  *PoolEngine
+
+	lobbyTimeout time.Duration
+	claimExpiry  time.Duration
+}
+
+// NewGameJobs 创建游戏定时任务，时长由 LOBBY_TIMEOUT 与 CLAIM_EXPIRY 配置
+func NewGameJobs(repos *repository.Repositories, ws *WebSocketManager, rewards *RewardService, engine *PoolEngine) *GameJobs {
+	return &GameJobs{
+		repos:        repos,
+		ws:           ws,
+		rewards:      rewards,
+		engine:       engine,
+		lobbyTimeout: durationEnv("LOBBY_TIMEOUT", defaultLobbyTimeout),
+		claimExpiry:  durationEnv("CLAIM_EXPIRY", defaultClaimExpiry),
+	}
+}
+
+// GetGameJobs 获取游戏定时任务实例
+func GetGameJobs() *GameJobs {
+	return gameJobs
+}
+
+// durationEnv 读取时长类型的环境变量，未配置或无效时使用默认值
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("%s 配置无效: %s，使用默认值 %v", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// Register 向任务队列注册处理器
+func (j *GameJobs) Register(q *JobQueue) {
+	HandleJob(q, JobLobbyTimeout, j.lobbyTimedOut)
+	HandleJob(q, JobStarvation, j.starvationDue)
+	HandleJob(q, JobClaimExpiry, j.claimExpired)
+	HandleJob(q, JobPayoutRetry, j.retryPayout)
+}
+
+// scheduleLobbyTimeout 奖池创建时在同一事务中安排等待超时
+func (j *GameJobs) scheduleLobbyTimeout(tx *repository.Repositories, poolID uint) error {
+	return scheduleJob(tx.Jobs, JobLobbyTimeout, poolJob{PoolID: poolID},
+		time.Now().Add(j.lobbyTimeout), defaultJobMaxAttempts, "lobby:"+strconv.FormatUint(uint64(poolID), 10))
+}
+
+// scheduleStarvation 在同一事务中安排青蛙的饿死时间，已有未结束的任务时由该任务到期后按最新的投喂时间推迟
+func (j *GameJobs) scheduleStarvation(tx *repository.Repositories, frog *model.Frog) error {
+	return scheduleJob(tx.Jobs, JobStarvation, frogJob{FrogID: frog.ID},
+		frog.StarvesAt(), defaultJobMaxAttempts, "starve:"+strconv.FormatUint(uint64(frog.ID), 10))
+}
+
+// RecordClaim 玩家发起提取后，在同一事务中安排过期通知并写入提取事件，事务提交后才发布事件
+func (j *GameJobs) RecordClaim(userID uint, amount float64) error {
+	return withEvents(j.repos, j.rewards.relay, func(tx *repository.Repositories) ([]event.Event, error) {
+		err := scheduleJob(tx.Jobs, JobClaimExpiry, claimJob{UserID: userID, Amount: amount},
+			time.Now().Add(j.claimExpiry), defaultJobMaxAttempts, "")
+		if err != nil {
+			return nil, err
+		}
+		return []event.Event{event.RewardClaimed{UserID: userID, Amount: amount}}, nil
+	})
+}
+
+// SchedulePayoutRetry 链上转账成功但结算失败时安排重试，同一笔交易只安排一次
+func (j *GameJobs) SchedulePayoutRetry(userID uint, amount float64, transactionHash string) error {
+	return scheduleJob(j.repos.Jobs, JobPayoutRetry, payoutJob{UserID: userID, Amount: amount, TransactionHash: transactionHash},
+		time.Now(), payoutMaxAttempts, "payout:"+transactionHash)
+}
+
+// lobbyTimedOut 奖池等待超时仍未满员时取消奖池，停用青蛙并退还激活费
+func (j *GameJobs) lobbyTimedOut(job *model.Job, payload poolJob) error {
+	return j.engine.Do(payload.PoolID, "lobby-timeout", func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+		// 已满员开始或已经结束
+		if pool.Status != model.PoolStatusCollecting {
+			return nil, nil
+		}
+
+		changed, err := transitionPool(tx, pool, model.PoolStatusCancelled, "lobby timeout", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		participants, err := tx.Participants.ListByPool(pool.ID)
+		if err != nil {
+			return nil, err
+		}
+		var userIDs []uint
+		for _, participant := range participants {
+			frog, err := tx.Frogs.Get(participant.FrogID)
+			if err != nil {
+				return nil, err
+			}
+			userIDs = append(userIDs, frog.UserID)
+			if !frog.IsActive {
+				continue
+			}
+			frog.IsActive = false
+			if err := tx.Frogs.Save(frog); err != nil {
+				return nil, err
+			}
+		}
+
+		// 奖池未开始，激活费全部退还给参与者
+		if err := j.rewards.using(tx).RefundPool(pool.ID, userIDs); err != nil {
+			return nil, err
+		}
+
+		log.Printf("奖池 %d 等待超时未满员，已取消并退还 %d 位玩家的激活费", pool.ID, len(userIDs))
+		return []event.Event{
+			changed,
+			event.PoolParticipantsChanged{PoolID: pool.ID},
+			event.PoolCompleted{PoolID: pool.ID},
+		}, nil
+	})
+}
+
+// starvationDue 青蛙的饿死时间到期时衰减饥饿值；期间被投喂过则推迟到新的饿死时间
+func (j *GameJobs) starvationDue(job *model.Job, payload frogJob) error {
+	frog, err := j.repos.Frogs.Get(payload.FrogID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !frog.IsActive {
+		return nil
+	}
+	if starvesAt := frog.StarvesAt(); time.Now().Before(starvesAt) {
+		return RescheduleJob(starvesAt)
+	}
+
+	var poolID uint
+	if participant, err := j.repos.Participants.GetLatestByFrog(frog.ID); err == nil {
+		poolID = participant.PoolID
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	// 在奖池的执行协程中衰减，不会与投喂交错；这是唯一修改饥饿值衰减的路径
+	var next time.Time
+	err = j.engine.Do(poolID, "starve", func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+		events, err := j.ws.decayHunger(tx, pool, []uint{frog.ID})
+		if err != nil {
+			return nil, err
+		}
+		current, err := tx.Frogs.Get(frog.ID)
+		if err != nil {
+			return nil, err
+		}
+		if current.IsActive {
+			next = current.StarvesAt()
+		}
+		return events, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !next.IsZero() {
+		return RescheduleJob(next)
+	}
+	return nil
+}
+
+// claimExpired 提取交易过期时奖励仍未结算，通知玩家重新提取
+func (j *GameJobs) claimExpired(job *model.Job, payload claimJob) error {
+	user, err := j.repos.Users.Get(payload.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.UnclaimedRewards <= 0 {
+		return nil
+	}
+
+	j.ws.NotifyClaimExpired(user.ID, user.UnclaimedRewards)
+	return nil
+}
+
+// retryPayout 重试结算；分录以链上交易签名关联，首次结算已提交（如提交后连接中断）或已重试过时直接完成，保证只记账一次
+func (j *GameJobs) retryPayout(job *model.Job, payload payoutJob) error {
+	settled, err := j.repos.Rewards.HasEntry(model.EntryClaim, model.PayoutReference(payload.TransactionHash))
+	if err != nil {
+		return err
+	}
+	if settled {
+		return nil
+	}
+
+	user, err := j.repos.Users.Get(payload.UserID)
+	if err != nil {
+		return err
+	}
+	if err := j.rewards.Settle(user, payload.Amount, payload.TransactionHash); err != nil {
+		return err
+	}
+
+	log.Printf("用户 %d 的奖励结算重试成功: amount=%v, tx=%s", user.ID, payload.Amount, payload.TransactionHash)
+	return nil
+}
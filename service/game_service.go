@@ -5,21 +5,32 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"singo/event"
 	"singo/model"
+	"singo/repository"
 	"singo/serializer"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+)
+
+var (
+	// errPoolNotActive 事务中发现奖池已不是活跃状态
+	errPoolNotActive = errors.New("pool is not active")
+	// errFrogInactive 执行命令时青蛙已被停用
+	errFrogInactive = errors.New("frog is not active")
 )
 
 // GameActivateService 游戏激活服务
 type GameActivateService struct {
 	TransactionHash string `form:"transactionHash" json:"transactionHash" binding:"required"`
+	// IdempotencyKey 来自 Idempotency-Key 请求头，相同的键重复提交返回首次的结果
+	IdempotencyKey string `json:"-"`
 }
 
-// GameHungerService 饥饿值更新服务
+// GameHungerService 饥饿值更新服务，一次投喂最多补满饥饿值
 type GameHungerService struct {
-	PizzaValue float64 `form:"pizzaValue" json:"pizzaValue" binding:"required"`
+	PizzaValue float64 `form:"pizzaValue" json:"pizzaValue" binding:"required,gt=0,lte=100"`
 }
 
 // GameCatchPrizeService 抓取大奖服务
@@ -27,15 +38,87 @@ type GameCatchPrizeService struct {
 	PoolID uint `form:"poolId" json:"poolId" binding:"required"`
 }
 
+// GameService 游戏逻辑：激活、投喂与抓取大奖
+type GameService struct {
+	frogs        repository.FrogRepository
+	pools        repository.PoolRepository
+	participants repository.ParticipantRepository
+	activations  repository.ActivationRepository
+	rewards      *RewardService
+	ws           *WebSocketManager
+	repos        *repository.Repositories
+	engine       *PoolEngine
+	jobs         *GameJobs
+
+	// verifyPayment 校验激活转账，测试时可替换
+	verifyPayment func(txHash string, treasury string) (bool, error)
+}
+
+// NewGameService 创建游戏服务
+func NewGameService(repos *repository.Repositories, ws *WebSocketManager, rewards *RewardService, engine *PoolEngine, jobs *GameJobs) *GameService {
+	return &GameService{
+		frogs:         repos.Frogs,
+		pools:         repos.Pools,
+		participants:  repos.Participants,
+		activations:   repos.Activations,
+		rewards:       rewards,
+		ws:            ws,
+		repos:         repos,
+		engine:        engine,
+		jobs:          jobs,
+		verifyPayment: VerifyTransaction,
+	}
+}
+
 // Activate 激活青蛙
 func (service *GameActivateService) Activate(c *gin.Context, user *model.User) serializer.Response {
+	if len(service.IdempotencyKey) > 64 {
+		return serializer.ParamErr("Idempotency-Key is too long", nil)
+	}
+	return GetGameService().Activate(user, service.TransactionHash, service.IdempotencyKey)
+}
+
+// UpdateHunger 更新饥饿值
+func (service *GameHungerService) UpdateHunger(c *gin.Context, user *model.User) serializer.Response {
+	return GetGameService().Feed(user, service.PizzaValue)
+}
+
+// CatchBigPrize 抓取大奖
+func (service *GameCatchPrizeService) CatchBigPrize(c *gin.Context, user *model.User) serializer.Response {
+	return GetGameService().CatchBigPrize(user, service.PoolID)
+}
+
+// HasActiveFrog 用户是否有激活的青蛙
+func (s *GameService) HasActiveFrog(userID uint) (bool, error) {
+	if _, err := s.frogs.GetActiveByUser(userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Activate 校验激活转账，创建青蛙并加入奖池
+//
+// 同一幂等键或同一笔支付重复提交时返回首次处理的结果；
+// 用户已有激活的青蛙时不再创建新的青蛙，本次支付记为待退款。
+func (s *GameService) Activate(user *model.User, transactionHash string, idempotencyKey string) serializer.Response {
 	treasuryPublicKey := os.Getenv("TREASURY_PUBLIC_KEY")
 	if treasuryPublicKey == "" {
 		return serializer.ParamErr("Treasury public key not configured", nil)
 	}
 
+	// 重复的请求返回已有的结果
+	if existing, res, done := s.findActivation(user, transactionHash, idempotencyKey); done {
+		if existing != nil {
+			return s.activationResponse(existing)
+		}
+		return res
+	}
+
 	// 验证转账交易
-	verified, err := VerifyTransaction(service.TransactionHash, treasuryPublicKey)
+	verified, err := s.verifyPayment(transactionHash, treasuryPublicKey)
 	if err != nil {
 		return serializer.ParamErr(fmt.Sprintf("Failed to verify transaction: %v", err), err)
 	}
@@ -43,41 +126,197 @@ func (service *GameActivateService) Activate(c *gin.Context, user *model.User) s
 		return serializer.ParamErr("Invalid transaction", nil)
 	}
 
-	// 创建青蛙
-	frog, err := model.CreateFrog(user.ID)
-	if err != nil {
-		return serializer.DBErr("Failed to create frog", err)
+	// 先记录激活请求，唯一约束保证同一笔支付只处理一次
+	activation := model.FrogActivation{
+		UserID:          user.ID,
+		TransactionHash: transactionHash,
+		Status:          model.ActivationPending,
+	}
+	if idempotencyKey != "" {
+		activation.IdempotencyKey = &idempotencyKey
 	}
+	if err := s.activations.Create(&activation); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return serializer.Err(serializer.CodeIdempotencyConflict, "Activation is already being processed", nil)
+		}
+		return serializer.DBErr("Failed to record activation", err)
+	}
+
+	// 创建青蛙、加入奖池、记账与写入事件在收集中奖池的执行协程中以同一事务完成
+	var frog model.Frog
+	var pool *model.PrizePool
+	err = s.engine.Join(func(tx *repository.Repositories, current *model.PrizePool) ([]event.Event, error) {
+		// 已有激活的青蛙时唯一约束拒绝
+		frog = model.NewFrog(user.ID)
+		if err := tx.Frogs.Create(&frog); err != nil {
+			return nil, err
+		}
+
+		// 加入收集中的奖池，没有时自动创建
+		joined, participant, err := tx.Pools.Join(frog.ID, user.WalletAddress)
+		if err != nil {
+			return nil, err
+		}
+		pool = joined
+		if current != nil && current.ID == joined.ID {
+			*current = *joined
+		}
+
+		// 激活费记入奖池奖金
+		if err := s.rewards.using(tx).RecordActivation(pool.ID); err != nil {
+			return nil, err
+		}
+
+		activation.Status = model.ActivationJoined
+		activation.FrogID = &frog.ID
+		activation.PoolID = &pool.ID
+		activation.SerialNumber = participant.SerialNumber
+		if err := tx.Activations.Save(&activation); err != nil {
+			return nil, err
+		}
+
+		// 新建的奖池安排等待超时，青蛙安排饿死时间
+		if participant.SerialNumber == 1 {
+			if err := s.jobs.scheduleLobbyTimeout(tx, pool.ID); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.jobs.scheduleStarvation(tx, &frog); err != nil {
+			return nil, err
+		}
+		// 满员后奖池变为活跃，开始定期移动大奖
+		if pool.Status == model.PoolStatusActive {
+			if err := schedulePrizeMove(tx, pool.ID); err != nil {
+				return nil, err
+			}
+		}
 
-	// 获取或创建奖池
-	pool, err := model.GetAvailablePool()
+		return joinEvents(&frog, pool, participant), nil
+	})
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// 没有可用的奖池，创建新的
-			pool, err = model.CreatePool()
-			if err != nil {
-				return serializer.DBErr("Failed to create pool", err)
+		if errors.Is(err, repository.ErrDuplicate) {
+			return s.markRefundable(&activation)
+		}
+		return serializer.DBErr("Failed to activate frog", err)
+	}
+
+	return joinedResponse(&activation, &frog, pool)
+}
+
+// joinEvents 玩家加入后的事件，新建的奖池记录创建，满员时奖池变为活跃
+func joinEvents(frog *model.Frog, pool *model.PrizePool, participant *model.PoolParticipant) []event.Event {
+	var events []event.Event
+	if participant.SerialNumber == 1 {
+		// 第一位玩家加入时奖池刚刚创建
+		events = append(events, event.PoolStatusChanged{
+			PoolID: pool.ID,
+			To:     string(model.PoolStatusCollecting),
+			Reason: "created",
+		})
+	}
+	events = append(events,
+		event.FrogActivated{
+			UserID:        frog.UserID,
+			FrogID:        frog.ID,
+			PoolID:        pool.ID,
+			SerialNumber:  participant.SerialNumber,
+			WalletAddress: participant.WalletAddress,
+			HungerLevel:   frog.HungerLevel,
+		},
+		event.PoolParticipantsChanged{PoolID: pool.ID},
+	)
+	if pool.Status == model.PoolStatusActive {
+		events = append(events,
+			event.PoolStatusChanged{
+				PoolID: pool.ID,
+				From:   string(model.PoolStatusCollecting),
+				To:     string(model.PoolStatusActive),
+				Reason: "pool full",
+			},
+			event.PoolActivated{PoolID: pool.ID},
+		)
+	}
+	return events
+}
+
+// findActivation 按幂等键与支付交易查找已有的激活请求
+// done为true时直接返回：existing非nil表示重复请求，否则返回res
+func (s *GameService) findActivation(user *model.User, transactionHash string, idempotencyKey string) (existing *model.FrogActivation, res serializer.Response, done bool) {
+	if idempotencyKey != "" {
+		activation, err := s.activations.GetByKey(user.ID, idempotencyKey)
+		if err == nil {
+			if activation.TransactionHash != transactionHash {
+				return nil, serializer.Err(serializer.CodeIdempotencyConflict, "Idempotency-Key was used with a different transaction", nil), true
 			}
-		} else {
-			return serializer.DBErr("Failed to get pool", err)
+			return activation, res, true
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, serializer.DBErr("Failed to get activation", err), true
 		}
 	}
 
-	// 将青蛙添加到奖池
-	err = pool.AddParticipant(frog.ID, user.WalletAddress)
-	if err != nil {
-		return serializer.DBErr("Failed to add participant", err)
+	activation, err := s.activations.GetByTransaction(transactionHash)
+	if err == nil {
+		if activation.UserID != user.ID {
+			return nil, serializer.ParamErr("Transaction has already been used", nil), true
+		}
+		return activation, res, true
 	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, serializer.DBErr("Failed to get activation", err), true
+	}
+	return nil, res, false
+}
 
-	// 获取青蛙在奖池中的序号
-	participant, err := model.GetParticipantByFrogAndPool(frog.ID, pool.ID)
+// markRefundable 用户已有激活的青蛙，本次支付记为待退款
+func (s *GameService) markRefundable(activation *model.FrogActivation) serializer.Response {
+	activation.Status = model.ActivationRefundable
+	activation.FrogID, activation.PoolID, activation.SerialNumber = nil, nil, 0
+	err := s.repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Activations.Save(activation); err != nil {
+			return err
+		}
+		return s.rewards.using(tx).RecordRefundable(activation.UserID)
+	})
 	if err != nil {
-		return serializer.DBErr("Failed to get participant info", err)
+		return serializer.DBErr("Failed to update activation", err)
 	}
+	log.Printf("用户 %d 已有激活的青蛙，支付 %s 记为待退款", activation.UserID, activation.TransactionHash)
+	return s.activationResponse(activation)
+}
 
+// activationResponse 根据激活请求的状态构建响应
+func (s *GameService) activationResponse(activation *model.FrogActivation) serializer.Response {
+	switch activation.Status {
+	case model.ActivationJoined:
+		frog, err := s.frogs.Get(*activation.FrogID)
+		if err != nil {
+			return serializer.DBErr("Failed to get frog", err)
+		}
+		pool, err := s.pools.Get(*activation.PoolID)
+		if err != nil {
+			return serializer.DBErr("Failed to get pool", err)
+		}
+		return joinedResponse(activation, frog, pool)
+	case model.ActivationRefundable:
+		return serializer.Response{
+			Code: serializer.CodeAlreadyActive,
+			Msg:  "User already has an active frog, the payment will be refunded",
+			Data: gin.H{
+				"activation": activationData(activation),
+			},
+		}
+	default:
+		return serializer.Err(serializer.CodeIdempotencyConflict, "Activation is still being processed", nil)
+	}
+}
+
+// joinedResponse 激活成功的响应
+func joinedResponse(activation *model.FrogActivation, frog *model.Frog, pool *model.PrizePool) serializer.Response {
 	return serializer.Response{
 		Code: 0,
 		Data: gin.H{
+			"activation": activationData(activation),
 			"frog": gin.H{
 				"id":          frog.ID,
 				"hungerLevel": frog.HungerLevel,
@@ -85,36 +324,80 @@ func (service *GameActivateService) Activate(c *gin.Context, user *model.User) s
 			"poolInfo": gin.H{
 				"id":             pool.ID,
 				"currentPlayers": pool.CurrentPlayers,
-				"serialNumber":   participant.SerialNumber,
+				"serialNumber":   activation.SerialNumber,
 			},
 		},
 	}
 }
 
-// UpdateHunger 更新饥饿值
-func (service *GameHungerService) UpdateHunger(c *gin.Context, user *model.User) serializer.Response {
+// activationData 激活请求的公开字段
+func activationData(activation *model.FrogActivation) gin.H {
+	return gin.H{
+		"id":              activation.ID,
+		"status":          activation.Status,
+		"transactionHash": activation.TransactionHash,
+	}
+}
+
+// Feed 投喂青蛙，增加饥饿值
+func (s *GameService) Feed(user *model.User, pizzaValue float64) serializer.Response {
+	// 记录玩家操作，用于在线状态
+	s.ws.Touch(user.ID, user.WalletAddress)
+
 	// 获取用户的青蛙
-	frog, err := model.GetFrogByUserID(user.ID)
+	frog, err := s.frogs.GetActiveByUser(user.ID)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return serializer.ParamErr("User has no active frog", nil)
+		}
 		return serializer.DBErr("Failed to get frog", err)
 	}
 
-	// 计算新的饥饿值
-	newHungerLevel := frog.HungerLevel + int(service.PizzaValue)
-	log.Printf("用户 %d 的青蛙当前饥饿值: %d, 增加值: %d, 计算后值: %d",
-		user.ID, frog.HungerLevel, int(service.PizzaValue), newHungerLevel)
+	// 青蛙所在的奖池，投喂与该奖池的饥饿值衰减由同一执行协程依次处理
+	var poolID uint
+	if participant, err := s.participants.GetLatestByFrog(frog.ID); err == nil {
+		poolID = participant.PoolID
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return serializer.DBErr("Failed to get pool", err)
+	}
 
-	// 更新饥饿值（会在 UpdateHungerLevel 中自动限制在 0-100 范围内）
-	err = frog.UpdateHungerLevel(newHungerLevel)
+	err = s.engine.Do(poolID, "feed", func(tx *repository.Repositories, _ *model.PrizePool) ([]event.Event, error) {
+		// 执行协程中重新读取，期间可能已被投喂或停用
+		current, err := tx.Frogs.Get(frog.ID)
+		if err != nil {
+			return nil, err
+		}
+		// 饥饿值已降至0的青蛙等待饿死任务停用，不能再投喂
+		hunger := current.CurrentHunger(time.Now())
+		if !current.IsActive || hunger == 0 {
+			return nil, errFrogInactive
+		}
+		frog = current
+
+		// 计算新的饥饿值
+		newHungerLevel := hunger + int(pizzaValue)
+		log.Printf("用户 %d 的青蛙当前饥饿值: %d, 增加值: %d, 计算后值: %d",
+			user.ID, hunger, int(pizzaValue), newHungerLevel)
+
+		// 更新饥饿值（SetHungerLevel 会限制在 0-100 范围内）
+		frog.SetHungerLevel(newHungerLevel)
+		if err := tx.Frogs.Save(frog); err != nil {
+			return nil, err
+		}
+		if err := s.jobs.scheduleStarvation(tx, frog); err != nil {
+			return nil, err
+		}
+		return []event.Event{event.FrogFed{UserID: user.ID, FrogID: frog.ID, PoolID: poolID, HungerLevel: frog.HungerLevel}}, nil
+	})
 	if err != nil {
+		if errors.Is(err, errFrogInactive) {
+			return serializer.ParamErr("User has no active frog", nil)
+		}
 		return serializer.DBErr("Failed to update hunger level", err)
 	}
 
 	log.Printf("用户 %d 的青蛙饥饿值已更新为: %d", user.ID, frog.HungerLevel)
 
-	// 通过WebSocket广播更新
-	wsManager.BroadcastHungerUpdate(user.ID, frog.ID, frog.HungerLevel)
-
 	return serializer.Response{
 		Code: 0,
 		Data: gin.H{
@@ -123,22 +406,24 @@ func (service *GameHungerService) UpdateHunger(c *gin.Context, user *model.User)
 	}
 }
 
-// CatchBigPrize 抓取大奖
-func (service *GameCatchPrizeService) CatchBigPrize(c *gin.Context, user *model.User) serializer.Response {
+// CatchBigPrize 抓取大奖，完成奖池并发放奖励
+func (s *GameService) CatchBigPrize(user *model.User, poolID uint) serializer.Response {
+	// 记录玩家操作，用于在线状态
+	s.ws.Touch(user.ID, user.WalletAddress)
+
 	// 获取用户的青蛙
-	frog, err := model.GetFrogByUserID(user.ID)
+	frog, err := s.frogs.GetActiveByUser(user.ID)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return serializer.ParamErr("User has no active frog", nil)
+		}
 		return serializer.DBErr("Failed to get frog", err)
 	}
-	if frog == nil {
-		return serializer.ParamErr("User has no active frog", nil)
-	}
 
 	// 获取奖池
-	var pool model.PrizePool
-	result := model.DB.First(&pool, service.PoolID)
-	if result.Error != nil {
-		return serializer.DBErr("Failed to get pool", result.Error)
+	pool, err := s.pools.Get(poolID)
+	if err != nil {
+		return serializer.DBErr("Failed to get pool", err)
 	}
 
 	// 检查奖池状态
@@ -147,55 +432,66 @@ func (service *GameCatchPrizeService) CatchBigPrize(c *gin.Context, user *model.
 	}
 
 	// 检查青蛙是否是参与者
-	_, err = model.GetParticipantByFrogAndPool(frog.ID, pool.ID)
-	if err != nil {
+	if _, err := s.participants.Get(pool.ID, frog.ID); err != nil {
 		return serializer.ParamErr("Frog is not in this pool", nil)
 	}
 
-	// 完成奖池并发放奖励
-	err = pool.CompletePool(user.WalletAddress)
-	if err != nil {
-		return serializer.DBErr("Failed to complete pool", err)
-	}
-
-	// 更新获胜者的未领取奖励
-	user.UnclaimedRewards += pool.PrizeAmount
-	if err := model.DB.Save(user).Error; err != nil {
-		return serializer.DBErr("Failed to update user rewards", err)
-	}
-
-	// 获取该奖池中的所有参与者
-	participants, err := model.GetParticipantsByPoolID(pool.ID)
-	if err != nil {
-		return serializer.DBErr("Failed to get pool participants", err)
-	}
+	// 结算奖池、发放奖励与停用青蛙在奖池的执行协程中以同一事务完成，提交后再广播
+	var reward float64
+	err = s.engine.Do(pool.ID, "catch", func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+		// 以执行协程持有的状态为准，避免与其他结束奖池的操作重复结算
+		if pool.Status != model.PoolStatusActive {
+			return nil, errPoolNotActive
+		}
 
-	// 将所有参与者的青蛙饥饿值设置为0并停用
-	for _, participant := range participants {
-		var participantFrog model.Frog
-		if err := model.DB.First(&participantFrog, participant.FrogID).Error; err != nil {
-			continue // 跳过错误，继续处理其他青蛙
+		// 抓到大奖后进入结算中，奖励支付后再由奖池服务切换为已完成
+		pool.BigPrizeWinner = user.WalletAddress
+		changed, err := transitionPool(tx, pool, model.PoolStatusSettling, "big prize caught", 0)
+		if err != nil {
+			return nil, err
 		}
 
-		participantFrog.HungerLevel = 0
-		participantFrog.IsActive = false
+		// 奖金记入获胜者的未领取奖励
+		reward, err = s.rewards.using(tx).AwardPrize(user, pool)
+		if err != nil {
+			return nil, err
+		}
 
-		if err := model.DB.Save(&participantFrog).Error; err != nil {
-			continue // 跳过错误，继续处理其他青蛙
+		// 将所有参与者的青蛙饥饿值设置为0并停用
+		participants, err := tx.Participants.ListByPool(pool.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, participant := range participants {
+			participantFrog, err := tx.Frogs.Get(participant.FrogID)
+			if err != nil {
+				return nil, err
+			}
+			participantFrog.HungerLevel = 0
+			participantFrog.IsActive = false
+			if err := tx.Frogs.Save(participantFrog); err != nil {
+				return nil, err
+			}
 		}
 
-		// 广播饥饿值更新
-		wsManager.BroadcastHungerUpdate(participantFrog.UserID, participantFrog.ID, 0)
+		return []event.Event{changed, event.PoolCompleted{
+			PoolID:      pool.ID,
+			Winner:      user.WalletAddress,
+			PrizeAmount: pool.PrizeAmount,
+		}}, nil
+	})
+	if err != nil {
+		if errors.Is(err, errPoolNotActive) || errors.Is(err, model.ErrInvalidTransition) {
+			return serializer.ParamErr("Pool is not active", nil)
+		}
+		return serializer.DBErr("Failed to complete pool", err)
 	}
 
-	// 广播游戏结束
-	wsManager.BroadcastGameOver(pool.ID, user.WalletAddress, pool.PrizeAmount)
-
 	return serializer.Response{
 		Code: 0,
 		Data: gin.H{
 			"success": true,
-			"reward":  pool.PrizeAmount,
+			"reward":  reward,
 		},
 	}
 }
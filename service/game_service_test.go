@@ -0,0 +1,211 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"singo/event"
+	"singo/model"
+	"singo/repository"
+	"singo/serializer"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testRepos *repository.Repositories
+
+// TestMain 使用内存仓库初始化服务，跳过链上转账校验
+// 事件处理器异步读取服务实例，因此整个包只初始化一次
+func TestMain(m *testing.M) {
+	os.Setenv("TREASURY_PUBLIC_KEY", "treasury")
+
+	testRepos = repository.NewMemory()
+	Init(testRepos)
+	gameService.verifyPayment = func(string, string) (bool, error) { return true, nil }
+
+	os.Exit(m.Run())
+}
+
+// 满员后奖池转为活跃，抓取大奖后进入结算，奖励支付后完成奖池
+func TestGameServiceFullRound(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+
+	var users []*model.User
+	for i := 0; i < model.MaxPoolPlayers; i++ {
+		user := &model.User{WalletAddress: fmt.Sprintf("round-wallet-%d", i)}
+		if err := repos.Users.Create(user); err != nil {
+			t.Fatal(err)
+		}
+		if res := s.Activate(user, fmt.Sprintf("tx-%d", i), ""); res.Code != 0 {
+			t.Fatalf("activate %d: %+v", i, res)
+		}
+		users = append(users, user)
+	}
+
+	pool, err := s.ws.currentPool(users[0].ID)
+	if err != nil || pool == nil {
+		t.Fatalf("current pool: %v (%v)", pool, err)
+	}
+	if pool.Status != model.PoolStatusActive || pool.CurrentPlayers != model.MaxPoolPlayers {
+		t.Fatalf("pool not active: %+v", pool)
+	}
+
+	if res := s.Feed(users[0], 5); res.Code != 0 {
+		t.Fatalf("feed: %+v", res)
+	}
+
+	winner := users[3]
+	if res := s.CatchBigPrize(winner, pool.ID); res.Code != 0 {
+		t.Fatalf("catch: %+v", res)
+	}
+
+	settling, _ := repos.Pools.Get(pool.ID)
+	if settling.Status != model.PoolStatusSettling || settling.BigPrizeWinner != winner.WalletAddress {
+		t.Fatalf("pool not settling: %+v", settling)
+	}
+
+	saved, _ := repos.Users.Get(winner.ID)
+	if saved.UnclaimedRewards != pool.PrizeAmount {
+		t.Fatalf("unclaimed rewards = %v, want %v", saved.UnclaimedRewards, pool.PrizeAmount)
+	}
+
+	// 奖励支付后奖池完成
+	if err := GetRewardService().Settle(saved, saved.UnclaimedRewards, "round-payout-tx"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetOutboxRelay().Dispatch(); err != nil {
+		t.Fatal(err)
+	}
+	event.Flush()
+
+	completed, _ := repos.Pools.Get(pool.ID)
+	if completed.Status != model.PoolStatusCompleted {
+		t.Fatalf("pool not completed: %+v", completed)
+	}
+
+	history, _ := repos.Pools.ListTransitions(pool.ID)
+	var statuses []model.PoolStatus
+	for _, transition := range history {
+		statuses = append(statuses, transition.ToStatus)
+	}
+	want := []model.PoolStatus{model.PoolStatusCollecting, model.PoolStatusActive, model.PoolStatusSettling, model.PoolStatusCompleted}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Fatalf("history = %v, want %v", statuses, want)
+	}
+
+	for _, user := range users {
+		if active, _ := s.HasActiveFrog(user.ID); active {
+			t.Fatalf("user %d still has an active frog", user.ID)
+		}
+	}
+}
+
+// 不在奖池中的青蛙不能抓取大奖
+func TestGameServiceCatchOutsidePool(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+
+	user := &model.User{WalletAddress: "wallet-a"}
+	repos.Users.Create(user)
+	if res := s.Activate(user, "tx-a", ""); res.Code != 0 {
+		t.Fatalf("activate: %+v", res)
+	}
+
+	other := model.NewPool()
+	other.Status = model.PoolStatusActive
+	repos.Pools.Create(&other)
+
+	if res := s.CatchBigPrize(user, other.ID); res.Code == 0 {
+		t.Fatal("expected catch outside pool to fail")
+	}
+}
+
+// 已有激活的青蛙时再次激活，支付记为待退款
+func TestGameServiceActivateAlreadyActive(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+
+	user := &model.User{WalletAddress: "wallet-twice"}
+	repos.Users.Create(user)
+	if res := s.Activate(user, "tx-twice-1", ""); res.Code != 0 {
+		t.Fatalf("activate: %+v", res)
+	}
+
+	res := s.Activate(user, "tx-twice-2", "")
+	if res.Code != serializer.CodeAlreadyActive {
+		t.Fatalf("second activate: %+v", res)
+	}
+	activation, err := repos.Activations.GetByTransaction("tx-twice-2")
+	if err != nil || activation.Status != model.ActivationRefundable {
+		t.Fatalf("activation = %+v (%v)", activation, err)
+	}
+	if balance, _ := repos.Rewards.Balance(model.AccountUserRefund, user.ID); balance != model.ToLamports(RequiredAmount) {
+		t.Fatalf("refund balance = %d", balance)
+	}
+}
+
+// 相同的幂等键或支付交易重复提交返回首次的结果
+func TestGameServiceActivateReplay(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+
+	user := &model.User{WalletAddress: "wallet-replay"}
+	repos.Users.Create(user)
+	first := s.Activate(user, "tx-replay", "key-1")
+	if first.Code != 0 {
+		t.Fatalf("activate: %+v", first)
+	}
+	frogID := first.Data.(gin.H)["frog"].(gin.H)["id"]
+
+	for _, key := range []string{"key-1", ""} {
+		res := s.Activate(user, "tx-replay", key)
+		if res.Code != 0 {
+			t.Fatalf("replay with key %q: %+v", key, res)
+		}
+		if got := res.Data.(gin.H)["frog"].(gin.H)["id"]; got != frogID {
+			t.Fatalf("replay with key %q returned frog %v, want %v", key, got, frogID)
+		}
+	}
+
+	if res := s.Activate(user, "tx-replay-other", "key-1"); res.Code != serializer.CodeIdempotencyConflict {
+		t.Fatalf("reused key: %+v", res)
+	}
+
+	other := &model.User{WalletAddress: "wallet-replay-other"}
+	repos.Users.Create(other)
+	if res := s.Activate(other, "tx-replay", ""); res.Code == 0 {
+		t.Fatal("expected another user's transaction to be rejected")
+	}
+}
+
+// 投喂以按时间计算的饥饿值为基础，饥饿值已降至0的青蛙不能投喂
+func TestGameServiceFeedUsesCurrentHunger(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+
+	user := &model.User{WalletAddress: "feed-hunger-wallet"}
+	repos.Users.Create(user)
+	if res := s.Activate(user, "feed-hunger-tx", ""); res.Code != 0 {
+		t.Fatalf("activate: %+v", res)
+	}
+	frog, _ := repos.Frogs.GetActiveByUser(user.ID)
+
+	frog.HungerLevel = 50
+	frog.LastFeedTime = time.Now().Add(-10*model.HungerDecayInterval - model.HungerDecayInterval/2)
+	repos.Frogs.Save(frog)
+	if got := frog.CurrentHunger(time.Now()); got != 40 {
+		t.Fatalf("current hunger = %d, want 40", got)
+	}
+
+	if res := s.Feed(user, 5); res.Code != 0 {
+		t.Fatalf("feed: %+v", res)
+	}
+	if fed, _ := repos.Frogs.Get(frog.ID); fed.HungerLevel != 45 {
+		t.Fatalf("hunger after feed = %d, want 45", fed.HungerLevel)
+	}
+
+	// 已超过饿死时间但饿死任务尚未执行
+	frog, _ = repos.Frogs.Get(frog.ID)
+	frog.LastFeedTime = time.Now().Add(-time.Duration(frog.HungerLevel+1) * model.HungerDecayInterval)
+	repos.Frogs.Save(frog)
+	if res := s.Feed(user, 5); res.Code == 0 {
+		t.Fatal("expected feeding a starved frog to fail")
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"singo/model"
+	"singo/serializer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntegrationPoolsService 服务端集成查询奖池的服务
+type IntegrationPoolsService struct {
+	Status string `form:"status" json:"status" binding:"omitempty,oneof=collecting active settling completed cancelled disputed"`
+	Limit  int    `form:"limit" json:"limit"`
+}
+
+// IntegrationLeaderboardService 服务端集成查询排行榜的服务
+type IntegrationLeaderboardService struct {
+	Limit int `form:"limit" json:"limit"`
+}
+
+// integrationLimit 规范化分页数量
+func integrationLimit(limit int) int {
+	if limit <= 0 || limit > 100 {
+		return 20
+	}
+	return limit
+}
+
+// List 列出奖池
+func (service *IntegrationPoolsService) List() serializer.Response {
+	pools, err := GetPoolService().ListPools(model.PoolStatus(service.Status), integrationLimit(service.Limit))
+	if err != nil {
+		return serializer.DBErr("Failed to list pools", err)
+	}
+
+	data := make([]gin.H, 0, len(pools))
+	for _, pool := range pools {
+		item := gin.H{
+			"id":             pool.ID,
+			"status":         pool.Status,
+			"currentPlayers": pool.CurrentPlayers,
+			"prizeAmount":    pool.PrizeAmount,
+			"winnerAddress":  pool.BigPrizeWinner,
+			"createdAt":      pool.CreatedAt.Unix(),
+		}
+		if pool.CompletedAt != nil {
+			item["completedAt"] = pool.CompletedAt.Unix()
+		}
+		data = append(data, item)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"pools": data,
+		},
+	}
+}
+
+// List 按历史总收益列出排行榜
+func (service *IntegrationLeaderboardService) List() serializer.Response {
+	users, err := GetPoolService().Leaderboard(integrationLimit(service.Limit))
+	if err != nil {
+		return serializer.DBErr("Failed to get leaderboard", err)
+	}
+
+	data := make([]gin.H, 0, len(users))
+	for i, user := range users {
+		data = append(data, gin.H{
+			"rank":           i + 1,
+			"walletAddress":  user.WalletAddress,
+			"historyRewards": user.HistoryRewards,
+		})
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"leaderboard": data,
+		},
+	}
+}
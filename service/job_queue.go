@@ -0,0 +1,292 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"singo/metrics"
+	"singo/model"
+	"singo/repository"
+	"singo/serializer"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultJobInterval 默认的任务轮询间隔
+	defaultJobInterval = time.Second
+	// defaultJobMaxAttempts 默认的最大执行次数
+	defaultJobMaxAttempts = 5
+	// jobBatchSize 每次认领的任务数量
+	jobBatchSize = 50
+	// jobLease 认领的租约，执行器崩溃时租约到期后由其他执行器重新执行
+	jobLease = time.Minute
+	// jobRetryBase 首次重试的等待时间，之后每次翻倍
+	jobRetryBase = 5 * time.Second
+	// jobRetryMax 重试等待时间的上限
+	jobRetryMax = 10 * time.Minute
+)
+
+// jobHandler 任务处理器，返回错误时按退避时间重试
+type jobHandler func(job *model.Job) error
+
+// rescheduleError 任务尚未到执行条件，推迟到指定时间且不计入执行次数
+type rescheduleError struct {
+	at time.Time
+}
+
+func (e *rescheduleError) Error() string {
+	return fmt.Sprintf("job rescheduled to %s", e.at.Format(time.RFC3339))
+}
+
+// RescheduleJob 在处理器中返回，将任务推迟到at再次执行
+func RescheduleJob(at time.Time) error {
+	return &rescheduleError{at: at}
+}
+
+// JobQueue 持久化的延迟任务队列
+//
+// 任务与产生它的状态变更可以在同一事务中写入，重启后不会丢失。执行至少一次：
+// 处理器须是幂等的，失败时按指数退避重试，次数用尽后进入死信列表等待人工处理。
+type JobQueue struct {
+	jobs     repository.JobRepository
+	interval time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]jobHandler
+}
+
+// NewJobQueue 创建任务队列，轮询间隔由 JOB_QUEUE_INTERVAL 配置
+func NewJobQueue(repos *repository.Repositories) *JobQueue {
+	interval := defaultJobInterval
+	if value := os.Getenv("JOB_QUEUE_INTERVAL"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("JOB_QUEUE_INTERVAL 配置无效: %s，使用默认值 %v", value, interval)
+		}
+	}
+
+	return &JobQueue{
+		jobs:     repos.Jobs,
+		interval: interval,
+		handlers: make(map[string]jobHandler),
+	}
+}
+
+// GetJobQueue 获取任务队列实例
+func GetJobQueue() *JobQueue {
+	return jobQueue
+}
+
+// HandleJob 注册T类型参数的任务处理器，同一类型只保留最后注册的处理器
+func HandleJob[T any](q *JobQueue, jobType string, handler func(job *model.Job, payload T) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = func(job *model.Job) error {
+		var payload T
+		if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+			return err
+		}
+		return handler(job, payload)
+	}
+}
+
+// scheduleJob 在给定的数据访问实现（可以是事务）中写入任务，已有相同去重键的未结束任务时忽略
+func scheduleJob(jobs repository.JobRepository, jobType string, payload interface{}, runAt time.Time, maxAttempts int, dedupKey string) error {
+	job, err := model.NewJob(jobType, payload, runAt, maxAttempts, dedupKey)
+	if err != nil {
+		return err
+	}
+	_, err = jobs.Schedule(&job)
+	return err
+}
+
+// Start 启动执行器，按轮询间隔执行到期的任务
+func (q *JobQueue) Start() {
+	ticker := time.NewTicker(q.interval)
+	go func() {
+		for range ticker.C {
+			if _, err := q.RunDue(); err != nil {
+				log.Printf("执行延迟任务失败: %v", err)
+			}
+		}
+	}()
+}
+
+// RunDue 认领并依次执行到期的任务，返回本次执行的数量
+//
+// 认领到整批任务时继续认领下一批；推迟到当前时间的任务留到下一轮，不会在本次重复执行。
+func (q *JobQueue) RunDue() (int, error) {
+	ran := 0
+	for {
+		jobs, err := q.jobs.Claim(jobBatchSize, jobLease)
+		if err != nil {
+			return ran, err
+		}
+
+		for i := range jobs {
+			if err := q.run(&jobs[i]); err != nil {
+				return ran, err
+			}
+			ran++
+		}
+		if len(jobs) < jobBatchSize {
+			return ran, nil
+		}
+	}
+}
+
+// run 执行一个任务并记录结果，只在记录结果失败时返回错误
+//
+// 执行时间超过租约时任务可能已被其他执行器重新认领，此时不覆盖对方的结果。
+func (q *JobQueue) run(job *model.Job) error {
+	err := q.record(job, q.invoke(job))
+	if errors.Is(err, repository.ErrLeaseLost) {
+		metrics.JobsLeaseLost.Add(job.Type, 1)
+		log.Printf("任务 %d (%s) 的租约已失效，执行结果不再记录", job.ID, job.Type)
+		return nil
+	}
+	return err
+}
+
+// record 按处理器返回的错误记录任务结果
+func (q *JobQueue) record(job *model.Job, err error) error {
+	var lockedUntil time.Time
+	if job.LockedUntil != nil {
+		lockedUntil = *job.LockedUntil
+	}
+
+	if err == nil {
+		metrics.JobsSucceeded.Add(job.Type, 1)
+		return q.jobs.Finish(job.ID, lockedUntil)
+	}
+
+	var reschedule *rescheduleError
+	if errors.As(err, &reschedule) {
+		return q.jobs.Release(job.ID, lockedUntil, reschedule.at, job.Attempts, job.LastError)
+	}
+
+	attempts := job.Attempts + 1
+	if attempts >= job.MaxAttempts {
+		metrics.JobsDead.Add(job.Type, 1)
+		log.Printf("任务 %d (%s) 执行 %d 次后仍失败，移入死信列表: %v", job.ID, job.Type, attempts, err)
+		return q.jobs.Bury(job.ID, lockedUntil, attempts, err.Error())
+	}
+
+	metrics.JobsRetried.Add(job.Type, 1)
+	log.Printf("任务 %d (%s) 第 %d 次执行失败: %v", job.ID, job.Type, attempts, err)
+	return q.jobs.Release(job.ID, lockedUntil, time.Now().Add(retryDelay(attempts)), attempts, err.Error())
+}
+
+// invoke 调用处理器，恢复处理器中的panic
+func (q *JobQueue) invoke(job *model.Job) (err error) {
+	q.mu.RLock()
+	handler, ok := q.handlers[job.Type]
+	q.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no handler for job type %q", job.Type)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("任务 %d (%s) 的处理器发生panic: %v\n%s", job.ID, job.Type, r, debug.Stack())
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return handler(job)
+}
+
+// retryDelay 第attempts次失败后的等待时间
+func retryDelay(attempts int) time.Duration {
+	delay := jobRetryBase
+	for i := 1; i < attempts && delay < jobRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > jobRetryMax {
+		delay = jobRetryMax
+	}
+	return delay
+}
+
+// JobListService 管理员查看任务队列的服务
+type JobListService struct {
+	Status string `form:"status" json:"status" binding:"omitempty,oneof=pending running done dead"`
+	Limit  int    `form:"limit" json:"limit"`
+}
+
+// List 返回各状态的任务数量与指定状态的任务，默认列出死信
+func (service *JobListService) List() serializer.Response {
+	status := model.JobStatus(service.Status)
+	if status == "" {
+		status = model.JobDead
+	}
+	limit := service.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	q := GetJobQueue()
+	counts, err := q.jobs.CountByStatus()
+	if err != nil {
+		return serializer.DBErr("Failed to count jobs", err)
+	}
+	jobs, err := q.jobs.List(status, limit)
+	if err != nil {
+		return serializer.DBErr("Failed to list jobs", err)
+	}
+
+	data := []gin.H{}
+	for _, job := range jobs {
+		data = append(data, gin.H{
+			"id":          job.ID,
+			"type":        job.Type,
+			"payload":     json.RawMessage(job.Payload),
+			"status":      job.Status,
+			"runAt":       job.RunAt.Unix(),
+			"attempts":    job.Attempts,
+			"maxAttempts": job.MaxAttempts,
+			"lastError":   job.LastError,
+			"createdAt":   job.CreatedAt.Unix(),
+		})
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"counts": counts,
+			"status": status,
+			"jobs":   data,
+		},
+	}
+}
+
+// RequeueJob 将死信任务重新排队
+func RequeueJob(actor *model.User, jobID string) serializer.Response {
+	id, err := strconv.ParseUint(jobID, 10, 64)
+	if err != nil {
+		return serializer.ParamErr("Invalid job id", err)
+	}
+
+	if err := GetJobQueue().jobs.Requeue(uint(id)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return serializer.ParamErr("Dead job not found", err)
+		}
+		return serializer.DBErr("Failed to requeue job", err)
+	}
+
+	log.Printf("用户 %d 将死信任务 %d 重新排队", actor.ID, id)
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"id":     id,
+			"status": model.JobPending,
+		},
+	}
+}
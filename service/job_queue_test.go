@@ -0,0 +1,433 @@
+package service
+
+import (
+	"errors"
+	"singo/model"
+	"singo/repository"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testJob struct {
+	N int `json:"n"`
+}
+
+// fastForward 将等待重试的任务改为立即到期，模拟退避时间已过
+func fastForward(t *testing.T, repos *repository.Repositories, id uint) {
+	t.Helper()
+	if !repository.MoveJob(repos, id, time.Now()) {
+		t.Fatalf("job %d is not pending", id)
+	}
+}
+
+// 失败的任务按指数退避重试，次数用尽后进入死信列表，重新排队后可再次执行
+func TestJobQueueRetriesIntoDeadLetter(t *testing.T) {
+	repos := repository.NewMemory()
+	q := NewJobQueue(repos)
+
+	fail := true
+	var calls int
+	HandleJob(q, "test.flaky", func(job *model.Job, payload testJob) error {
+		calls++
+		if payload.N != 7 {
+			t.Errorf("payload = %+v", payload)
+		}
+		if fail {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	})
+	if err := scheduleJob(repos.Jobs, "test.flaky", testJob{N: 7}, time.Now(), 3, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, _ := repos.Jobs.List(model.JobPending, 10)
+	id := pending[0].ID
+	for attempt := 1; attempt <= 3; attempt++ {
+		if ran, err := q.RunDue(); err != nil || ran != 1 {
+			t.Fatalf("attempt %d: ran %d (%v)", attempt, ran, err)
+		}
+		if attempt == 3 {
+			break
+		}
+
+		pending, _ := repos.Jobs.List(model.JobPending, 10)
+		if len(pending) != 1 || pending[0].Attempts != attempt || pending[0].LastError != "downstream unavailable" {
+			t.Fatalf("after attempt %d: %+v", attempt, pending)
+		}
+		wait := time.Until(pending[0].RunAt)
+		if want := retryDelay(attempt); wait > want || wait < want-time.Second {
+			t.Fatalf("retry %d waits %v, want %v", attempt, wait, want)
+		}
+
+		// 退避时间未到时不会执行
+		if ran, _ := q.RunDue(); ran != 0 {
+			t.Fatalf("job ran before its retry time")
+		}
+		fastForward(t, repos, id)
+	}
+
+	dead, _ := repos.Jobs.List(model.JobDead, 10)
+	if len(dead) != 1 || dead[0].Attempts != 3 || calls != 3 {
+		t.Fatalf("dead = %+v, calls = %d", dead, calls)
+	}
+
+	// 只有死信可以重新排队
+	if err := repos.Jobs.Requeue(id + 100); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("requeue missing job: %v", err)
+	}
+	fail = false
+	if err := repos.Jobs.Requeue(id); err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	if ran, err := q.RunDue(); err != nil || ran != 1 {
+		t.Fatalf("requeued job ran %d (%v)", ran, err)
+	}
+	counts, _ := repos.Jobs.CountByStatus()
+	if counts[model.JobDone] != 1 || counts[model.JobDead] != 0 {
+		t.Fatalf("counts = %v", counts)
+	}
+}
+
+// 推迟执行不计入重试次数，处理器panic按失败处理，去重键在任务结束后释放
+func TestJobQueueRescheduleAndDedup(t *testing.T) {
+	repos := repository.NewMemory()
+	q := NewJobQueue(repos)
+
+	ready := false
+	HandleJob(q, "test.wait", func(job *model.Job, payload testJob) error {
+		if !ready {
+			return RescheduleJob(time.Now())
+		}
+		return nil
+	})
+	HandleJob(q, "test.panic", func(job *model.Job, payload testJob) error {
+		panic("boom")
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := scheduleJob(repos.Jobs, "test.wait", testJob{N: i}, time.Now(), 1, "wait"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scheduleJob(repos.Jobs, "test.panic", testJob{}, time.Now().Add(time.Hour), 1, "")
+
+	if ran, _ := q.RunDue(); ran != 1 {
+		t.Fatalf("duplicate job was scheduled: ran %d", ran)
+	}
+	pending, _ := repos.Jobs.List(model.JobPending, 10)
+	for _, job := range pending {
+		if job.Type == "test.wait" && job.Attempts != 0 {
+			t.Fatalf("reschedule counted as an attempt: %+v", job)
+		}
+	}
+
+	ready = true
+	if ran, _ := q.RunDue(); ran != 1 {
+		t.Fatalf("rescheduled job did not run: %d", ran)
+	}
+	if err := scheduleJob(repos.Jobs, "test.wait", testJob{}, time.Now().Add(time.Hour), 1, "wait"); err != nil {
+		t.Fatal(err)
+	}
+	if counts, _ := repos.Jobs.CountByStatus(); counts[model.JobPending] != 2 {
+		t.Fatalf("dedup key was not released: %v", counts)
+	}
+
+	for _, job := range pending {
+		if job.Type == "test.panic" {
+			fastForward(t, repos, job.ID)
+		}
+	}
+	if _, err := q.RunDue(); err != nil {
+		t.Fatal(err)
+	}
+	dead, _ := repos.Jobs.List(model.JobDead, 10)
+	if len(dead) != 1 || dead[0].Type != "test.panic" {
+		t.Fatalf("panicking job not buried: %+v", dead)
+	}
+}
+
+// 执行超过租约、任务已被其他执行器重新认领时，不覆盖对方的结果
+func TestJobQueueLostLease(t *testing.T) {
+	repos := repository.NewMemory()
+	q := NewJobQueue(repos)
+	HandleJob(q, "test.slow", func(job *model.Job, payload testJob) error {
+		return errors.New("too late")
+	})
+	if err := scheduleJob(repos.Jobs, "test.slow", testJob{}, time.Now(), 3, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一个执行器的租约在执行期间过期，第二个执行器重新认领
+	stale, err := repos.Jobs.Claim(1, -time.Millisecond)
+	if err != nil || len(stale) != 1 {
+		t.Fatalf("first claim: %+v (%v)", stale, err)
+	}
+	current, err := repos.Jobs.Claim(1, time.Minute)
+	if err != nil || len(current) != 1 {
+		t.Fatalf("reclaim after lease expiry: %+v (%v)", current, err)
+	}
+
+	if err := q.run(&stale[0]); err != nil {
+		t.Fatalf("lost lease reported as an error: %v", err)
+	}
+	running, _ := repos.Jobs.List(model.JobRunning, 10)
+	if len(running) != 1 || running[0].Attempts != 0 || !running[0].LockedUntil.Equal(*current[0].LockedUntil) {
+		t.Fatalf("stale result overwrote the current lease: %+v", running)
+	}
+
+	if err := repos.Jobs.Finish(current[0].ID, *current[0].LockedUntil); err != nil {
+		t.Fatal(err)
+	}
+	if counts, _ := repos.Jobs.CountByStatus(); counts[model.JobDone] != 1 {
+		t.Fatalf("counts = %v", counts)
+	}
+}
+
+// 奖池等待超时仍在收集中时取消，停用青蛙并退还激活费；已结束的奖池不受影响
+func TestLobbyTimeoutCancelsCollectingPool(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+
+	user := &model.User{WalletAddress: "lobby-timeout-wallet"}
+	repos.Users.Create(user)
+	if res := s.Activate(user, "lobby-timeout-tx", ""); res.Code != 0 {
+		t.Fatalf("activate: %+v", res)
+	}
+	frog, err := repos.Frogs.GetActiveByUser(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	participant, err := repos.Participants.GetLatestByFrog(frog.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poolID := participant.PoolID
+
+	// 加入时已安排饿死时间，新奖池已安排等待超时
+	pending, _ := repos.Jobs.List(model.JobPending, 200)
+	scheduled := map[string]bool{}
+	for _, job := range pending {
+		scheduled[job.Type+":"+job.Payload] = true
+	}
+	if !scheduled[JobStarvation+`:{"frogId":`+strconv.FormatUint(uint64(frog.ID), 10)+`}`] {
+		t.Fatalf("starvation job not scheduled: %+v", pending)
+	}
+
+	if participant.SerialNumber == 1 && !scheduled[JobLobbyTimeout+`:{"poolId":`+strconv.FormatUint(uint64(poolID), 10)+`}`] {
+		t.Fatalf("lobby timeout not scheduled: %+v", pending)
+	}
+
+	if err := GetGameJobs().lobbyTimedOut(&model.Job{}, poolJob{PoolID: poolID}); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, _ := repos.Pools.Get(poolID)
+	if pool.Status != model.PoolStatusCancelled {
+		t.Fatalf("pool status = %s", pool.Status)
+	}
+	if frog, _ := repos.Frogs.Get(frog.ID); frog.IsActive {
+		t.Fatal("frog still active after lobby timeout")
+	}
+	if balance, _ := repos.Rewards.Balance(model.AccountPoolPrize, poolID); balance != 0 {
+		t.Fatalf("pool prize balance = %d", balance)
+	}
+	if balance, _ := repos.Rewards.Balance(model.AccountUserRefund, user.ID); balance != model.ToLamports(RequiredAmount) {
+		t.Fatalf("refund balance = %d", balance)
+	}
+	// 已结束的奖池再次超时不做任何处理
+	if err := GetGameJobs().lobbyTimedOut(&model.Job{}, poolJob{PoolID: poolID}); err != nil {
+		t.Fatal(err)
+	}
+	if balance, _ := repos.Rewards.Balance(model.AccountUserRefund, user.ID); balance != model.ToLamports(RequiredAmount) {
+		t.Fatalf("refunded twice: %d", balance)
+	}
+}
+
+// 饿死时间未到时推迟到饿死时间，到期后停用青蛙
+func TestStarvationJobFollowsFeeding(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+
+	user := &model.User{WalletAddress: "starvation-wallet"}
+	repos.Users.Create(user)
+	if res := s.Activate(user, "starvation-tx", ""); res.Code != 0 {
+		t.Fatalf("activate: %+v", res)
+	}
+	frog, _ := repos.Frogs.GetActiveByUser(user.ID)
+	jobs := GetGameJobs()
+
+	var reschedule *rescheduleError
+	err := jobs.starvationDue(&model.Job{}, frogJob{FrogID: frog.ID})
+	if !errors.As(err, &reschedule) || !reschedule.at.Equal(frog.StarvesAt()) {
+		t.Fatalf("fresh frog: %v", err)
+	}
+
+	// 饥饿值只剩1且已超过饿死时间
+	frog.HungerLevel = 1
+	frog.LastFeedTime = time.Now().Add(-2 * model.HungerDecayInterval)
+	repos.Frogs.Save(frog)
+	if err := jobs.starvationDue(&model.Job{}, frogJob{FrogID: frog.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if frog, _ := repos.Frogs.Get(frog.ID); frog.IsActive || frog.HungerLevel != 0 {
+		t.Fatalf("frog not starved: %+v", frog)
+	}
+
+	// 青蛙已停用时直接完成
+	if err := jobs.starvationDue(&model.Job{}, frogJob{FrogID: frog.ID}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 收集中奖池的青蛙全部饿死时与等待超时相同，取消奖池并退还激活费
+func TestStarvationCancelsCollectingPoolWithRefund(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+	jobs := GetGameJobs()
+
+	// 先结束其他测试留下的收集中奖池，保证玩家加入新的奖池
+	collecting, _ := repos.Pools.ListByStatus(model.PoolStatusCollecting)
+	for _, pool := range collecting {
+		if err := jobs.lobbyTimedOut(&model.Job{}, poolJob{PoolID: pool.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user := &model.User{WalletAddress: "starved-lobby-wallet"}
+	repos.Users.Create(user)
+	if res := s.Activate(user, "starved-lobby-tx", ""); res.Code != 0 {
+		t.Fatalf("activate: %+v", res)
+	}
+	frog, _ := repos.Frogs.GetActiveByUser(user.ID)
+	participant, _ := repos.Participants.GetLatestByFrog(frog.ID)
+
+	frog.HungerLevel = 1
+	frog.LastFeedTime = time.Now().Add(-2 * model.HungerDecayInterval)
+	repos.Frogs.Save(frog)
+	if err := jobs.starvationDue(&model.Job{}, frogJob{FrogID: frog.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, _ := repos.Pools.Get(participant.PoolID)
+	if pool.Status != model.PoolStatusCancelled {
+		t.Fatalf("pool status = %s", pool.Status)
+	}
+	if balance, _ := repos.Rewards.Balance(model.AccountPoolPrize, pool.ID); balance != 0 {
+		t.Fatalf("pool prize balance = %d", balance)
+	}
+	if balance, _ := repos.Rewards.Balance(model.AccountUserRefund, user.ID); balance != model.ToLamports(RequiredAmount) {
+		t.Fatalf("refund balance = %d", balance)
+	}
+}
+
+// 结算以链上交易签名记账：首次结算已提交时重试不再记账，重复重试也只结算一次
+func TestPayoutRetryIsIdempotent(t *testing.T) {
+	repos := testRepos
+	user := &model.User{WalletAddress: "payout-retry-wallet"}
+	repos.Users.Create(user)
+
+	pool := model.NewPool()
+	pool.CollectingSlot = nil
+	pool.Status = model.PoolStatusActive
+	pool.PrizeAmount = 0.5
+	repos.Pools.Create(&pool)
+	if err := GetRewardService().RecordActivation(pool.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetRewardService().AwardPrize(user, &pool); err != nil {
+		t.Fatal(err)
+	}
+
+	// 首次结算已提交，但提交方没有收到结果并安排了重试
+	if err := GetRewardService().Settle(user, 0.25, "payout-retry-tx"); err != nil {
+		t.Fatal(err)
+	}
+	job := &model.Job{ID: 424242}
+	payload := payoutJob{UserID: user.ID, Amount: 0.25, TransactionHash: "payout-retry-tx"}
+	if err := GetGameJobs().retryPayout(job, payload); err != nil {
+		t.Fatal(err)
+	}
+	if balance, _ := repos.Rewards.Balance(model.AccountUserPaid, user.ID); balance != model.ToLamports(0.25) {
+		t.Fatalf("paid balance after committed settle = %d", balance)
+	}
+
+	// 首次结算未提交时由重试结算，重复执行只结算一次
+	payload.TransactionHash = "payout-retry-tx-2"
+	for i := 0; i < 2; i++ {
+		if err := GetGameJobs().retryPayout(job, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if balance, _ := repos.Rewards.Balance(model.AccountUserPaid, user.ID); balance != model.ToLamports(0.5) {
+		t.Fatalf("paid balance = %d", balance)
+	}
+	if balance, _ := repos.Rewards.Balance(model.AccountUserUnclaimed, user.ID); balance != 0 {
+		t.Fatalf("unclaimed balance = %d", balance)
+	}
+}
+
+// 大奖移动任务在一轮中不重复选择同一只青蛙，奖池没有活跃青蛙时结束并停止移动
+func TestPrizeMoveJobCyclesHolders(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+
+	var users []*model.User
+	for i := 0; i < model.MaxPoolPlayers; i++ {
+		user := &model.User{WalletAddress: "prize-move-wallet-" + strconv.Itoa(i)}
+		repos.Users.Create(user)
+		if res := s.Activate(user, "prize-move-tx-"+strconv.Itoa(i), ""); res.Code != 0 {
+			t.Fatalf("activate %d: %+v", i, res)
+		}
+		users = append(users, user)
+	}
+	pool, err := s.ws.currentPool(users[0].ID)
+	if err != nil || pool == nil || pool.Status != model.PoolStatusActive {
+		t.Fatalf("pool not active: %+v (%v)", pool, err)
+	}
+
+	// 奖池变为活跃时已安排大奖移动
+	pending, _ := repos.Jobs.List(model.JobPending, 500)
+	var scheduled bool
+	for _, job := range pending {
+		scheduled = scheduled || job.Type+":"+job.Payload == JobPrizeMove+`:{"poolId":`+strconv.FormatUint(uint64(pool.ID), 10)+`}`
+	}
+	if !scheduled {
+		t.Fatalf("prize move not scheduled: %+v", pending)
+	}
+
+	participants, _ := repos.Participants.ListByPool(pool.ID)
+	updater := GetPrizeUpdaterService()
+	holders := map[string]bool{}
+	for range participants {
+		var reschedule *rescheduleError
+		if err := updater.prizeMoveDue(&model.Job{}, poolJob{PoolID: pool.ID}); !errors.As(err, &reschedule) {
+			t.Fatalf("prize move: %v", err)
+		}
+		moved, _ := repos.Pools.Get(pool.ID)
+		if holders[moved.CurrentBigPrizeHolder] {
+			t.Fatalf("holder %s repeated within a round", moved.CurrentBigPrizeHolder)
+		}
+		holders[moved.CurrentBigPrizeHolder] = true
+	}
+
+	// 所有青蛙都出现过后开始新的一轮
+	var reschedule *rescheduleError
+	if err := updater.prizeMoveDue(&model.Job{}, poolJob{PoolID: pool.ID}); !errors.As(err, &reschedule) {
+		t.Fatalf("new round: %v", err)
+	}
+
+	for _, p := range participants {
+		frog, _ := repos.Frogs.Get(p.FrogID)
+		frog.IsActive = false
+		repos.Frogs.Save(frog)
+	}
+	if err := updater.prizeMoveDue(&model.Job{}, poolJob{PoolID: pool.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if completed, _ := repos.Pools.Get(pool.ID); completed.Status != model.PoolStatusCompleted {
+		t.Fatalf("pool status = %s", completed.Status)
+	}
+	// 奖池结束后任务直接完成
+	if err := updater.prizeMoveDue(&model.Job{}, poolJob{PoolID: pool.ID}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"log"
+	"singo/serializer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mr-tron/base58"
+)
+
+// MemoLoginService 通过签名的备忘录交易登录，供无法签名任意消息的硬件钱包使用
+//
+// 客户端构造一笔只包含Memo指令的交易，备忘录内容为 auth/nonce 返回的SIWS消息，
+// 由钱包签名但不广播，将序列化后的交易以base64提交。
+type MemoLoginService struct {
+	WalletAddress string `form:"walletAddress" json:"walletAddress" binding:"required"`
+	Transaction   string `form:"transaction" json:"transaction" binding:"required"`
+}
+
+// Login 校验备忘录交易并签发与普通登录相同的令牌
+func (service *MemoLoginService) Login(c *gin.Context) serializer.Response {
+	raw, err := base64.StdEncoding.DecodeString(service.Transaction)
+	if err != nil {
+		return serializer.ParamErr("Invalid transaction encoding", err)
+	}
+
+	tx, err := DecodeTransaction(raw)
+	if err != nil {
+		return serializer.ParamErr("Invalid transaction", err)
+	}
+
+	// 手续费支付者必须是登录的钱包
+	if tx.FeePayer() != service.WalletAddress {
+		return serializer.ParamErr("Fee payer does not match wallet", nil)
+	}
+
+	// 只允许一条Memo指令，确保这笔交易即使被广播也不会转移资产；钱包自动添加的计算预算指令同样拒绝
+	memo := ""
+	for _, ix := range tx.Instructions {
+		switch ix.ProgramID {
+		case MemoProgramID, MemoV1ProgramID:
+			if memo != "" {
+				return serializer.ParamErr("Transaction must contain exactly one memo", nil)
+			}
+			memo = string(ix.Data)
+		default:
+			return serializer.ParamErr("Transaction contains non-memo instructions", nil)
+		}
+	}
+	if memo == "" {
+		return serializer.ParamErr("Transaction has no memo", nil)
+	}
+
+	return loginWithSIWS(c, service.WalletAddress, memo, func() bool {
+		return service.verifyFeePayerSignature(tx)
+	})
+}
+
+// verifyFeePayerSignature 验证手续费支付者对交易消息的签名
+func (service *MemoLoginService) verifyFeePayerSignature(tx *DecodedTransaction) bool {
+	pubKey, err := base58.Decode(service.WalletAddress)
+	if err != nil || len(pubKey) != ed25519.PublicKeySize {
+		log.Printf("Failed to decode wallet address %s: %v", service.WalletAddress, err)
+		return false
+	}
+	if len(tx.Signatures) == 0 {
+		return false
+	}
+	return ed25519.Verify(pubKey, tx.Message, tx.Signatures[0])
+}
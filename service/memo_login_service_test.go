@@ -0,0 +1,82 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mr-tron/base58"
+)
+
+// testSystemProgramID 系统程序，转账指令由其执行
+const testSystemProgramID = "11111111111111111111111111111111"
+
+// memoLoginMessage 为钱包构造当前配置下有效的SIWS消息
+func memoLoginMessage(t *testing.T, wallet string) SIWSMessage {
+	t.Setenv("SIWS_DOMAIN", siwsTestConfig.Domain)
+	t.Setenv("SIWS_URI", siwsTestConfig.URI)
+	t.Setenv("SIWS_CHAIN_ID", siwsTestConfig.ChainID)
+	msg := newSIWSMessage(time.Now())
+	msg.Address = wallet
+	return msg
+}
+
+// 交易的签名者、指令或备忘录内容不符合要求时拒绝登录，且不会消费nonce
+func TestMemoLoginRejects(t *testing.T) {
+	signer, other := testKey(1), testKey(2)
+	wallet := base58.Encode(signer.Public().(ed25519.PublicKey))
+	message := memoLoginMessage(t, wallet)
+	valid := message.String()
+
+	foreign := message
+	foreign.Address = base58.Encode(other.Public().(ed25519.PublicKey))
+	wrongDomain := message
+	wrongDomain.Domain = "evil.example"
+
+	extraInstruction := memoTransaction(false, signer, valid)
+	extraInstruction.programs = append(extraInstruction.programs, testSystemProgramID)
+	extraInstruction.instructions = append(extraInstruction.instructions,
+		testInstruction{program: 2, accounts: []byte{0, 0}, data: []byte{2, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0}})
+	twoMemos := memoTransaction(false, signer, valid)
+	twoMemos.instructions = append(twoMemos.instructions, twoMemos.instructions[0])
+	noMemo := testTransaction{signers: []ed25519.PrivateKey{signer}}
+	// 钱包自动添加的计算预算指令同样拒绝，登录交易只能包含备忘录
+	computeBudget := memoTransaction(false, signer, valid)
+	computeBudget.programs = append(computeBudget.programs, ComputeBudgetProgramID)
+	computeBudget.instructions = append(computeBudget.instructions,
+		testInstruction{program: 2, data: []byte{2, 0x40, 0x0d, 0x03, 0x00}})
+	lookup := memoTransaction(true, signer, valid)
+	lookup.lookups = 1
+
+	// 手续费支付者是钱包本身，但签名来自其他密钥
+	forged := memoTransaction(false, signer, valid).serialize(t)
+	copy(forged[1:65], ed25519.Sign(other, memoTransaction(false, signer, valid).message(t)))
+
+	encode := func(tx testTransaction) string { return base64.StdEncoding.EncodeToString(tx.serialize(t)) }
+	cases := map[string]struct {
+		transaction string
+		want        string
+	}{
+		"not base64":        {"%%%", "Invalid transaction encoding"},
+		"not a transaction": {base64.StdEncoding.EncodeToString([]byte("hello")), "Invalid transaction"},
+		"lookup table":      {encode(lookup), "Invalid transaction"},
+		"wrong signer":      {encode(memoTransaction(false, other, valid)), "Fee payer does not match wallet"},
+		"forged signature":  {base64.StdEncoding.EncodeToString(forged), "Invalid signature"},
+		"extra instruction": {encode(extraInstruction), "non-memo instructions"},
+		"compute budget":    {encode(computeBudget), "non-memo instructions"},
+		"two memos":         {encode(twoMemos), "exactly one memo"},
+		"no memo":           {encode(noMemo), "no memo"},
+		"memo for other":    {encode(memoTransaction(false, signer, foreign.String())), "address does not match wallet"},
+		"memo wrong domain": {encode(memoTransaction(false, signer, wrongDomain.String())), "domain mismatch"},
+		"memo not siws":     {encode(memoTransaction(false, signer, "let me in")), "Invalid sign-in message"},
+	}
+	for name, c := range cases {
+		service := MemoLoginService{WalletAddress: wallet, Transaction: c.transaction}
+		res := service.Login(nil)
+		if res.Code == 0 || !strings.Contains(res.Msg, c.want) {
+			t.Errorf("%s: %+v", name, res)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"log"
+	"os"
+	"singo/event"
+	"singo/metrics"
+	"singo/model"
+	"singo/repository"
+	"time"
+)
+
+const (
+	// defaultRelayInterval 默认的发件箱轮询间隔
+	defaultRelayInterval = time.Second
+	// relayBatchSize 每次认领的消息数量
+	relayBatchSize = 100
+	// relayLease 认领的租约，投递器崩溃时租约到期后由其他投递器重新投递
+	relayLease = 30 * time.Second
+)
+
+// OutboxRelay 将发件箱中已提交的事件发布到事件总线
+//
+// 投递至少一次：先发布再标记已投递，标记失败时会再次发布，事件总线按发件箱的事件ID去重。
+type OutboxRelay struct {
+	outbox   repository.OutboxRepository
+	interval time.Duration
+	notify   chan struct{}
+}
+
+// NewOutboxRelay 创建发件箱投递器，轮询间隔由 OUTBOX_RELAY_INTERVAL 配置
+func NewOutboxRelay(repos *repository.Repositories) *OutboxRelay {
+	interval := defaultRelayInterval
+	if value := os.Getenv("OUTBOX_RELAY_INTERVAL"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("OUTBOX_RELAY_INTERVAL 配置无效: %s，使用默认值 %v", value, interval)
+		}
+	}
+
+	return &OutboxRelay{
+		outbox:   repos.Outbox,
+		interval: interval,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// GetOutboxRelay 获取发件箱投递器实例
+func GetOutboxRelay() *OutboxRelay {
+	return outboxRelay
+}
+
+// Notify 事务提交后通知投递器立即投递，不阻塞
+func (r *OutboxRelay) Notify() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Start 启动投递器：收到通知或到达轮询间隔时投递未发布的事件
+func (r *OutboxRelay) Start() {
+	ticker := time.NewTicker(r.interval)
+	go func() {
+		for {
+			if _, err := r.Dispatch(); err != nil {
+				log.Printf("投递发件箱事件失败: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-r.notify:
+			}
+		}
+	}()
+}
+
+// Dispatch 投递全部未发布的事件，返回本次投递的数量
+func (r *OutboxRelay) Dispatch() (int, error) {
+	dispatched := 0
+	defer func() {
+		if pending, err := r.outbox.CountPending(); err == nil {
+			metrics.OutboxPending.Set(pending)
+		}
+	}()
+
+	for {
+		messages, err := r.outbox.Claim(relayBatchSize, relayLease)
+		if err != nil {
+			return dispatched, err
+		}
+		if len(messages) == 0 {
+			return dispatched, nil
+		}
+
+		for _, message := range messages {
+			e, err := event.Decode(message.Type, []byte(message.Payload))
+			if err != nil {
+				log.Printf("发件箱事件 %s 解码失败: %v", message.EventID, err)
+				metrics.OutboxFailures.Add(1)
+				if err := r.outbox.MarkFailed(message.ID, err.Error()); err != nil {
+					return dispatched, err
+				}
+				continue
+			}
+			event.PublishOnce(message.EventID, e)
+			if err := r.outbox.MarkDispatched(message.ID); err != nil {
+				return dispatched, err
+			}
+			metrics.OutboxDispatched.Add(1)
+			dispatched++
+		}
+	}
+}
+
+// withEvents 在同一事务中执行状态变更并写入其产生的事件，提交后通知投递器
+// 属于奖池的事件同时追加到奖池事件存储，用于重放奖池的时间线
+func withEvents(repos *repository.Repositories, relay *OutboxRelay, fn func(tx *repository.Repositories) ([]event.Event, error)) error {
+	err := repos.Transaction(func(tx *repository.Repositories) error {
+		events, err := fn(tx)
+		if err != nil {
+			return err
+		}
+
+		messages := make([]model.OutboxMessage, 0, len(events))
+		var poolEvents []model.PoolEvent
+		for _, e := range events {
+			message, err := model.NewOutboxMessage(e.EventName(), e.Pool(), e)
+			if err != nil {
+				return err
+			}
+			messages = append(messages, message)
+
+			if e.Pool() != 0 {
+				poolEvent, err := model.NewPoolEvent(e.EventName(), e.Pool(), e)
+				if err != nil {
+					return err
+				}
+				poolEvents = append(poolEvents, poolEvent)
+			}
+		}
+		if err := tx.PoolEvents.Append(poolEvents...); err != nil {
+			return err
+		}
+		return tx.Outbox.Add(messages...)
+	})
+	if err != nil {
+		return err
+	}
+
+	relay.Notify()
+	return nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"singo/event"
+	"singo/model"
+	"singo/repository"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 激活写入的事件在投递后发布到事件总线，重复投递的消息按事件ID去重
+func TestOutboxRelayDispatch(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+
+	received := make(chan event.FrogActivated, 64)
+	sub := event.Subscribe(func(e event.FrogActivated) {
+		received <- e
+	})
+	defer sub.Unsubscribe()
+
+	user := &model.User{WalletAddress: "wallet-outbox"}
+	repos.Users.Create(user)
+	if res := s.Activate(user, "tx-outbox", ""); res.Code != 0 {
+		t.Fatalf("activate: %+v", res)
+	}
+	if pending, _ := repos.Outbox.CountPending(); pending == 0 {
+		t.Fatal("activation did not write to the outbox")
+	}
+
+	if _, err := GetOutboxRelay().Dispatch(); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := repos.Outbox.CountPending(); pending != 0 {
+		t.Fatalf("pending = %d after dispatch", pending)
+	}
+	event.Flush()
+
+	activated := 0
+	for len(received) > 0 {
+		if e := <-received; e.UserID == user.ID {
+			activated++
+		}
+	}
+	if activated != 1 {
+		t.Fatalf("frog activated delivered %d times, want 1", activated)
+	}
+
+	// 标记已投递失败时会再次发布，相同的事件ID只处理一次
+	for i := 0; i < 2; i++ {
+		event.PublishOnce("outbox-replay", event.FrogActivated{UserID: user.ID})
+	}
+	event.Flush()
+	if len(received) != 1 {
+		t.Fatalf("replayed event delivered %d times, want 1", len(received))
+	}
+}
+
+// 事务回滚时状态变更与事件都不保留
+func TestOutboxRollback(t *testing.T) {
+	repos := testRepos
+	before, _ := repos.Outbox.CountPending()
+
+	var poolID uint
+	errAbort := errors.New("abort")
+	err := withEvents(repos, GetOutboxRelay(), func(tx *repository.Repositories) ([]event.Event, error) {
+		pool := model.NewPool()
+		if err := tx.Pools.Create(&pool); err != nil {
+			return nil, err
+		}
+		poolID = pool.ID
+		if err := tx.Outbox.Add(model.OutboxMessage{EventID: "rollback", Type: "test"}); err != nil {
+			return nil, err
+		}
+		return nil, errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("err = %v", err)
+	}
+
+	if _, err := repos.Pools.Get(poolID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("pool survived rollback: %v", err)
+	}
+	if after, _ := repos.Outbox.CountPending(); after != before {
+		t.Fatalf("pending = %d, want %d", after, before)
+	}
+}
+
+// 发起提取时安排过期通知并写入发件箱，事件在投递后才发布
+func TestClaimEventPublishedThroughOutbox(t *testing.T) {
+	repos := testRepos
+	received := make(chan event.RewardClaimed, 64)
+	sub := event.Subscribe(func(e event.RewardClaimed) {
+		received <- e
+	})
+	defer sub.Unsubscribe()
+
+	user := &model.User{WalletAddress: "wallet-claim-outbox"}
+	repos.Users.Create(user)
+	if err := GetGameJobs().RecordClaim(user.ID, 0.25); err != nil {
+		t.Fatal(err)
+	}
+
+	event.Flush()
+	if len(received) != 0 {
+		t.Fatal("claim event published before the outbox was dispatched")
+	}
+	pending, _ := repos.Jobs.List(model.JobPending, 200)
+	scheduled := false
+	for _, job := range pending {
+		scheduled = scheduled || (job.Type == JobClaimExpiry && strings.Contains(job.Payload, `"userId":`+strconv.FormatUint(uint64(user.ID), 10)+`,`))
+	}
+	if !scheduled {
+		t.Fatalf("claim expiry not scheduled: %+v", pending)
+	}
+
+	if _, err := GetOutboxRelay().Dispatch(); err != nil {
+		t.Fatal(err)
+	}
+	event.Flush()
+	claimed := 0
+	for len(received) > 0 {
+		if e := <-received; e.UserID == user.ID && e.Amount == 0.25 {
+			claimed++
+		}
+	}
+	if claimed != 1 {
+		t.Fatalf("claim event delivered %d times, want 1", claimed)
+	}
+}
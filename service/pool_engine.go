@@ -0,0 +1,196 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"singo/event"
+	"singo/metrics"
+	"singo/model"
+	"singo/repository"
+	"sync"
+	"time"
+)
+
+const (
+	// actorQueueSize 每个奖池执行协程的命令队列长度
+	actorQueueSize = 64
+	// actorIdleTimeout 执行协程空闲多久后退出，下次有命令时重新创建
+	actorIdleTimeout = time.Minute
+)
+
+// poolCommandFunc 奖池命令，在事务中执行并返回需要写入发件箱的事件
+//
+// pool 为执行协程持有的奖池状态副本，命令修改后须通过仓库持久化；
+// 命令成功后副本成为新的状态，失败时丢弃并在下次命令前从数据库重新加载。
+type poolCommandFunc func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error)
+
+// poolCommand 发送给执行协程的命令
+type poolCommand struct {
+	name  string
+	run   poolCommandFunc
+	reply chan error
+}
+
+// PoolEngine 奖池游戏引擎
+//
+// 每个奖池由一个执行协程依次处理加入、投喂、饥饿值衰减、大奖移动、抓取大奖与状态切换等命令，
+// 同一奖池的读-改-写不会并发执行。执行协程在奖池结束或空闲一段时间后退出。
+// 命令中不能再向引擎发送命令，否则会等待自身而阻塞。
+type PoolEngine struct {
+	mu     sync.Mutex
+	actors map[uint]*poolActor
+
+	repos *repository.Repositories
+	relay *OutboxRelay
+	idle  time.Duration
+}
+
+// poolActor 单个奖池的执行协程
+type poolActor struct {
+	engine   *PoolEngine
+	poolID   uint
+	commands chan poolCommand
+	pending  int // 已提交尚未处理完的命令数，受engine.mu保护
+
+	pool *model.PrizePool // 奖池当前状态，只在执行协程中访问，nil表示需要重新加载
+}
+
+// NewPoolEngine 创建奖池游戏引擎
+func NewPoolEngine(repos *repository.Repositories, relay *OutboxRelay) *PoolEngine {
+	return &PoolEngine{
+		actors: make(map[uint]*poolActor),
+		repos:  repos,
+		relay:  relay,
+		idle:   actorIdleTimeout,
+	}
+}
+
+// GetPoolEngine 获取奖池游戏引擎实例
+func GetPoolEngine() *PoolEngine {
+	return poolEngine
+}
+
+// Do 在奖池的执行协程中执行命令并等待结果；poolID为0时命令不属于任何奖池，直接在事务中执行
+func (e *PoolEngine) Do(poolID uint, name string, fn poolCommandFunc) error {
+	if poolID == 0 {
+		return withEvents(e.repos, e.relay, func(tx *repository.Repositories) ([]event.Event, error) {
+			return fn(tx, nil)
+		})
+	}
+
+	reply := make(chan error, 1)
+	actor := e.acquire(poolID)
+	actor.commands <- poolCommand{name: name, run: fn, reply: reply}
+	return <-reply
+}
+
+// Join 在收集中奖池的执行协程中执行加入命令
+//
+// 加入时由仓库选择奖池，没有收集中的奖池时直接执行并由仓库创建新奖池，
+// 奖池在命令排队期间满员时仓库会加入下一个奖池，其条件更新保证不会超员。
+func (e *PoolEngine) Join(fn poolCommandFunc) error {
+	pools, err := e.repos.Pools.ListByStatus(model.PoolStatusCollecting)
+	if err != nil {
+		return err
+	}
+	var poolID uint
+	if len(pools) > 0 {
+		poolID = pools[0].ID
+	}
+	return e.Do(poolID, "join", fn)
+}
+
+// ActorCount 当前运行的执行协程数量
+func (e *PoolEngine) ActorCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.actors)
+}
+
+// acquire 获取奖池的执行协程，不存在时创建，并登记一条待处理的命令
+func (e *PoolEngine) acquire(poolID uint) *poolActor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	actor, ok := e.actors[poolID]
+	if !ok {
+		actor = &poolActor{
+			engine:   e,
+			poolID:   poolID,
+			commands: make(chan poolCommand, actorQueueSize),
+		}
+		e.actors[poolID] = actor
+		metrics.PoolActors.Set(int64(len(e.actors)))
+		go actor.run()
+	}
+	actor.pending++
+	return actor
+}
+
+// run 依次处理命令，奖池结束或空闲超时且没有待处理的命令时退出
+func (a *poolActor) run() {
+	for {
+		select {
+		case cmd := <-a.commands:
+			a.handle(cmd)
+
+			a.engine.mu.Lock()
+			a.pending--
+			a.engine.mu.Unlock()
+
+			if a.pool != nil && !a.pool.Status.Live() && a.stop() {
+				return
+			}
+		case <-time.After(a.engine.idle):
+			if a.stop() {
+				return
+			}
+		}
+	}
+}
+
+// stop 没有待处理的命令时注销执行协程
+func (a *poolActor) stop() bool {
+	e := a.engine
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if a.pending > 0 {
+		return false
+	}
+	delete(e.actors, a.poolID)
+	metrics.PoolActors.Set(int64(len(e.actors)))
+	return true
+}
+
+// handle 执行一条命令：状态变更与事件写入在同一事务中完成，成功后采用命令修改后的状态
+func (a *poolActor) handle(cmd poolCommand) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.pool = nil
+			log.Printf("奖池 %d 的命令 %s 发生panic: %v\n%s", a.poolID, cmd.name, r, debug.Stack())
+			cmd.reply <- fmt.Errorf("pool %d command %s panicked: %v", a.poolID, cmd.name, r)
+		}
+	}()
+
+	if a.pool == nil {
+		pool, err := a.engine.repos.Pools.Get(a.poolID)
+		if err != nil {
+			cmd.reply <- err
+			return
+		}
+		a.pool = pool
+	}
+
+	working := *a.pool
+	err := withEvents(a.engine.repos, a.engine.relay, func(tx *repository.Repositories) ([]event.Event, error) {
+		return cmd.run(tx, &working)
+	})
+	if err != nil {
+		a.pool = nil
+	} else {
+		a.pool = &working
+	}
+	cmd.reply <- err
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"singo/event"
+	"singo/model"
+	"singo/repository"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 同一奖池的命令依次执行，命令之间共享执行协程持有的奖池状态，失败的命令不影响状态
+func TestPoolEngineSerializesCommands(t *testing.T) {
+	const commands = 50
+	engine := NewPoolEngine(testRepos, GetOutboxRelay())
+
+	pool := model.NewPool()
+	pool.Status = model.PoolStatusActive
+	pool.CollectingSlot = nil
+	testRepos.Pools.Create(&pool)
+
+	var inFlight, overlapped int32
+	var wg sync.WaitGroup
+	for i := 0; i < commands; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := engine.Do(pool.ID, "count", func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+				if atomic.AddInt32(&inFlight, 1) > 1 {
+					atomic.StoreInt32(&overlapped, 1)
+				}
+				defer atomic.AddInt32(&inFlight, -1)
+				// 只修改内存中的状态，不持久化
+				pool.CurrentPlayers++
+				return nil, nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if overlapped != 0 {
+		t.Fatal("commands for the same pool overlapped")
+	}
+
+	errAbort := errors.New("abort")
+	var players int
+	engine.Do(pool.ID, "read", func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+		players = pool.CurrentPlayers
+		pool.CurrentPlayers = 0
+		return nil, errAbort
+	})
+	if players != commands {
+		t.Fatalf("players = %d, want %d", players, commands)
+	}
+
+	// 失败的命令丢弃修改，重新从仓库加载
+	engine.Do(pool.ID, "read", func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+		players = pool.CurrentPlayers
+		return nil, nil
+	})
+	if players != 0 {
+		t.Fatalf("players after reload = %d, want 0", players)
+	}
+}
+
+// 奖池结束或空闲超时后执行协程退出
+func TestPoolEngineStopsActors(t *testing.T) {
+	engine := NewPoolEngine(testRepos, GetOutboxRelay())
+	engine.idle = 10 * time.Millisecond
+
+	ended := model.NewPool()
+	ended.Status = model.PoolStatusActive
+	ended.CollectingSlot = nil
+	testRepos.Pools.Create(&ended)
+	idle := ended
+	idle.ID = 0
+	testRepos.Pools.Create(&idle)
+
+	err := engine.Do(ended.ID, "cancel", func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+		changed, err := transitionPool(tx, pool, model.PoolStatusCancelled, "test", 0)
+		return []event.Event{changed}, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Do(idle.ID, "noop", func(*repository.Repositories, *model.PrizePool) ([]event.Event, error) {
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for engine.ActorCount() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d actors still running", engine.ActorCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
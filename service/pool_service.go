@@ -0,0 +1,304 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"singo/event"
+	"singo/model"
+	"singo/repository"
+	"singo/serializer"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PoolService 奖池与排行榜查询，以及奖池状态的管理
+type PoolService struct {
+	users        repository.UserRepository
+	frogs        repository.FrogRepository
+	pools        repository.PoolRepository
+	participants repository.ParticipantRepository
+	repos        *repository.Repositories
+	ws           *WebSocketManager
+	rewards      *RewardService
+	engine       *PoolEngine
+
+	subscriptions []*event.Subscription
+}
+
+// PoolTransitionService 管理员切换奖池状态的服务
+type PoolTransitionService struct {
+	Status string `form:"status" json:"status" binding:"required"`
+	Reason string `form:"reason" json:"reason" binding:"required,max=255"`
+}
+
+// NewPoolService 创建奖池服务
+func NewPoolService(repos *repository.Repositories, ws *WebSocketManager, rewards *RewardService, engine *PoolEngine) *PoolService {
+	return &PoolService{
+		users:        repos.Users,
+		frogs:        repos.Frogs,
+		pools:        repos.Pools,
+		participants: repos.Participants,
+		repos:        repos,
+		ws:           ws,
+		rewards:      rewards,
+		engine:       engine,
+	}
+}
+
+// SubscribeEvents 获胜者的奖励支付后，将其结算中的奖池切换为已完成
+func (s *PoolService) SubscribeEvents() {
+	s.subscriptions = append(s.subscriptions,
+		event.Subscribe(s.handleRewardPaid),
+	)
+}
+
+// UnsubscribeEvents 取消全部事件订阅
+func (s *PoolService) UnsubscribeEvents() {
+	for _, sub := range s.subscriptions {
+		sub.Unsubscribe()
+	}
+	s.subscriptions = nil
+}
+
+// handleRewardPaid 完成获胜者结算中的奖池
+func (s *PoolService) handleRewardPaid(e event.RewardPaid) {
+	user, err := s.users.Get(e.UserID)
+	if err != nil {
+		log.Printf("获取用户 %d 失败: %v", e.UserID, err)
+		return
+	}
+
+	pools, err := s.pools.ListWonBy(user.WalletAddress, model.PoolStatusSettling)
+	if err != nil {
+		log.Printf("获取用户 %d 结算中的奖池失败: %v", e.UserID, err)
+		return
+	}
+	for _, pool := range pools {
+		err := s.engine.Do(pool.ID, "complete", func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+			changed, err := transitionPool(tx, pool, model.PoolStatusCompleted, "reward paid", 0)
+			if err != nil {
+				return nil, err
+			}
+			return []event.Event{changed}, nil
+		})
+		if err != nil {
+			// 奖池可能已被管理员标记为有争议，保持其当前状态
+			log.Printf("完成奖池 %d 失败: %v", pool.ID, err)
+		}
+	}
+}
+
+// transitionPool 在事务中切换奖池状态并写入变更记录，返回对应的状态变更事件
+func transitionPool(tx *repository.Repositories, pool *model.PrizePool, to model.PoolStatus, reason string, actorUserID uint) (event.Event, error) {
+	transition, err := tx.Pools.Transition(pool, to, reason, actorUserID)
+	if err != nil {
+		return nil, err
+	}
+	return event.PoolStatusChanged{
+		PoolID: pool.ID,
+		From:   string(transition.FromStatus),
+		To:     string(transition.ToStatus),
+		Reason: reason,
+	}, nil
+}
+
+// CurrentPool 获取用户当前参与的奖池状态
+func (s *PoolService) CurrentPool(user *model.User) serializer.Response {
+	empty := serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"pool":         nil,
+			"participants": []interface{}{},
+		},
+	}
+
+	// 查找用户激活青蛙最近参与的未完成的奖池
+	pool, err := s.ws.currentPool(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to get pool", err)
+	}
+	if pool == nil {
+		return empty
+	}
+
+	// 获取奖池所有参与者
+	participants, err := s.participants.ListByPool(pool.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to get participants", err)
+	}
+
+	// 构建参与者信息
+	var participantsData []gin.H
+	for _, p := range participants {
+		participantsData = append(participantsData, gin.H{
+			"walletAddress":  p.WalletAddress,
+			"serialNumber":   p.SerialNumber,
+			"canSeeBigPrize": p.WalletAddress == pool.CurrentBigPrizeHolder,
+		})
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"pool": gin.H{
+				"id":             pool.ID,
+				"status":         pool.Status,
+				"currentPlayers": pool.CurrentPlayers,
+				"prizeAmount":    pool.PrizeAmount,
+			},
+			"participants": participantsData,
+		},
+	}
+}
+
+// LivePool 获取可观战的奖池（收集中或活跃中）
+func (s *PoolService) LivePool(poolID uint) (*model.PrizePool, error) {
+	pool, err := s.pools.Get(poolID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrPoolNotLive
+		}
+		return nil, err
+	}
+	if !pool.Status.Live() {
+		return nil, ErrPoolNotLive
+	}
+	return pool, nil
+}
+
+// ListPools 按创建时间倒序列出奖池，status为空时不过滤
+func (s *PoolService) ListPools(status model.PoolStatus, limit int) ([]model.PrizePool, error) {
+	return s.pools.List(status, limit)
+}
+
+// Leaderboard 按历史总收益列出用户
+func (s *PoolService) Leaderboard(limit int) ([]model.User, error) {
+	return s.users.Leaderboard(limit)
+}
+
+// adminTargets 管理员可以切换到的状态
+var adminTargets = map[model.PoolStatus]bool{
+	model.PoolStatusSettling:  true,
+	model.PoolStatusCompleted: true,
+	model.PoolStatusCancelled: true,
+	model.PoolStatusDisputed:  true,
+}
+
+// Transition 管理员切换奖池状态
+func (service *PoolTransitionService) Transition(actor *model.User, poolID string) serializer.Response {
+	return GetPoolService().Transition(actor, poolID, model.PoolStatus(service.Status), service.Reason)
+}
+
+// Transition 管理员将奖池切换到目标状态
+//
+// 取消奖池或在无人获胜时完成奖池会停用参与者的青蛙，剩余奖金归平台并广播游戏结束。
+func (s *PoolService) Transition(actor *model.User, poolID string, to model.PoolStatus, reason string) serializer.Response {
+	id, err := strconv.ParseUint(poolID, 10, 64)
+	if err != nil {
+		return serializer.ParamErr("Invalid pool id", err)
+	}
+	if !adminTargets[to] {
+		return serializer.ParamErr("Invalid pool status", nil)
+	}
+
+	var result model.PrizePool
+	err = s.engine.Do(uint(id), "transition", func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+		if to == model.PoolStatusSettling && pool.BigPrizeWinner == "" {
+			return nil, &model.TransitionError{PoolID: pool.ID, From: pool.Status, To: to}
+		}
+
+		changed, err := transitionPool(tx, pool, to, reason, actor.ID)
+		if err != nil {
+			return nil, err
+		}
+		events := []event.Event{changed}
+
+		if to == model.PoolStatusCancelled || (to == model.PoolStatusCompleted && pool.BigPrizeWinner == "") {
+			if err := s.endWithoutWinner(tx, pool); err != nil {
+				return nil, err
+			}
+			events = append(events, event.PoolCompleted{PoolID: pool.ID})
+		}
+		result = *pool
+		return events, nil
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return serializer.ParamErr("Pool not found", err)
+		}
+		if errors.Is(err, model.ErrInvalidTransition) {
+			return serializer.Err(serializer.CodeInvalidTransition, err.Error(), err)
+		}
+		return serializer.DBErr("Failed to change pool status", err)
+	}
+
+	log.Printf("管理员 %s 将奖池 %d 切换为 %s: %s", actor.WalletAddress, result.ID, result.Status, reason)
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"id":     result.ID,
+			"status": result.Status,
+		},
+	}
+}
+
+// endWithoutWinner 停用奖池中仍活跃的青蛙，剩余奖金归平台
+func (s *PoolService) endWithoutWinner(tx *repository.Repositories, pool *model.PrizePool) error {
+	participants, err := tx.Participants.ListByPool(pool.ID)
+	if err != nil {
+		return err
+	}
+	for _, participant := range participants {
+		frog, err := tx.Frogs.Get(participant.FrogID)
+		if err != nil {
+			return err
+		}
+		if !frog.IsActive {
+			continue
+		}
+		frog.IsActive = false
+		if err := tx.Frogs.Save(frog); err != nil {
+			return err
+		}
+	}
+	return s.rewards.using(tx).ForfeitPool(pool.ID)
+}
+
+// PoolHistory 按时间顺序列出奖池的状态变更记录
+func (s *PoolService) PoolHistory(poolID string) serializer.Response {
+	id, err := strconv.ParseUint(poolID, 10, 64)
+	if err != nil {
+		return serializer.ParamErr("Invalid pool id", err)
+	}
+	if _, err := s.pools.Get(uint(id)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return serializer.ParamErr("Pool not found", err)
+		}
+		return serializer.DBErr("Failed to get pool", err)
+	}
+
+	transitions, err := s.pools.ListTransitions(uint(id))
+	if err != nil {
+		return serializer.DBErr("Failed to get pool history", err)
+	}
+	data := []gin.H{}
+	for _, t := range transitions {
+		data = append(data, gin.H{
+			"id":          t.ID,
+			"from":        t.FromStatus,
+			"to":          t.ToStatus,
+			"reason":      t.Reason,
+			"actorUserId": t.ActorUserID,
+			"createdAt":   t.CreatedAt.Unix(),
+		})
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"poolId":      id,
+			"transitions": data,
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"singo/model"
+	"singo/serializer"
+	"testing"
+)
+
+// 管理员按状态机切换奖池状态，不允许的切换返回 CodeInvalidTransition
+func TestPoolTransitionService(t *testing.T) {
+	repos := testRepos
+
+	admin := &model.User{WalletAddress: "wallet-pool-admin"}
+	repos.Users.Create(admin)
+
+	pool := model.NewPool()
+	pool.Status = model.PoolStatusActive
+	pool.CollectingSlot = nil
+	repos.Pools.Create(&pool)
+	id := fmt.Sprint(pool.ID)
+
+	steps := []struct {
+		status string
+		code   int
+	}{
+		{"settling", serializer.CodeInvalidTransition}, // 没有获胜者
+		{"disputed", 0},
+		{"active", serializer.CodeParamErr}, // 管理员不能恢复游戏
+		{"cancelled", 0},
+		{"completed", serializer.CodeInvalidTransition}, // 终态
+	}
+	for _, step := range steps {
+		service := PoolTransitionService{Status: step.status, Reason: "test"}
+		if res := service.Transition(admin, id); res.Code != step.code {
+			t.Fatalf("transition to %s: %+v, want code %d", step.status, res, step.code)
+		}
+	}
+
+	saved, _ := repos.Pools.Get(pool.ID)
+	if saved.Status != model.PoolStatusCancelled || saved.CompletedAt == nil {
+		t.Fatalf("pool not cancelled: %+v", saved)
+	}
+
+	history, _ := repos.Pools.ListTransitions(pool.ID)
+	if len(history) != 2 || history[1].ActorUserID != admin.ID || history[1].Reason != "test" {
+		t.Fatalf("history = %+v", history)
+	}
+
+	_, err := repos.Pools.Transition(saved, model.PoolStatusActive, "", 0)
+	var transitionErr *model.TransitionError
+	if !errors.Is(err, model.ErrInvalidTransition) || !errors.As(err, &transitionErr) || transitionErr.From != model.PoolStatusCancelled {
+		t.Fatalf("transition error = %v", err)
+	}
+}
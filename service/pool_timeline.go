@@ -0,0 +1,177 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"singo/event"
+	"singo/model"
+	"singo/repository"
+	"singo/serializer"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PoolTimelineService 管理员查询奖池时间线的服务
+type PoolTimelineService struct {
+	// At 重放到的时刻，RFC3339 或 Unix 秒，为空时重放全部事件
+	At string `form:"at" json:"at"`
+}
+
+// PoolPlayerState 重放得到的玩家状态
+type PoolPlayerState struct {
+	UserID        uint   `json:"userId"`
+	FrogID        uint   `json:"frogId"`
+	WalletAddress string `json:"walletAddress"`
+	SerialNumber  int    `json:"serialNumber"`
+	HungerLevel   int    `json:"hungerLevel"`
+	Active        bool   `json:"active"`
+}
+
+// PoolReplayState 重放得到的奖池状态
+type PoolReplayState struct {
+	PoolID         uint               `json:"poolId"`
+	Status         model.PoolStatus   `json:"status"` // 奖池创建之前为空
+	BigPrizeHolder string             `json:"bigPrizeHolder"`
+	Winner         string             `json:"winner"`
+	PrizeAmount    float64            `json:"prizeAmount"`
+	Players        []*PoolPlayerState `json:"players"` // 按序号排列
+	LastSeq        uint               `json:"lastSeq"` // 已应用的最后一个事件的序号
+}
+
+// ReplayPool 按序号依次应用奖池事件，还原at时刻（含）的奖池状态；at为零值时应用全部事件
+//
+// 重放只依赖事件本身，相同的事件总是得到相同的状态。
+func ReplayPool(poolID uint, events []model.PoolEvent, at time.Time) (*PoolReplayState, error) {
+	ordered := append([]model.PoolEvent(nil), events...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Seq < ordered[j].Seq })
+
+	state := &PoolReplayState{PoolID: poolID, Players: []*PoolPlayerState{}}
+	players := make(map[uint]*PoolPlayerState) // frogID -> player
+	for _, stored := range ordered {
+		if stored.PoolID != poolID {
+			continue
+		}
+		if !at.IsZero() && stored.CreatedAt.After(at) {
+			break
+		}
+
+		e, err := event.Decode(stored.Type, []byte(stored.Payload))
+		if err != nil {
+			return nil, fmt.Errorf("pool %d event %d: %w", poolID, stored.Seq, err)
+		}
+
+		switch e := e.(type) {
+		case event.PoolStatusChanged:
+			state.Status = model.PoolStatus(e.To)
+		case event.FrogActivated:
+			player := &PoolPlayerState{
+				UserID:        e.UserID,
+				FrogID:        e.FrogID,
+				WalletAddress: e.WalletAddress,
+				SerialNumber:  e.SerialNumber,
+				HungerLevel:   e.HungerLevel,
+				Active:        true,
+			}
+			players[e.FrogID] = player
+			state.Players = append(state.Players, player)
+		case event.FrogFed:
+			if player, ok := players[e.FrogID]; ok {
+				player.HungerLevel = e.HungerLevel
+			}
+		case event.HungerTicked:
+			if player, ok := players[e.FrogID]; ok {
+				player.HungerLevel = e.HungerLevel
+			}
+		case event.FrogStarved:
+			if player, ok := players[e.FrogID]; ok {
+				player.HungerLevel = 0
+				player.Active = false
+			}
+		case event.PrizeMoved:
+			state.BigPrizeHolder = e.Holder
+		case event.PoolCompleted:
+			// 抓到大奖时所有青蛙饥饿值归零，其余情况仅停用
+			state.Winner = e.Winner
+			state.PrizeAmount = e.PrizeAmount
+			for _, player := range state.Players {
+				player.Active = false
+				if e.Winner != "" {
+					player.HungerLevel = 0
+				}
+			}
+		}
+		state.LastSeq = stored.Seq
+	}
+
+	sort.SliceStable(state.Players, func(i, j int) bool {
+		return state.Players[i].SerialNumber < state.Players[j].SerialNumber
+	})
+	return state, nil
+}
+
+// parseTimelineAt 解析RFC3339或Unix秒表示的时刻
+func parseTimelineAt(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// Timeline 返回奖池的全部事件以及重放到指定时刻的状态
+func (service *PoolTimelineService) Timeline(poolID string) serializer.Response {
+	at, err := parseTimelineAt(service.At)
+	if err != nil {
+		return serializer.ParamErr("Invalid time", err)
+	}
+	return GetPoolService().Timeline(poolID, at)
+}
+
+// Timeline 返回奖池的全部事件以及重放到at时的状态，at为零值时重放全部事件
+func (s *PoolService) Timeline(poolID string, at time.Time) serializer.Response {
+	id, err := strconv.ParseUint(poolID, 10, 64)
+	if err != nil {
+		return serializer.ParamErr("Invalid pool id", err)
+	}
+
+	if _, err := s.pools.Get(uint(id)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return serializer.ParamErr("Pool not found", err)
+		}
+		return serializer.DBErr("Failed to get pool", err)
+	}
+
+	events, err := s.repos.PoolEvents.ListByPool(uint(id))
+	if err != nil {
+		return serializer.DBErr("Failed to get pool events", err)
+	}
+	state, err := ReplayPool(uint(id), events, at)
+	if err != nil {
+		return serializer.Err(serializer.CodeDBError, "Failed to replay pool events", err)
+	}
+
+	timeline := []gin.H{}
+	for _, e := range events {
+		timeline = append(timeline, gin.H{
+			"seq":       e.Seq,
+			"type":      e.Type,
+			"payload":   json.RawMessage(e.Payload),
+			"createdAt": e.CreatedAt.UnixMilli(),
+		})
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"poolId":   id,
+			"timeline": timeline,
+			"state":    state,
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"singo/event"
+	"singo/model"
+	"testing"
+	"time"
+)
+
+// poolEvent 构造已存储的奖池事件
+func poolEvent(t *testing.T, seq uint, at time.Time, e event.Event) model.PoolEvent {
+	stored, err := model.NewPoolEvent(e.EventName(), e.Pool(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.Seq = seq
+	stored.CreatedAt = at
+	return stored
+}
+
+// 按序号重放，与输入顺序无关；指定时刻只应用此前的事件
+func TestReplayPool(t *testing.T) {
+	start := time.Now()
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	// 故意打乱顺序
+	events := []model.PoolEvent{
+		poolEvent(t, 7, at(6), event.PoolCompleted{PoolID: 7, Winner: "wallet-b", PrizeAmount: 2}),
+		poolEvent(t, 1, at(1), event.PoolStatusChanged{PoolID: 7, To: "collecting", Reason: "created"}),
+		poolEvent(t, 3, at(3), event.FrogActivated{PoolID: 7, FrogID: 2, UserID: 2, SerialNumber: 2, WalletAddress: "wallet-b", HungerLevel: 50}),
+		poolEvent(t, 2, at(2), event.FrogActivated{PoolID: 7, FrogID: 1, UserID: 1, SerialNumber: 1, WalletAddress: "wallet-a", HungerLevel: 50}),
+		poolEvent(t, 4, at(4), event.PoolStatusChanged{PoolID: 7, From: "collecting", To: "active"}),
+		poolEvent(t, 6, at(5), event.PrizeMoved{PoolID: 7, Holder: "wallet-b"}),
+		poolEvent(t, 5, at(5), event.HungerTicked{PoolID: 7, FrogID: 1, UserID: 1, HungerLevel: 40}),
+	}
+
+	state, err := ReplayPool(7, events, at(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != model.PoolStatusActive || state.BigPrizeHolder != "wallet-b" || state.Winner != "" {
+		t.Fatalf("state at 5s = %+v", state)
+	}
+	if len(state.Players) != 2 || state.Players[0].HungerLevel != 40 || !state.Players[0].Active {
+		t.Fatalf("players at 5s = %+v", state.Players)
+	}
+
+	final, err := ReplayPool(7, events, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.Winner != "wallet-b" || final.LastSeq != 7 || final.Players[1].Active || final.Players[1].HungerLevel != 0 {
+		t.Fatalf("final state = %+v", final)
+	}
+
+	again, _ := ReplayPool(7, events, time.Time{})
+	if again.LastSeq != final.LastSeq || again.Winner != final.Winner || len(again.Players) != len(final.Players) {
+		t.Fatal("replay is not deterministic")
+	}
+}
+
+// 激活与投喂写入奖池事件存储，重放得到当前的饥饿值
+func TestPoolTimelineRecordsEvents(t *testing.T) {
+	s, repos := GetGameService(), testRepos
+
+	user := &model.User{WalletAddress: "wallet-timeline"}
+	repos.Users.Create(user)
+	if res := s.Activate(user, "tx-timeline", ""); res.Code != 0 {
+		t.Fatalf("activate: %+v", res)
+	}
+	if res := s.Feed(user, 10); res.Code != 0 {
+		t.Fatalf("feed: %+v", res)
+	}
+
+	pool, err := s.ws.currentPool(user.ID)
+	if err != nil || pool == nil {
+		t.Fatalf("current pool: %v (%v)", pool, err)
+	}
+	frog, _ := repos.Frogs.GetActiveByUser(user.ID)
+
+	events, err := repos.PoolEvents.ListByPool(pool.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range events {
+		if e.Seq != uint(i+1) {
+			t.Fatalf("event %d has seq %d", i, e.Seq)
+		}
+	}
+
+	state, err := ReplayPool(pool.ID, events, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, player := range state.Players {
+		if player.FrogID == frog.ID {
+			found = true
+			if player.HungerLevel != frog.HungerLevel || player.WalletAddress != user.WalletAddress || !player.Active {
+				t.Fatalf("player = %+v, frog = %+v", player, frog)
+			}
+		}
+	}
+	if !found || !state.Status.Live() {
+		t.Fatalf("state = %+v", state)
+	}
+}
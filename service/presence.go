@@ -0,0 +1,202 @@
+package service
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	// afkTimeout 在线但超过该时间没有操作即视为离开键盘
+	afkTimeout = 60 * time.Second
+	// presenceCheckInterval AFK状态检查间隔
+	presenceCheckInterval = 10 * time.Second
+	// offlinePresenceTTL 离线玩家的在线状态保留时间，超过后从内存中移除
+	offlinePresenceTTL = 10 * time.Minute
+)
+
+// presenceState 玩家在线状态
+type presenceState struct {
+	userID   uint
+	online   bool      // 是否有WebSocket连接
+	afk      bool      // 在线但长时间没有操作
+	lastSeen time.Time // 最后一次操作时间
+	leftAt   time.Time // 最后一个连接关闭的时间
+}
+
+// expired 离线且最后的操作与断开都已超过保留时间
+func (state *presenceState) expired(now time.Time) bool {
+	if state.online {
+		return false
+	}
+	idleSince := state.lastSeen
+	if state.leftAt.After(idleSince) {
+		idleSince = state.leftAt
+	}
+	return now.Sub(idleSince) > offlinePresenceTTL
+}
+
+// presenceChange 待广播的在线状态变化
+type presenceChange struct {
+	walletAddress string
+	state         presenceState
+}
+
+// setOnline 更新玩家的连接状态，状态变化时广播给奖池
+func (m *WebSocketManager) setOnline(userID uint, walletAddress string, online bool) {
+	m.presenceMux.Lock()
+	state, exists := m.presence[walletAddress]
+	if !exists {
+		state = &presenceState{userID: userID}
+		m.presence[walletAddress] = state
+	}
+	changed := state.online != online
+	state.online = online
+	state.afk = false
+	if online {
+		// 建立连接视为一次操作
+		state.lastSeen = time.Now()
+	} else {
+		state.leftAt = time.Now()
+	}
+	snapshot := *state
+	m.presenceMux.Unlock()
+
+	if changed {
+		m.broadcastPresence(walletAddress, snapshot)
+	}
+}
+
+// Touch 记录玩家的一次操作（投喂、抓取大奖等）
+func (m *WebSocketManager) Touch(userID uint, walletAddress string) {
+	m.presenceMux.Lock()
+	state, exists := m.presence[walletAddress]
+	if !exists {
+		state = &presenceState{userID: userID}
+		m.presence[walletAddress] = state
+	}
+	wasAfk := state.afk
+	state.afk = false
+	state.lastSeen = time.Now()
+	snapshot := *state
+	m.presenceMux.Unlock()
+
+	if wasAfk {
+		m.broadcastPresence(walletAddress, snapshot)
+	}
+}
+
+// withPresence 为参与者数据附加在线状态
+func (m *WebSocketManager) withPresence(participants []map[string]interface{}) []map[string]interface{} {
+	m.presenceMux.RLock()
+	defer m.presenceMux.RUnlock()
+
+	result := make([]map[string]interface{}, 0, len(participants))
+	for _, p := range participants {
+		data := make(map[string]interface{}, len(p)+3)
+		for k, v := range p {
+			data[k] = v
+		}
+
+		data["isOnline"] = false
+		data["isAfk"] = false
+		data["lastSeen"] = nil
+		if walletAddress, ok := p["walletAddress"].(string); ok {
+			if state, exists := m.presence[walletAddress]; exists {
+				data["isOnline"] = state.online
+				data["isAfk"] = state.afk
+				if !state.lastSeen.IsZero() {
+					data["lastSeen"] = state.lastSeen.Unix()
+				}
+			}
+		}
+		result = append(result, data)
+	}
+	return result
+}
+
+// broadcastPresence 向玩家所在的奖池广播在线状态变化
+func (m *WebSocketManager) broadcastPresence(walletAddress string, state presenceState) {
+	pool, err := m.currentPool(state.userID)
+	if err != nil {
+		log.Printf("获取用户 %d 当前奖池失败: %v", state.userID, err)
+		return
+	}
+	if pool == nil {
+		return
+	}
+
+	message := map[string]interface{}{
+		"type":          "presence-update",
+		"poolId":        pool.ID,
+		"walletAddress": walletAddress,
+		"isOnline":      state.online,
+		"isAfk":         state.afk,
+		"lastSeen":      nil,
+	}
+	if !state.lastSeen.IsZero() {
+		message["lastSeen"] = state.lastSeen.Unix()
+	}
+
+	// 只推送给同一奖池的参与者与观战者，其他奖池的玩家看不到
+	m.publishToPool(pool.ID, message)
+	m.broadcastToSpectators(pool.ID, message)
+}
+
+// publishToPool 将消息推送给奖池参与者的SSE订阅与WebSocket连接
+func (m *WebSocketManager) publishToPool(poolID uint, message map[string]interface{}) {
+	participants, err := m.participants.ListByPool(poolID)
+	if err != nil {
+		log.Printf("获取奖池 %d 参与者信息失败: %v", poolID, err)
+		return
+	}
+
+	for _, p := range participants {
+		frog, err := m.frogs.Get(p.FrogID)
+		if err != nil {
+			log.Printf("获取青蛙 %d 状态失败: %v", p.FrogID, err)
+			continue
+		}
+		m.publish(frog.UserID, message)
+
+		m.clientsMux.RLock()
+		client, exists := m.clients[frog.UserID]
+		m.clientsMux.RUnlock()
+		if exists {
+			client.Send(message)
+		}
+	}
+}
+
+// StartPresenceWorker 启动AFK状态检查工作器
+func (m *WebSocketManager) StartPresenceWorker() {
+	ticker := time.NewTicker(presenceCheckInterval)
+	go func() {
+		for range ticker.C {
+			m.checkAfk()
+		}
+	}()
+}
+
+// checkAfk 将长时间没有操作的在线玩家标记为AFK，移除离线已久的玩家
+func (m *WebSocketManager) checkAfk() {
+	var changes []presenceChange
+	now := time.Now()
+
+	m.presenceMux.Lock()
+	for walletAddress, state := range m.presence {
+		if state.expired(now) {
+			delete(m.presence, walletAddress)
+			continue
+		}
+		if state.online && !state.afk && time.Since(state.lastSeen) > afkTimeout {
+			state.afk = true
+			changes = append(changes, presenceChange{walletAddress: walletAddress, state: *state})
+		}
+	}
+	m.presenceMux.Unlock()
+
+	for _, change := range changes {
+		log.Printf("用户 %d 已超过 %v 没有操作，标记为AFK", change.state.userID, afkTimeout)
+		m.broadcastPresence(change.walletAddress, change.state)
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"fmt"
+	"singo/model"
+	"singo/repository"
+	"testing"
+	"time"
+)
+
+// 在线状态变化只推送给同一奖池的参与者，其他奖池的玩家收不到
+func TestPresenceScopedToPool(t *testing.T) {
+	repos := repository.NewMemory()
+	m := NewWebSocketManager(repos, nil, nil)
+
+	// 前MaxPoolPlayers只青蛙占满第一个奖池，最后一只进入新的奖池
+	var frogs []model.Frog
+	for i := 0; i <= model.MaxPoolPlayers; i++ {
+		frog := model.NewFrog(uint(i + 1))
+		if err := repos.Frogs.Create(&frog); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := repos.Pools.Join(frog.ID, fmt.Sprintf("presence-wallet-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+		frogs = append(frogs, frog)
+	}
+	player, poolmate, outsider := frogs[0], frogs[1], frogs[model.MaxPoolPlayers]
+	if pool, _ := m.currentPool(outsider.UserID); pool == nil {
+		t.Fatal("outsider has no pool")
+	} else if current, _ := m.currentPool(player.UserID); current.ID == pool.ID {
+		t.Fatal("outsider joined the player's pool")
+	}
+
+	mate, _, _ := m.SubscribeStream(poolmate.UserID, 0)
+	defer m.UnsubscribeStream(mate)
+	other, _, _ := m.SubscribeStream(outsider.UserID, 0)
+	defer m.UnsubscribeStream(other)
+
+	m.setOnline(player.UserID, "presence-wallet-0", true)
+
+	select {
+	case e := <-mate.Events:
+		if e.Type != "presence-update" || e.Data["walletAddress"] != "presence-wallet-0" || e.Data["isOnline"] != true {
+			t.Fatalf("poolmate event = %+v", e)
+		}
+	default:
+		t.Fatal("poolmate did not receive the presence update")
+	}
+	select {
+	case e := <-other.Events:
+		t.Fatalf("player in another pool received %+v", e)
+	default:
+	}
+}
+
+// 离线超过保留时间的玩家由AFK检查移除，在线与刚离线的玩家保留
+func TestPresencePrunesOfflinePlayers(t *testing.T) {
+	m := NewWebSocketManager(repository.NewMemory(), nil, nil)
+
+	m.setOnline(1, "online-wallet", true)
+	m.setOnline(2, "left-wallet", true)
+	m.setOnline(2, "left-wallet", false)
+	m.setOnline(3, "stale-wallet", true)
+	m.setOnline(3, "stale-wallet", false)
+	m.Touch(4, "idle-wallet")
+
+	stale := time.Now().Add(-offlinePresenceTTL - time.Minute)
+	m.presenceMux.Lock()
+	m.presence["online-wallet"].lastSeen = stale
+	m.presence["stale-wallet"].lastSeen = stale
+	m.presence["stale-wallet"].leftAt = stale
+	m.presence["idle-wallet"].lastSeen = stale
+	m.presenceMux.Unlock()
+
+	m.checkAfk()
+
+	m.presenceMux.RLock()
+	defer m.presenceMux.RUnlock()
+	for wallet, kept := range map[string]bool{"online-wallet": true, "left-wallet": true, "stale-wallet": false, "idle-wallet": false} {
+		if _, exists := m.presence[wallet]; exists != kept {
+			t.Errorf("%s: kept = %v, want %v", wallet, exists, kept)
+		}
+	}
+}
@@ -1,181 +1,200 @@
 package service
 
 import (
+	"errors"
 	"log"
 	"math/rand"
 	"singo/event"
 	"singo/model"
-	"sync"
+	"singo/repository"
+	"strconv"
 	"time"
 )
 
+// JobPrizeMove 活跃奖池定期移动大奖位置的任务，执行后推迟到下一次移动，奖池结束时完成
+const JobPrizeMove = "pool.prize_move"
+
+// prizeMoveInterval 大奖位置的移动间隔
+const prizeMoveInterval = 10 * time.Second
+
 // PrizeUpdaterService 大奖位置更新服务
+//
+// 移动节奏由持久化的任务驱动，重启或多实例部署时不会丢失或重复；
+// 已出现过大奖的青蛙从奖池事件中的 PrizeMoved 记录恢复。
 type PrizeUpdaterService struct {
-	updaters    map[uint]chan struct{} // poolID -> stop channel
-	updatersMux sync.RWMutex
+	pools   repository.PoolRepository
+	repos   *repository.Repositories
+	rewards *RewardService
+	engine  *PoolEngine
 }
 
-var (
-	prizeUpdater = &PrizeUpdaterService{
-		updaters: make(map[uint]chan struct{}),
+// NewPrizeUpdaterService 创建大奖位置更新服务
+func NewPrizeUpdaterService(repos *repository.Repositories, rewards *RewardService, engine *PoolEngine) *PrizeUpdaterService {
+	return &PrizeUpdaterService{
+		pools:   repos.Pools,
+		repos:   repos,
+		rewards: rewards,
+		engine:  engine,
 	}
-)
+}
 
 // GetPrizeUpdaterService 获取大奖位置更新服务实例
 func GetPrizeUpdaterService() *PrizeUpdaterService {
 	return prizeUpdater
 }
 
-func init() {
-	// 初始化随机数生成器
-	rand.Seed(time.Now().UnixNano())
+// Register 向任务队列注册大奖移动任务的处理器
+func (s *PrizeUpdaterService) Register(q *JobQueue) {
+	HandleJob(q, JobPrizeMove, s.prizeMoveDue)
+}
 
-	// 订阅奖池激活事件
-	event.Subscribe(event.PoolBecameActive, func(e event.PoolEvent) {
-		prizeUpdater.StartUpdater(e.PoolID)
-	})
+// schedulePrizeMove 奖池变为活跃时在同一事务中安排大奖移动，每个奖池只有一个未结束的任务
+func schedulePrizeMove(tx *repository.Repositories, poolID uint) error {
+	return scheduleJob(tx.Jobs, JobPrizeMove, poolJob{PoolID: poolID},
+		time.Now().Add(prizeMoveInterval), defaultJobMaxAttempts, "prize:"+strconv.FormatUint(uint64(poolID), 10))
 }
 
-// InitializeUpdaters 初始化所有活跃奖池的大奖更新器
-func (s *PrizeUpdaterService) InitializeUpdaters() {
-	var pools []model.PrizePool
-	result := model.DB.Where("status = ?", model.PoolStatusActive).Find(&pools)
-	if result.Error != nil {
-		log.Printf("获取活跃奖池失败: %v", result.Error)
+// ScheduleActivePools 为已活跃但还没有移动任务的奖池安排大奖移动，已有任务的奖池不受影响
+func (s *PrizeUpdaterService) ScheduleActivePools() {
+	pools, err := s.pools.ListByStatus(model.PoolStatusActive)
+	if err != nil {
+		log.Printf("获取活跃奖池失败: %v", err)
 		return
 	}
 
 	for _, pool := range pools {
-		s.StartUpdater(pool.ID)
+		if err := schedulePrizeMove(s.repos, pool.ID); err != nil {
+			log.Printf("安排奖池 %d 的大奖移动失败: %v", pool.ID, err)
+		}
 	}
 }
 
-// StartUpdater 启动大奖位置更新器
-func (s *PrizeUpdaterService) StartUpdater(poolID uint) {
-	s.updatersMux.Lock()
-	defer s.updatersMux.Unlock()
-
-	// 如果已经存在更新器，先停止它
-	if stopCh, exists := s.updaters[poolID]; exists {
-		close(stopCh)
-		delete(s.updaters, poolID)
-	}
-
-	stopCh := make(chan struct{})
-	s.updaters[poolID] = stopCh
-
-	go func() {
-		// 用于追踪已经出现过大奖的青蛙
-		appearedFrogs := make(map[uint]bool)
-		ticker := time.NewTicker(10 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-stopCh:
-				return
-			case <-ticker.C:
-				// 获取奖池信息
-				var pool model.PrizePool
-				if err := model.DB.First(&pool, poolID).Error; err != nil {
-					log.Printf("获取奖池 %d 信息失败: %v", poolID, err)
-					return
-				}
-
-				// 如果奖池已完成，停止更新器
-				if pool.Status == model.PoolStatusCompleted {
-					s.StopUpdater(poolID)
-					return
-				}
-
-				// 获取所有活跃的青蛙
-				participants, err := model.GetParticipantsByPoolID(poolID)
-				if err != nil {
-					log.Printf("获取奖池 %d 参与者失败: %v", poolID, err)
-					continue
-				}
-
-				var activeFrogs []uint
-				for _, p := range participants {
-					var frog model.Frog
-					if err := model.DB.First(&frog, p.FrogID).Error; err != nil {
-						continue
-					}
-					if frog.IsActive {
-						activeFrogs = append(activeFrogs, frog.ID)
-					}
-				}
-
-				if len(activeFrogs) == 0 {
-					log.Printf("奖池 %d 没有活跃的青蛙", poolID)
-					now := time.Now()
-					pool.Status = model.PoolStatusCompleted
-					pool.CompletedAt = &now
-					if err := model.DB.Save(&pool).Error; err != nil {
-						log.Printf("更新奖池状态失败: %v", err)
-						return
-					}
-
-					// 获取WebSocket管理器并广播游戏结束
-					wsManager := GetWebSocketManager()
-					wsManager.BroadcastGameOver(pool.ID, "", 0)
-
-					// 停止当前奖池的更新器
-					s.StopUpdater(poolID)
-					return
-				}
-
-				// 如果所有活跃青蛙都出现过，重置记录
-				if len(appearedFrogs) >= len(activeFrogs) {
-					appearedFrogs = make(map[uint]bool)
-				}
-
-				// 从未出现过的青蛙中随机选择一个
-				var availableFrogs []uint
-				for _, frogID := range activeFrogs {
-					if !appearedFrogs[frogID] {
-						availableFrogs = append(availableFrogs, frogID)
-					}
-				}
-
-				if len(availableFrogs) == 0 {
-					continue
-				}
-
-				// 随机选择一个青蛙
-				selectedFrogID := availableFrogs[rand.Intn(len(availableFrogs))]
-				appearedFrogs[selectedFrogID] = true
-
-				// 获取选中青蛙的钱包地址
-				var selectedParticipant model.PoolParticipant
-				if err := model.DB.Where("pool_id = ? AND frog_id = ?", poolID, selectedFrogID).First(&selectedParticipant).Error; err != nil {
-					log.Printf("获取选中青蛙 %d 的参与者信息失败: %v", selectedFrogID, err)
-					continue
-				}
-
-				// 更新大奖位置
-				if err := pool.UpdateBigPrizeHolder(selectedParticipant.WalletAddress); err != nil {
-					log.Printf("更新奖池 %d 大奖位置失败: %v", poolID, err)
-					continue
-				}
-
-				log.Printf("奖池 %d 大奖位置已更新到青蛙 %d", poolID, selectedFrogID)
-				GetWebSocketManager().BroadcastBigPrizeLocation(poolID, selectedParticipant.WalletAddress)
-			}
+// prizeMoveDue 移动一次大奖并推迟到下一次移动，奖池已不是活跃状态时结束
+func (s *PrizeUpdaterService) prizeMoveDue(job *model.Job, payload poolJob) error {
+	// 移动大奖由奖池的执行协程处理，与投喂、衰减和抓取大奖依次执行
+	var done bool
+	err := s.engine.Do(payload.PoolID, "move-prize", func(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+		var events []event.Event
+		var err error
+		events, done, err = s.movePrize(tx, pool)
+		return events, err
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
 		}
-	}()
+		return err
+	}
+	if done {
+		log.Printf("奖池 %d 已不是活跃状态，停止移动大奖", payload.PoolID)
+		return nil
+	}
+	return RescheduleJob(time.Now().Add(prizeMoveInterval))
+}
+
+// appearedHolders 按奖池事件中的 PrizeMoved 记录恢复本轮已出现过大奖的钱包
+// 与逐次移动时相同：出现过的数量达到活跃青蛙数时开始新的一轮
+func appearedHolders(tx *repository.Repositories, poolID uint, active int) (map[string]bool, error) {
+	stored, err := tx.PoolEvents.ListByPool(poolID)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Printf("奖池 %d 的大奖位置更新器已启动", poolID)
+	appeared := make(map[string]bool)
+	for _, e := range stored {
+		if e.Type != event.NamePrizeMoved {
+			continue
+		}
+		decoded, err := event.Decode(e.Type, []byte(e.Payload))
+		if err != nil {
+			return nil, err
+		}
+		if len(appeared) >= active {
+			appeared = make(map[string]bool)
+		}
+		appeared[decoded.(event.PrizeMoved).Holder] = true
+	}
+	return appeared, nil
 }
 
-// StopUpdater 停止大奖位置更新器
-func (s *PrizeUpdaterService) StopUpdater(poolID uint) {
-	s.updatersMux.Lock()
-	defer s.updatersMux.Unlock()
+// movePrize 将大奖随机移动到一只本轮尚未持有过大奖的活跃青蛙，没有活跃的青蛙时结束奖池
+// done为true表示奖池已不是活跃状态，不再移动
+func (s *PrizeUpdaterService) movePrize(tx *repository.Repositories, pool *model.PrizePool) (events []event.Event, done bool, err error) {
+	// 奖池已不是活跃状态（结算中、已完成、已取消或有争议），不再移动
+	if pool.Status != model.PoolStatusActive {
+		return nil, true, nil
+	}
+
+	// 获取所有活跃的青蛙
+	participants, err := tx.Participants.ListByPool(pool.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var activeFrogs []uint
+	holders := make(map[uint]string)
+	for _, p := range participants {
+		frog, err := tx.Frogs.Get(p.FrogID)
+		if err != nil {
+			continue
+		}
+		if frog.IsActive {
+			activeFrogs = append(activeFrogs, frog.ID)
+			holders[frog.ID] = p.WalletAddress
+		}
+	}
+
+	if len(activeFrogs) == 0 {
+		log.Printf("奖池 %d 没有活跃的青蛙", pool.ID)
+		events, err := s.forfeit(tx, pool)
+		return events, true, err
+	}
+
+	// 如果所有活跃青蛙都出现过，重置记录
+	appeared, err := appearedHolders(tx, pool.ID, len(activeFrogs))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(appeared) >= len(activeFrogs) {
+		appeared = make(map[string]bool)
+	}
+
+	// 从未出现过的青蛙中随机选择一个
+	var availableFrogs []uint
+	for _, frogID := range activeFrogs {
+		if !appeared[holders[frogID]] {
+			availableFrogs = append(availableFrogs, frogID)
+		}
+	}
+	if len(availableFrogs) == 0 {
+		return nil, false, nil
+	}
+	selectedFrogID := availableFrogs[rand.Intn(len(availableFrogs))]
+
+	// 更新大奖位置
+	holder := holders[selectedFrogID]
+	if err := tx.Pools.MoveBigPrize(pool.ID, holder); err != nil {
+		if errors.Is(err, repository.ErrPoolNotActive) {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	pool.CurrentBigPrizeHolder = holder
+
+	log.Printf("奖池 %d 大奖位置已更新到青蛙 %d", pool.ID, selectedFrogID)
+	return []event.Event{event.PrizeMoved{PoolID: pool.ID, Holder: holder}}, false, nil
+}
 
-	if stopCh, exists := s.updaters[poolID]; exists {
-		close(stopCh)
-		delete(s.updaters, poolID)
-		log.Printf("奖池 %d 的大奖位置更新器已停止", poolID)
+// forfeit 结束没有活跃青蛙的奖池，奖金归平台，游戏结束消息经发件箱广播
+func (s *PrizeUpdaterService) forfeit(tx *repository.Repositories, pool *model.PrizePool) ([]event.Event, error) {
+	// 没有赢家
+	changed, err := transitionPool(tx, pool, model.PoolStatusCompleted, "no active frogs", 0)
+	if err != nil {
+		return nil, err
 	}
+	if err := s.rewards.using(tx).ForfeitPool(pool.ID); err != nil {
+		return nil, err
+	}
+
+	return []event.Event{changed, event.PoolCompleted{PoolID: pool.ID}}, nil
 }
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"os"
+	"singo/event"
+	"singo/metrics"
+	"singo/model"
+	"singo/repository"
+	"singo/serializer"
+	"singo/util"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultReconcileInterval 默认的对账间隔
+const defaultReconcileInterval = 10 * time.Minute
+
+// RewardService 奖励账本：每次游戏与提取操作都记一笔平衡的分录
+type RewardService struct {
+	users   repository.UserRepository
+	rewards repository.RewardRepository
+	repos   *repository.Repositories
+	relay   *OutboxRelay
+
+	// rakePercent 获胜奖金中平台抽成的百分比
+	rakePercent int64
+}
+
+// NewRewardService 创建奖励服务
+func NewRewardService(repos *repository.Repositories, relay *OutboxRelay) *RewardService {
+	return &RewardService{
+		users:       repos.Users,
+		rewards:     repos.Rewards,
+		repos:       repos,
+		relay:       relay,
+		rakePercent: houseRakePercent(),
+	}
+}
+
+// using 返回使用给定数据访问实现的副本，用于在事务中记账
+func (s *RewardService) using(repos *repository.Repositories) *RewardService {
+	tx := *s
+	tx.users = repos.Users
+	tx.rewards = repos.Rewards
+	tx.repos = repos
+	return &tx
+}
+
+// houseRakePercent 读取 HOUSE_RAKE_PERCENT，默认不抽成
+func houseRakePercent() int64 {
+	value := os.Getenv("HOUSE_RAKE_PERCENT")
+	if value == "" {
+		return 0
+	}
+	percent, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || percent < 0 || percent > 100 {
+		log.Printf("HOUSE_RAKE_PERCENT 配置无效: %s，不抽成", value)
+		return 0
+	}
+	return percent
+}
+
+// RecordActivation 玩家支付的激活费进入奖池
+func (s *RewardService) RecordActivation(poolID uint) error {
+	fee := model.ToLamports(RequiredAmount)
+	entry := model.NewLedgerEntry(model.EntryActivation, model.PoolReference(poolID)).
+		Post(model.AccountTreasury, 0, -fee).
+		Post(model.AccountPoolPrize, poolID, fee)
+	return s.rewards.Post(entry)
+}
+
+// RecordRefundable 玩家已有激活的青蛙时，本次支付的激活费记为待退还
+func (s *RewardService) RecordRefundable(userID uint) error {
+	fee := model.ToLamports(RequiredAmount)
+	entry := model.NewLedgerEntry(model.EntryRefundable, "user:"+strconv.FormatUint(uint64(userID), 10)).
+		Post(model.AccountTreasury, 0, -fee).
+		Post(model.AccountUserRefund, userID, fee)
+	return s.rewards.Post(entry)
+}
+
+// AwardPrize 奖池奖金扣除抽成后计入获胜者的未领取奖励，返回获胜者实得金额(SOL)
+func (s *RewardService) AwardPrize(user *model.User, pool *model.PrizePool) (float64, error) {
+	prize := model.ToLamports(pool.PrizeAmount)
+	rake := prize * s.rakePercent / 100
+	reward := prize - rake
+
+	entry := model.NewLedgerEntry(model.EntryPrize, model.PoolReference(pool.ID)).
+		Post(model.AccountPoolPrize, pool.ID, -prize).
+		Post(model.AccountUserUnclaimed, user.ID, reward).
+		Post(model.AccountHouseRake, 0, rake)
+	if err := s.rewards.Post(entry); err != nil {
+		return 0, err
+	}
+
+	user.UnclaimedRewards += model.ToSOL(reward)
+	return model.ToSOL(reward), nil
+}
+
+// ForfeitPool 奖池无人获胜而结束时，剩余奖金归平台
+func (s *RewardService) ForfeitPool(poolID uint) error {
+	balance, err := s.rewards.Balance(model.AccountPoolPrize, poolID)
+	if err != nil {
+		return err
+	}
+	if balance <= 0 {
+		return nil
+	}
+
+	entry := model.NewLedgerEntry(model.EntryForfeit, model.PoolReference(poolID)).
+		Post(model.AccountPoolPrize, poolID, -balance).
+		Post(model.AccountHouseRake, 0, balance)
+	return s.rewards.Post(entry)
+}
+
+// RefundPool 奖池未开始即取消时，将奖池中的激活费退还给各参与者，记为待退还
+func (s *RewardService) RefundPool(poolID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	fee := model.ToLamports(RequiredAmount)
+	entry := model.NewLedgerEntry(model.EntryPoolRefund, model.PoolReference(poolID)).
+		Post(model.AccountPoolPrize, poolID, -fee*int64(len(userIDs)))
+	for _, userID := range userIDs {
+		entry.Post(model.AccountUserRefund, userID, fee)
+	}
+	return s.rewards.Post(entry)
+}
+
+// Settle 奖励提取成功后，将金额从未领取转入已支付，同时记录 RewardPaid 事件
+// 分录以链上交易签名关联，结算重试据此判断是否已经结算
+func (s *RewardService) Settle(user *model.User, amount float64, txHash string) error {
+	lamports := model.ToLamports(amount)
+	entry := model.NewLedgerEntry(model.EntryClaim, model.PayoutReference(txHash)).
+		Post(model.AccountUserUnclaimed, user.ID, -lamports).
+		Post(model.AccountUserPaid, user.ID, lamports)
+	err := withEvents(s.repos, s.relay, func(tx *repository.Repositories) ([]event.Event, error) {
+		if err := tx.Rewards.Post(entry); err != nil {
+			return nil, err
+		}
+		return []event.Event{event.RewardPaid{UserID: user.ID, Amount: model.ToSOL(lamports)}}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	user.UnclaimedRewards -= model.ToSOL(lamports)
+	user.HistoryRewards += model.ToSOL(lamports)
+	return nil
+}
+
+// BalanceDrift 用户缓存的余额与账本不一致
+type BalanceDrift struct {
+	UserID        uint                `json:"userId"`
+	WalletAddress string              `json:"walletAddress"`
+	Account       model.LedgerAccount `json:"account"`
+	Cached        int64               `json:"cachedLamports"`
+	Ledger        int64               `json:"ledgerLamports"`
+}
+
+// PoolDrift 奖池的奖金余额不合理：余额为负，或奖池已完成、已取消仍有余额
+type PoolDrift struct {
+	PoolID uint             `json:"poolId"`
+	Status model.PoolStatus `json:"status"`
+	Ledger int64            `json:"ledgerLamports"`
+}
+
+// Reconciliation 对账结果
+type Reconciliation struct {
+	CheckedAt         time.Time      `json:"checkedAt"`
+	UsersChecked      int            `json:"usersChecked"`
+	Imbalance         int64          `json:"imbalanceLamports"` // 全部记账之和，正常为0
+	UnbalancedEntries []uint         `json:"unbalancedEntries"` // 记账之和不为0的分录
+	Drifts            []BalanceDrift `json:"drifts"`
+	PoolDrifts        []PoolDrift    `json:"poolDrifts"`
+}
+
+// OK 账本平衡且没有余额偏差
+func (r *Reconciliation) OK() bool {
+	return r.Imbalance == 0 && len(r.UnbalancedEntries) == 0 && len(r.Drifts) == 0 && len(r.PoolDrifts) == 0
+}
+
+// Reconcile 核对用户缓存的未领取奖励、历史收益与账本余额
+func (s *RewardService) Reconcile() (*Reconciliation, error) {
+	result := &Reconciliation{
+		CheckedAt:         time.Now(),
+		UnbalancedEntries: []uint{},
+		Drifts:            []BalanceDrift{},
+		PoolDrifts:        []PoolDrift{},
+	}
+
+	total, err := s.rewards.Total()
+	if err != nil {
+		return nil, err
+	}
+	result.Imbalance = total
+
+	// 各分录写入时都已校验平衡，总和不为0说明有分录被绕过账本修改，逐条找出
+	unbalanced, err := s.rewards.UnbalancedEntries()
+	if err != nil {
+		return nil, err
+	}
+	result.UnbalancedEntries = append(result.UnbalancedEntries, unbalanced...)
+
+	if err := s.reconcilePools(result); err != nil {
+		return nil, err
+	}
+
+	unclaimed, err := s.rewards.Balances(model.AccountUserUnclaimed)
+	if err != nil {
+		return nil, err
+	}
+	paid, err := s.rewards.Balances(model.AccountUserPaid)
+	if err != nil {
+		return nil, err
+	}
+
+	// 有缓存余额的用户与账本中出现过的用户都需要核对
+	users, err := s.users.ListWithRewards()
+	if err != nil {
+		return nil, err
+	}
+	checked := make(map[uint]bool, len(users))
+	for _, user := range users {
+		checked[user.ID] = true
+	}
+	for _, balances := range []map[uint]int64{unclaimed, paid} {
+		for userID, balance := range balances {
+			if checked[userID] || balance == 0 {
+				continue
+			}
+			checked[userID] = true
+			user, err := s.users.Get(userID)
+			if err != nil {
+				if errors.Is(err, repository.ErrNotFound) {
+					user = &model.User{}
+					user.ID = userID
+				} else {
+					return nil, err
+				}
+			}
+			users = append(users, *user)
+		}
+	}
+
+	for _, user := range users {
+		if cached := model.ToLamports(user.UnclaimedRewards); cached != unclaimed[user.ID] {
+			result.Drifts = append(result.Drifts, BalanceDrift{
+				UserID:        user.ID,
+				WalletAddress: user.WalletAddress,
+				Account:       model.AccountUserUnclaimed,
+				Cached:        cached,
+				Ledger:        unclaimed[user.ID],
+			})
+		}
+		if cached := model.ToLamports(user.HistoryRewards); cached != paid[user.ID] {
+			result.Drifts = append(result.Drifts, BalanceDrift{
+				UserID:        user.ID,
+				WalletAddress: user.WalletAddress,
+				Account:       model.AccountUserPaid,
+				Cached:        cached,
+				Ledger:        paid[user.ID],
+			})
+		}
+	}
+	result.UsersChecked = len(users)
+
+	return result, nil
+}
+
+// reconcilePools 核对各奖池的奖金余额
+// 奖金只会从奖池转出到获胜者、平台或退款，余额不能为负；完成或取消的奖池应已全部转出
+func (s *RewardService) reconcilePools(result *Reconciliation) error {
+	balances, err := s.rewards.Balances(model.AccountPoolPrize)
+	if err != nil {
+		return err
+	}
+	poolIDs := make([]uint, 0, len(balances))
+	for poolID, balance := range balances {
+		if balance != 0 {
+			poolIDs = append(poolIDs, poolID)
+		}
+	}
+	sort.Slice(poolIDs, func(i, j int) bool { return poolIDs[i] < poolIDs[j] })
+
+	for _, poolID := range poolIDs {
+		balance := balances[poolID]
+		var status model.PoolStatus
+		pool, err := s.repos.Pools.Get(poolID)
+		if err == nil {
+			status = pool.Status
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if balance < 0 || status == "" || status == model.PoolStatusCompleted || status == model.PoolStatusCancelled {
+			result.PoolDrifts = append(result.PoolDrifts, PoolDrift{PoolID: poolID, Status: status, Ledger: balance})
+		}
+	}
+	return nil
+}
+
+// reconcileAndReport 执行一次对账并记录结果
+func (s *RewardService) reconcileAndReport() {
+	result, err := s.Reconcile()
+	if err != nil {
+		log.Printf("对账失败: %v", err)
+		return
+	}
+
+	metrics.LedgerImbalance.Set(result.Imbalance)
+	metrics.LedgerUnbalancedEntries.Set(int64(len(result.UnbalancedEntries)))
+	metrics.LedgerDrifts.Set(int64(len(result.Drifts)))
+	metrics.LedgerPoolDrifts.Set(int64(len(result.PoolDrifts)))
+	if result.OK() {
+		return
+	}
+
+	metrics.LedgerReconcileFailures.Add(1)
+	if result.Imbalance != 0 {
+		log.Printf("账本不平衡: 全部记账之和为 %d lamports", result.Imbalance)
+	}
+	if len(result.UnbalancedEntries) > 0 {
+		log.Printf("账本不平衡: 分录 %v 的记账之和不为0", result.UnbalancedEntries)
+	}
+	for _, d := range result.Drifts {
+		log.Printf("用户 %d 的 %s 余额与账本不一致: 缓存 %d, 账本 %d (lamports)",
+			d.UserID, d.Account, d.Cached, d.Ledger)
+	}
+	for _, d := range result.PoolDrifts {
+		log.Printf("奖池 %d (%s) 的奖金余额异常: %d lamports", d.PoolID, d.Status, d.Ledger)
+	}
+	util.Log().Error("对账未通过: 请检查账本，最近一次对账时间 %s", result.CheckedAt.Format(time.RFC3339))
+}
+
+// StartReconcileWorker 定期对账，间隔由 LEDGER_RECONCILE_INTERVAL 配置
+func (s *RewardService) StartReconcileWorker() {
+	interval := defaultReconcileInterval
+	if value := os.Getenv("LEDGER_RECONCILE_INTERVAL"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("LEDGER_RECONCILE_INTERVAL 配置无效: %s，使用默认值 %v", value, interval)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		s.reconcileAndReport()
+		for range ticker.C {
+			s.reconcileAndReport()
+		}
+	}()
+}
+
+// LedgerReconcileService 手动触发对账
+type LedgerReconcileService struct{}
+
+// Reconcile 执行对账并返回偏差
+func (service *LedgerReconcileService) Reconcile() serializer.Response {
+	result, err := GetRewardService().Reconcile()
+	if err != nil {
+		return serializer.DBErr("Failed to reconcile ledger", err)
+	}
+
+	res := serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"ok":             result.OK(),
+			"reconciliation": result,
+		},
+	}
+	if !result.OK() {
+		res.Code = serializer.CodeLedgerMismatch
+		res.Msg = "Ledger does not reconcile"
+	}
+	return res
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"singo/model"
+	"singo/repository"
+	"testing"
+)
+
+// 激活、获胜与提取都记平衡的分录，对账无偏差
+func TestRewardLedgerRound(t *testing.T) {
+	rewards := GetRewardService()
+
+	winner := &model.User{WalletAddress: "ledger-winner"}
+	testRepos.Users.Create(winner)
+
+	pool := model.NewPool()
+	testRepos.Pools.Create(&pool)
+	for i := 0; i < model.MaxPoolPlayers; i++ {
+		if err := rewards.RecordActivation(pool.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reward, err := rewards.AwardPrize(winner, &pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reward != pool.PrizeAmount {
+		t.Fatalf("reward = %v, want %v", reward, pool.PrizeAmount)
+	}
+
+	// 10 笔激活费正好覆盖奖金
+	if balance, _ := testRepos.Rewards.Balance(model.AccountPoolPrize, pool.ID); balance != 0 {
+		t.Fatalf("pool prize balance = %d", balance)
+	}
+
+	if err := rewards.Settle(winner, reward, "ledger-round-tx"); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := testRepos.Users.Get(winner.ID)
+	if saved.UnclaimedRewards != 0 || saved.HistoryRewards != reward {
+		t.Fatalf("cached balances = %v / %v", saved.UnclaimedRewards, saved.HistoryRewards)
+	}
+	if paid, _ := testRepos.Rewards.Balance(model.AccountUserPaid, winner.ID); paid != model.ToLamports(reward) {
+		t.Fatalf("user paid balance = %d", paid)
+	}
+
+	result, err := rewards.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range result.Drifts {
+		if d.UserID == winner.ID {
+			t.Fatalf("unexpected drift: %+v", d)
+		}
+	}
+	if result.Imbalance != 0 {
+		t.Fatalf("ledger imbalance = %d", result.Imbalance)
+	}
+}
+
+// 无人获胜的奖池，剩余奖金转入平台
+func TestRewardLedgerForfeit(t *testing.T) {
+	rewards := GetRewardService()
+
+	pool := model.NewPool()
+	testRepos.Pools.Create(&pool)
+	rewards.RecordActivation(pool.ID)
+	rewards.RecordActivation(pool.ID)
+
+	before, _ := testRepos.Rewards.Balance(model.AccountHouseRake, 0)
+	if err := rewards.ForfeitPool(pool.ID); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := testRepos.Rewards.Balance(model.AccountHouseRake, 0)
+
+	if after-before != 2*model.ToLamports(RequiredAmount) {
+		t.Fatalf("house rake increased by %d", after-before)
+	}
+	if balance, _ := testRepos.Rewards.Balance(model.AccountPoolPrize, pool.ID); balance != 0 {
+		t.Fatalf("pool prize balance = %d", balance)
+	}
+}
+
+// 绕过账本修改的余额会在对账时报告
+func TestRewardLedgerDrift(t *testing.T) {
+	user := &model.User{WalletAddress: "ledger-drift", UnclaimedRewards: 1}
+	testRepos.Users.Create(user)
+
+	result, err := GetRewardService().Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range result.Drifts {
+		if d.UserID == user.ID && d.Account == model.AccountUserUnclaimed &&
+			d.Cached == model.LamportsPerSOL && d.Ledger == 0 {
+			return
+		}
+	}
+	t.Fatalf("drift not reported: %+v", result.Drifts)
+}
+
+// 分录金额之和不为0时拒绝写入
+func TestLedgerEntryUnbalanced(t *testing.T) {
+	entry := model.NewLedgerEntry(model.EntryPrize, "pool:0").
+		Post(model.AccountPoolPrize, 1, -10).
+		Post(model.AccountHouseRake, 0, 9)
+	if err := testRepos.Rewards.Post(entry); err == nil {
+		t.Fatal("expected unbalanced entry to be rejected")
+	}
+}
+
+// 已取消的奖池仍有奖金时对账未通过
+func TestRewardLedgerPoolDrift(t *testing.T) {
+	repos := repository.NewMemory()
+	rewards := NewRewardService(repos, nil)
+
+	pool := model.NewPool()
+	repos.Pools.Create(&pool)
+	rewards.RecordActivation(pool.ID)
+	result, err := rewards.Reconcile()
+	if err != nil || !result.OK() {
+		t.Fatalf("open pool: %+v (%v)", result, err)
+	}
+
+	if _, err := repos.Pools.Transition(&pool, model.PoolStatusCancelled, "test", 0); err != nil {
+		t.Fatal(err)
+	}
+	result, err = rewards.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OK() || len(result.PoolDrifts) != 1 || result.PoolDrifts[0].PoolID != pool.ID ||
+		result.PoolDrifts[0].Ledger != model.ToLamports(RequiredAmount) {
+		t.Fatalf("pool drift not reported: %+v", result.PoolDrifts)
+	}
+}
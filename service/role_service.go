@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"os"
+	"singo/auth"
+	"singo/model"
+	"singo/repository"
+	"singo/serializer"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserRoleService 修改用户角色的服务
+type UserRoleService struct {
+	Role   string `form:"role" json:"role" binding:"required"`
+	Reason string `form:"reason" json:"reason" binding:"max=255"`
+}
+
+// RoleAuditService 查询权限变更记录的服务
+type RoleAuditService struct {
+	WalletAddress string `form:"walletAddress" json:"walletAddress"`
+	Limit         int    `form:"limit" json:"limit"`
+}
+
+// BootstrapAdminWallets 将ADMIN_WALLETS中配置的钱包设为管理员
+// 用户不存在时会先创建，角色变更同样记录审计
+func BootstrapAdminWallets() {
+	users := GetUserService()
+	for _, wallet := range strings.Split(os.Getenv("ADMIN_WALLETS"), ",") {
+		wallet = strings.TrimSpace(wallet)
+		if wallet == "" {
+			continue
+		}
+
+		user, _, err := users.GetOrCreate(wallet)
+		if err != nil {
+			log.Printf("获取管理员钱包 %s 失败: %v", wallet, err)
+			continue
+		}
+
+		if user.Role == string(auth.RoleAdmin) {
+			continue
+		}
+		if err := users.ChangeRole(user, string(auth.RoleAdmin), nil, "bootstrap from ADMIN_WALLETS"); err != nil {
+			log.Printf("设置钱包 %s 为管理员失败: %v", wallet, err)
+			continue
+		}
+		log.Printf("钱包 %s 已根据配置设为管理员", wallet)
+	}
+}
+
+// Update 修改指定钱包的角色
+func (service *UserRoleService) Update(actor *model.User, walletAddress string) serializer.Response {
+	if !auth.ValidRole(service.Role) {
+		return serializer.ParamErr("Invalid role", nil)
+	}
+	if actor.WalletAddress == walletAddress {
+		return serializer.Err(serializer.CodeNoRightErr, "Cannot change your own role", nil)
+	}
+
+	users := GetUserService()
+	user, err := users.GetByWallet(walletAddress)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return serializer.ParamErr("User not found", err)
+		}
+		return serializer.DBErr("Failed to get user", err)
+	}
+
+	if user.Role != service.Role {
+		if err := users.ChangeRole(user, service.Role, actor, service.Reason); err != nil {
+			return serializer.DBErr("Failed to change role", err)
+		}
+		log.Printf("用户 %d 将钱包 %s 的角色修改为 %s", actor.ID, walletAddress, service.Role)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"walletAddress": user.WalletAddress,
+			"role":          user.Role,
+			"permissions":   auth.Permissions(user.Role),
+		},
+	}
+}
+
+// List 查询权限变更记录
+func (service *RoleAuditService) List() serializer.Response {
+	limit := service.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	var targetUserID uint
+	if service.WalletAddress != "" {
+		user, err := GetUserService().GetByWallet(service.WalletAddress)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return serializer.ParamErr("User not found", err)
+			}
+			return serializer.DBErr("Failed to get user", err)
+		}
+		targetUserID = user.ID
+	}
+
+	logs, err := GetUserService().RoleAudits(targetUserID, limit)
+	if err != nil {
+		return serializer.DBErr("Failed to get audit logs", err)
+	}
+
+	var data []gin.H
+	for _, l := range logs {
+		data = append(data, gin.H{
+			"id":           l.ID,
+			"targetWallet": l.TargetWallet,
+			"oldRole":      l.OldRole,
+			"newRole":      l.NewRole,
+			"actorWallet":  l.ActorWallet,
+			"reason":       l.Reason,
+			"createdAt":    l.CreatedAt.Unix(),
+		})
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"logs": data,
+		},
+	}
+}
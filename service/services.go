@@ -0,0 +1,63 @@
+package service
+
+import "singo/repository"
+
+var (
+	wsManager     *WebSocketManager
+	prizeUpdater  *PrizeUpdaterService
+	gameService   *GameService
+	poolService   *PoolService
+	rewardService *RewardService
+	outboxRelay   *OutboxRelay
+	poolEngine    *PoolEngine
+	jobQueue      *JobQueue
+	gameJobs      *GameJobs
+	userService   *UserService
+)
+
+// Init 使用给定的数据访问实现构造各服务实例，须在启动路由与工作器之前调用
+func Init(repos *repository.Repositories) {
+	// 重复初始化时先取消旧实例的事件订阅
+	if wsManager != nil {
+		wsManager.UnsubscribeEvents()
+	}
+	if poolService != nil {
+		poolService.UnsubscribeEvents()
+	}
+
+	userService = NewUserService(repos)
+	outboxRelay = NewOutboxRelay(repos)
+	jobQueue = NewJobQueue(repos)
+	rewardService = NewRewardService(repos, outboxRelay)
+	poolEngine = NewPoolEngine(repos, outboxRelay)
+	wsManager = NewWebSocketManager(repos, rewardService, poolEngine)
+	prizeUpdater = NewPrizeUpdaterService(repos, rewardService, poolEngine)
+	gameJobs = NewGameJobs(repos, wsManager, rewardService, poolEngine)
+	gameService = NewGameService(repos, wsManager, rewardService, poolEngine, gameJobs)
+	poolService = NewPoolService(repos, wsManager, rewardService, poolEngine)
+
+	wsManager.SubscribeEvents()
+	poolService.SubscribeEvents()
+	gameJobs.Register(jobQueue)
+	prizeUpdater.Register(jobQueue)
+}
+
+// GetGameService 获取游戏服务实例
+func GetGameService() *GameService {
+	return gameService
+}
+
+// GetUserService 获取用户服务实例
+func GetUserService() *UserService {
+	return userService
+}
+
+// GetPoolService 获取奖池查询服务实例
+func GetPoolService() *PoolService {
+	return poolService
+}
+
+// GetRewardService 获取奖励服务实例
+func GetRewardService() *RewardService {
+	return rewardService
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// siwsVersion 当前支持的SIWS消息版本
+	siwsVersion = "1"
+	// siwsMaxValidity 签名消息的最长有效期
+	siwsMaxValidity = 10 * time.Minute
+	// siwsClockSkew 允许的客户端时钟偏差
+	siwsClockSkew = 30 * time.Second
+	// siwsHeaderSuffix 消息首行中域名之后的固定文本
+	siwsHeaderSuffix = " wants you to sign in with your Solana account:"
+)
+
+// SIWSMessage Sign-In With Solana 结构化登录消息
+//
+// 消息格式：
+//
+//	{domain} wants you to sign in with your Solana account:
+//	{address}
+//
+//	{statement}
+//
+//	URI: {uri}
+//	Version: 1
+//	Chain ID: {chainId}
+//	Nonce: {nonce}
+//	Issued At: {issuedAt}
+//	Expiration Time: {expirationTime}
+type SIWSMessage struct {
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        string
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime time.Time
+}
+
+// SIWSConfig 服务端期望的SIWS参数
+type SIWSConfig struct {
+	Domain  string
+	URI     string
+	ChainID string
+}
+
+// ErrSIWSNotConfigured 未配置SIWS域名或URI，无法校验登录消息的来源
+var ErrSIWSNotConfigured = errors.New("SIWS_DOMAIN and SIWS_URI must be configured")
+
+// siwsStatement 登录消息中的说明文字
+const siwsStatement = "Sign in to Pizza Miner."
+
+// loadSIWSConfig 从环境变量读取SIWS配置
+func loadSIWSConfig() SIWSConfig {
+	chainID := os.Getenv("SIWS_CHAIN_ID")
+	if chainID == "" {
+		chainID = currentNetwork
+	}
+	return SIWSConfig{
+		Domain:  os.Getenv("SIWS_DOMAIN"),
+		URI:     os.Getenv("SIWS_URI"),
+		ChainID: chainID,
+	}
+}
+
+// Check 校验配置完整，域名与URI缺一不可
+func (c SIWSConfig) Check() error {
+	if c.Domain == "" || c.URI == "" {
+		return ErrSIWSNotConfigured
+	}
+	return nil
+}
+
+// CheckSIWSConfig 启动时校验SIWS配置，未配置时拒绝启动
+func CheckSIWSConfig() error {
+	return loadSIWSConfig().Check()
+}
+
+// String 按标准格式输出消息文本
+func (m *SIWSMessage) String() string {
+	var b strings.Builder
+	b.WriteString(m.Domain + siwsHeaderSuffix + "\n")
+	b.WriteString(m.Address + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n\n")
+	}
+	b.WriteString("URI: " + m.URI + "\n")
+	b.WriteString("Version: " + m.Version + "\n")
+	b.WriteString("Chain ID: " + m.ChainID + "\n")
+	b.WriteString("Nonce: " + m.Nonce + "\n")
+	b.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339) + "\n")
+	b.WriteString("Expiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339))
+	return b.String()
+}
+
+// ParseSIWSMessage 解析SIWS消息，兼容\r\n换行
+func ParseSIWSMessage(raw string) (*SIWSMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	if len(lines) < 3 {
+		return nil, errors.New("message too short")
+	}
+
+	msg := &SIWSMessage{}
+
+	// 首行：域名
+	if !strings.HasSuffix(lines[0], siwsHeaderSuffix) {
+		return nil, errors.New("invalid message header")
+	}
+	msg.Domain = strings.TrimSuffix(lines[0], siwsHeaderSuffix)
+
+	// 第二行：钱包地址
+	msg.Address = lines[1]
+
+	// 空行之后为可选的说明文字，直到第一个字段行
+	i := 2
+	if lines[i] != "" {
+		return nil, errors.New("missing blank line after address")
+	}
+	i++
+	if i < len(lines) && !strings.HasPrefix(lines[i], "URI: ") {
+		msg.Statement = lines[i]
+		i++
+		if i >= len(lines) || lines[i] != "" {
+			return nil, errors.New("missing blank line after statement")
+		}
+		i++
+	}
+
+	// 字段按固定顺序出现，缺一不可
+	fields := []struct {
+		prefix string
+		target *string
+	}{
+		{"URI: ", &msg.URI},
+		{"Version: ", &msg.Version},
+		{"Chain ID: ", &msg.ChainID},
+		{"Nonce: ", &msg.Nonce},
+	}
+	for _, field := range fields {
+		if i >= len(lines) || !strings.HasPrefix(lines[i], field.prefix) {
+			return nil, fmt.Errorf("missing field %q", strings.TrimSuffix(field.prefix, ": "))
+		}
+		*field.target = strings.TrimPrefix(lines[i], field.prefix)
+		i++
+	}
+
+	times := []struct {
+		prefix string
+		target *time.Time
+	}{
+		{"Issued At: ", &msg.IssuedAt},
+		{"Expiration Time: ", &msg.ExpirationTime},
+	}
+	for _, field := range times {
+		if i >= len(lines) || !strings.HasPrefix(lines[i], field.prefix) {
+			return nil, fmt.Errorf("missing field %q", strings.TrimSuffix(field.prefix, ": "))
+		}
+		t, err := time.Parse(time.RFC3339, strings.TrimPrefix(lines[i], field.prefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", strings.TrimSuffix(field.prefix, ": "), err)
+		}
+		*field.target = t
+		i++
+	}
+
+	// 不允许多余内容
+	for ; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			return nil, errors.New("unexpected trailing content")
+		}
+	}
+
+	return msg, nil
+}
+
+// Validate 校验消息的每个字段是否与服务端期望一致，配置不完整时拒绝所有消息
+func (m *SIWSMessage) Validate(config SIWSConfig, walletAddress string, now time.Time) error {
+	if err := config.Check(); err != nil {
+		return err
+	}
+	if m.Domain != config.Domain {
+		return fmt.Errorf("domain mismatch: %s", m.Domain)
+	}
+	if m.Address != walletAddress {
+		return errors.New("address does not match wallet")
+	}
+	if m.URI != config.URI {
+		return fmt.Errorf("uri mismatch: %s", m.URI)
+	}
+	if m.Version != siwsVersion {
+		return fmt.Errorf("unsupported version: %s", m.Version)
+	}
+	if m.ChainID != config.ChainID {
+		return fmt.Errorf("chain id mismatch: %s", m.ChainID)
+	}
+	if m.Nonce == "" {
+		return errors.New("nonce is empty")
+	}
+	if m.IssuedAt.After(now.Add(siwsClockSkew)) {
+		return errors.New("message issued in the future")
+	}
+	if !m.ExpirationTime.After(now) {
+		return errors.New("message expired")
+	}
+	if !m.ExpirationTime.After(m.IssuedAt) || m.ExpirationTime.Sub(m.IssuedAt) > siwsMaxValidity {
+		return errors.New("invalid validity window")
+	}
+	return nil
+}
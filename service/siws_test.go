@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"singo/cache"
+	"singo/serializer"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+var siwsTestConfig = SIWSConfig{Domain: "localhost:3000", URI: "http://localhost:3000", ChainID: "devnet"}
+
+// newSIWSMessage 构造符合siwsTestConfig的有效消息
+func newSIWSMessage(now time.Time) SIWSMessage {
+	return SIWSMessage{
+		Domain:         siwsTestConfig.Domain,
+		Address:        "siws-wallet",
+		Statement:      siwsStatement,
+		URI:            siwsTestConfig.URI,
+		Version:        siwsVersion,
+		ChainID:        siwsTestConfig.ChainID,
+		Nonce:          "0123456789abcdef",
+		IssuedAt:       now.UTC().Truncate(time.Second),
+		ExpirationTime: now.UTC().Truncate(time.Second).Add(nonceTTL),
+	}
+}
+
+// 消息文本可以解析回相同的字段，兼容\r\n换行与省略说明文字
+func TestParseSIWSMessage(t *testing.T) {
+	want := newSIWSMessage(time.Now())
+	noStatement := want
+	noStatement.Statement = ""
+
+	for name, raw := range map[string]string{
+		"standard":     want.String(),
+		"crlf":         strings.ReplaceAll(want.String(), "\n", "\r\n"),
+		"no statement": noStatement.String(),
+	} {
+		got, err := ParseSIWSMessage(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if name == "no statement" {
+			if *got != noStatement {
+				t.Fatalf("%s: got %+v", name, got)
+			}
+		} else if *got != want {
+			t.Fatalf("%s: got %+v", name, got)
+		}
+	}
+}
+
+// 格式不完整或有多余内容的消息无法解析
+func TestParseSIWSMessageMalformed(t *testing.T) {
+	msg := newSIWSMessage(time.Now())
+	valid := msg.String()
+	lines := strings.Split(valid, "\n")
+	without := func(prefix string) string {
+		var kept []string
+		for _, line := range lines {
+			if !strings.HasPrefix(line, prefix) {
+				kept = append(kept, line)
+			}
+		}
+		return strings.Join(kept, "\n")
+	}
+
+	cases := map[string]string{
+		"empty":              "",
+		"too short":          lines[0] + "\n" + lines[1],
+		"bad header":         strings.Replace(valid, "wants you to sign in", "wants you to log in", 1),
+		"no blank line":      lines[0] + "\n" + lines[1] + "\n" + strings.Join(lines[3:], "\n"),
+		"missing uri":        without("URI: "),
+		"missing nonce":      without("Nonce: "),
+		"missing expiration": without("Expiration Time: "),
+		"fields reordered":   strings.Replace(strings.Replace(valid, "Version: ", "X: ", 1), "Chain ID: ", "Version: ", 1),
+		"bad issued at":      strings.Replace(valid, lines[len(lines)-2], "Issued At: yesterday", 1),
+		"trailing content":   valid + "\nResources: https://evil.example",
+	}
+	for name, raw := range cases {
+		if _, err := ParseSIWSMessage(raw); err == nil {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+
+	// 末尾的空行可以忽略
+	if _, err := ParseSIWSMessage(valid + "\n\n"); err != nil {
+		t.Fatalf("trailing newline: %v", err)
+	}
+}
+
+// 任一字段与服务端期望不一致或不在有效期内时拒绝登录
+func TestSIWSValidate(t *testing.T) {
+	now := time.Now()
+	if msg := newSIWSMessage(now); msg.Validate(siwsTestConfig, "siws-wallet", now) != nil {
+		t.Fatalf("valid message rejected: %v", msg.Validate(siwsTestConfig, "siws-wallet", now))
+	}
+
+	cases := map[string]func(m *SIWSMessage){
+		"wrong domain":   func(m *SIWSMessage) { m.Domain = "evil.example" },
+		"wrong uri":      func(m *SIWSMessage) { m.URI = "https://evil.example" },
+		"wrong address":  func(m *SIWSMessage) { m.Address = "other-wallet" },
+		"wrong version":  func(m *SIWSMessage) { m.Version = "2" },
+		"wrong chain":    func(m *SIWSMessage) { m.ChainID = "mainnet" },
+		"empty nonce":    func(m *SIWSMessage) { m.Nonce = "" },
+		"expired":        func(m *SIWSMessage) { m.IssuedAt, m.ExpirationTime = now.Add(-time.Hour), now.Add(-time.Second) },
+		"not yet valid":  func(m *SIWSMessage) { m.IssuedAt, m.ExpirationTime = now.Add(time.Minute), now.Add(2*time.Minute) },
+		"window too big": func(m *SIWSMessage) { m.ExpirationTime = m.IssuedAt.Add(siwsMaxValidity + time.Second) },
+		"inverted":       func(m *SIWSMessage) { m.IssuedAt = m.ExpirationTime.Add(-siwsMaxValidity - time.Hour) },
+	}
+	for name, mutate := range cases {
+		msg := newSIWSMessage(now)
+		mutate(&msg)
+		if err := msg.Validate(siwsTestConfig, "siws-wallet", now); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+
+	// 时钟偏差范围内的签发时间可以接受
+	msg := newSIWSMessage(now.Add(siwsClockSkew / 2))
+	if err := msg.Validate(siwsTestConfig, "siws-wallet", now); err != nil {
+		t.Fatalf("within clock skew: %v", err)
+	}
+}
+
+// 未配置域名或URI时拒绝所有消息，即使消息中的字段同样为空
+func TestSIWSValidateFailsClosed(t *testing.T) {
+	now := time.Now()
+	for name, config := range map[string]SIWSConfig{
+		"no domain": {URI: siwsTestConfig.URI, ChainID: siwsTestConfig.ChainID},
+		"no uri":    {Domain: siwsTestConfig.Domain, ChainID: siwsTestConfig.ChainID},
+	} {
+		msg := newSIWSMessage(now)
+		msg.Domain, msg.URI = config.Domain, config.URI
+		if err := msg.Validate(config, "siws-wallet", now); !errors.Is(err, ErrSIWSNotConfigured) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	t.Setenv("SIWS_DOMAIN", "")
+	t.Setenv("SIWS_URI", siwsTestConfig.URI)
+	if err := CheckSIWSConfig(); !errors.Is(err, ErrSIWSNotConfigured) {
+		t.Fatalf("startup check: %v", err)
+	}
+	service := AuthNonceService{WalletAddress: "siws-wallet"}
+	if res := service.Issue(nil); res.Code != serializer.CodeServiceUnavailable {
+		t.Fatalf("issue without config: %+v", res)
+	}
+}
+
+// nonce只能由签发时的钱包使用一次，未知的nonce无效
+func TestConsumeNonce(t *testing.T) {
+	server := miniredis.RunT(t)
+	previous := cache.RedisClient
+	cache.RedisClient = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer func() { cache.RedisClient = previous }()
+	t.Setenv("SIWS_DOMAIN", siwsTestConfig.Domain)
+	t.Setenv("SIWS_URI", siwsTestConfig.URI)
+
+	issue := func() string {
+		service := AuthNonceService{WalletAddress: "siws-wallet"}
+		res := service.Issue(nil)
+		if res.Code != 0 {
+			t.Fatalf("issue: %+v", res)
+		}
+		data := res.Data.(gin.H)
+		msg, err := ParseSIWSMessage(data["message"].(string))
+		if err != nil || msg.Nonce != data["nonce"] {
+			t.Fatalf("issued message: %+v (%v)", msg, err)
+		}
+		return msg.Nonce
+	}
+
+	if err := consumeNonce("unknown", "siws-wallet"); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("unknown nonce: %v", err)
+	}
+
+	nonce := issue()
+	if err := consumeNonce(nonce, "siws-wallet"); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := consumeNonce(nonce, "siws-wallet"); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("reused nonce: %v", err)
+	}
+
+	// 其他钱包使用后nonce同样作废，原钱包也不能再用
+	nonce = issue()
+	if err := consumeNonce(nonce, "other-wallet"); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("other wallet: %v", err)
+	}
+	if err := consumeNonce(nonce, "siws-wallet"); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("nonce survived a foreign attempt: %v", err)
+	}
+
+	// 过期的nonce无效
+	nonce = issue()
+	server.FastForward(nonceTTL + time.Second)
+	if err := consumeNonce(nonce, "siws-wallet"); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("expired nonce: %v", err)
+	}
+}
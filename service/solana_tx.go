@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mr-tron/base58"
+)
+
+const (
+	// MemoProgramID SPL Memo程序（v2）
+	MemoProgramID = "MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr"
+	// MemoV1ProgramID SPL Memo程序（v1）
+	MemoV1ProgramID = "Memo1UhkJRfHyvLMcVucJwxXeuD728EqVDDwQDxFMNo"
+	// ComputeBudgetProgramID 计算预算程序，部分钱包会自动添加其指令，备忘录登录交易不接受
+	ComputeBudgetProgramID = "ComputeBudget111111111111111111111111111111"
+)
+
+// DecodedInstruction 解码后的交易指令
+type DecodedInstruction struct {
+	ProgramID string
+	Accounts  []uint8
+	Data      []byte
+}
+
+// DecodedTransaction 解码后的交易（支持legacy与不使用地址查找表的v0格式）
+type DecodedTransaction struct {
+	Signatures   [][]byte
+	Message      []byte // 被签名的消息原文
+	Versioned    bool   // 是否为v0版本化交易
+	AccountKeys  []string
+	NumSigners   int
+	Instructions []DecodedInstruction
+}
+
+// txReader 交易二进制读取器
+type txReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *txReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errors.New("unexpected end of transaction")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *txReader) readBytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errors.New("unexpected end of transaction")
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// readCompactU16 读取Solana的compact-u16变长整数
+func (r *txReader) readCompactU16() (int, error) {
+	value := 0
+	for i := 0; i < 3; i++ {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= int(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, errors.New("invalid compact-u16")
+}
+
+// DecodeTransaction 解码序列化的Solana交易
+func DecodeTransaction(raw []byte) (*DecodedTransaction, error) {
+	r := &txReader{data: raw}
+	tx := &DecodedTransaction{}
+
+	// 签名
+	numSignatures, err := r.readCompactU16()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < numSignatures; i++ {
+		sig, err := r.readBytes(64)
+		if err != nil {
+			return nil, err
+		}
+		tx.Signatures = append(tx.Signatures, sig)
+	}
+	tx.Message = raw[r.pos:]
+
+	// 消息头，最高位为1表示版本化交易
+	first, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if first&0x80 != 0 {
+		if version := first & 0x7f; version != 0 {
+			return nil, fmt.Errorf("unsupported transaction version %d", version)
+		}
+		tx.Versioned = true
+		if first, err = r.readByte(); err != nil {
+			return nil, err
+		}
+	}
+	tx.NumSigners = int(first)
+	if _, err := r.readBytes(2); err != nil { // 只读签名账户数、只读非签名账户数
+		return nil, err
+	}
+
+	// 账户公钥
+	numKeys, err := r.readCompactU16()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < numKeys; i++ {
+		key, err := r.readBytes(32)
+		if err != nil {
+			return nil, err
+		}
+		tx.AccountKeys = append(tx.AccountKeys, base58.Encode(key))
+	}
+
+	// 最近的blockhash
+	if _, err := r.readBytes(32); err != nil {
+		return nil, err
+	}
+
+	// 指令
+	numInstructions, err := r.readCompactU16()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < numInstructions; i++ {
+		programIndex, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if int(programIndex) >= len(tx.AccountKeys) {
+			return nil, errors.New("program id index out of range")
+		}
+
+		numAccounts, err := r.readCompactU16()
+		if err != nil {
+			return nil, err
+		}
+		accounts, err := r.readBytes(numAccounts)
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range accounts {
+			if int(index) >= len(tx.AccountKeys) {
+				return nil, errors.New("account index out of range")
+			}
+		}
+
+		dataLen, err := r.readCompactU16()
+		if err != nil {
+			return nil, err
+		}
+		data, err := r.readBytes(dataLen)
+		if err != nil {
+			return nil, err
+		}
+
+		tx.Instructions = append(tx.Instructions, DecodedInstruction{
+			ProgramID: tx.AccountKeys[programIndex],
+			Accounts:  accounts,
+			Data:      data,
+		})
+	}
+
+	// v0交易在指令之后是地址查找表，查找表中的账户无法离线确认，不予支持
+	if tx.Versioned {
+		numLookups, err := r.readCompactU16()
+		if err != nil {
+			return nil, err
+		}
+		if numLookups > 0 {
+			return nil, errors.New("address lookup tables are not supported")
+		}
+	}
+
+	if r.pos != len(raw) {
+		return nil, errors.New("unexpected trailing bytes after message")
+	}
+
+	if len(tx.Signatures) != tx.NumSigners {
+		return nil, errors.New("signature count does not match header")
+	}
+
+	return tx, nil
+}
+
+// FeePayer 交易的手续费支付者（第一个账户）
+func (tx *DecodedTransaction) FeePayer() string {
+	if len(tx.AccountKeys) == 0 {
+		return ""
+	}
+	return tx.AccountKeys[0]
+}
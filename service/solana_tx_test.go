@@ -0,0 +1,211 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+
+	"github.com/mr-tron/base58"
+)
+
+// testInstruction 测试交易中的一条指令，program为账户列表中的下标
+type testInstruction struct {
+	program  byte
+	accounts []byte
+	data     []byte
+}
+
+// testTransaction 按Solana线格式构造的测试交易
+// 账户列表依次为签名者公钥与programs中的程序ID（只读非签名账户）
+type testTransaction struct {
+	versioned    bool
+	signers      []ed25519.PrivateKey
+	programs     []string
+	instructions []testInstruction
+	lookups      int // v0交易的地址查找表数量
+}
+
+// compactU16 编码Solana的compact-u16变长整数
+func compactU16(n int) []byte {
+	var out []byte
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+// message 序列化被签名的消息
+func (tx testTransaction) message(t *testing.T) []byte {
+	var m bytes.Buffer
+	if tx.versioned {
+		m.WriteByte(0x80) // 版本前缀：v0
+	}
+	// 消息头：签名账户数、只读签名账户数、只读非签名账户数
+	m.Write([]byte{byte(len(tx.signers)), 0, byte(len(tx.programs))})
+
+	m.Write(compactU16(len(tx.signers) + len(tx.programs)))
+	for _, key := range tx.signers {
+		m.Write(key.Public().(ed25519.PublicKey))
+	}
+	for _, program := range tx.programs {
+		key, err := base58.Decode(program)
+		if err != nil || len(key) != 32 {
+			t.Fatalf("program id %s: %v", program, err)
+		}
+		m.Write(key)
+	}
+
+	m.Write(bytes.Repeat([]byte{0xbb}, 32)) // recent blockhash
+
+	m.Write(compactU16(len(tx.instructions)))
+	for _, ix := range tx.instructions {
+		m.WriteByte(ix.program)
+		m.Write(compactU16(len(ix.accounts)))
+		m.Write(ix.accounts)
+		m.Write(compactU16(len(ix.data)))
+		m.Write(ix.data)
+	}
+
+	if tx.versioned {
+		m.Write(compactU16(tx.lookups))
+		for i := 0; i < tx.lookups; i++ {
+			m.Write(bytes.Repeat([]byte{0xcc}, 32)) // 查找表地址
+			m.Write(compactU16(1))                  // 可写账户下标
+			m.WriteByte(0)
+			m.Write(compactU16(0)) // 只读账户下标
+		}
+	}
+	return m.Bytes()
+}
+
+// serialize 签名并序列化完整交易
+func (tx testTransaction) serialize(t *testing.T) []byte {
+	message := tx.message(t)
+	out := compactU16(len(tx.signers))
+	for _, key := range tx.signers {
+		out = append(out, ed25519.Sign(key, message)...)
+	}
+	return append(out, message...)
+}
+
+// testKey 由种子生成的确定性密钥
+func testKey(seed byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+// memoTransaction 只包含一条Memo指令的交易
+func memoTransaction(versioned bool, signer ed25519.PrivateKey, memo string) testTransaction {
+	return testTransaction{
+		versioned:    versioned,
+		signers:      []ed25519.PrivateKey{signer},
+		programs:     []string{MemoProgramID},
+		instructions: []testInstruction{{program: 1, accounts: []byte{0}, data: []byte(memo)}},
+	}
+}
+
+func TestCompactU16(t *testing.T) {
+	for _, n := range []int{0, 1, 0x7f, 0x80, 0x3fff, 0x4000, 0xffff} {
+		r := &txReader{data: compactU16(n)}
+		if got, err := r.readCompactU16(); err != nil || got != n || r.pos != len(r.data) {
+			t.Fatalf("%d: got %d (%v)", n, got, err)
+		}
+	}
+	r := &txReader{data: []byte{0x80, 0x80, 0x80}}
+	if _, err := r.readCompactU16(); err == nil {
+		t.Fatal("accepted a 4-byte compact-u16")
+	}
+}
+
+// legacy与v0格式的Memo交易解码出相同的指令与签名
+func TestDecodeTransaction(t *testing.T) {
+	signer := testKey(1)
+	wallet := base58.Encode(signer.Public().(ed25519.PublicKey))
+	budget := testTransaction{
+		versioned: true,
+		signers:   []ed25519.PrivateKey{signer},
+		programs:  []string{ComputeBudgetProgramID, MemoV1ProgramID},
+		instructions: []testInstruction{
+			{program: 1, data: []byte{2, 0x40, 0x0d, 0x03, 0x00}},
+			{program: 2, accounts: []byte{0}, data: []byte("hello")},
+		},
+	}
+
+	for name, spec := range map[string]testTransaction{
+		"legacy":         memoTransaction(false, signer, "hello"),
+		"v0":             memoTransaction(true, signer, "hello"),
+		"v0 with budget": budget,
+	} {
+		raw := spec.serialize(t)
+		tx, err := DecodeTransaction(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if tx.Versioned != spec.versioned || tx.NumSigners != 1 || tx.FeePayer() != wallet {
+			t.Fatalf("%s: %+v", name, tx)
+		}
+		if !bytes.Equal(tx.Message, spec.message(t)) || !ed25519.Verify(signer.Public().(ed25519.PublicKey), tx.Message, tx.Signatures[0]) {
+			t.Fatalf("%s: signed message does not round-trip", name)
+		}
+		memo := tx.Instructions[len(tx.Instructions)-1]
+		if memo.ProgramID != spec.programs[len(spec.programs)-1] || string(memo.Data) != "hello" || !bytes.Equal(memo.Accounts, []byte{0}) {
+			t.Fatalf("%s: memo instruction = %+v", name, memo)
+		}
+		if len(tx.Instructions) != len(spec.instructions) {
+			t.Fatalf("%s: %d instructions", name, len(tx.Instructions))
+		}
+	}
+}
+
+// 格式错误、被截断或包含无法校验内容的交易被拒绝
+func TestDecodeTransactionRejects(t *testing.T) {
+	signer := testKey(1)
+
+	withLookup := memoTransaction(true, signer, "hello")
+	withLookup.lookups = 1
+	badProgram := memoTransaction(false, signer, "hello")
+	badProgram.instructions[0].program = 2
+	badAccount := memoTransaction(false, signer, "hello")
+	badAccount.instructions[0].accounts = []byte{5}
+
+	v1 := memoTransaction(true, signer, "hello").serialize(t)
+	v1[1+64] = 0x81
+	// 两个签名，但消息头只声明了一个签名账户
+	signed := memoTransaction(false, signer, "hello").serialize(t)
+	extraSignature := append(append([]byte{2}, signed[1:65]...), signed[1:]...)
+
+	cases := map[string]struct {
+		raw  []byte
+		want string
+	}{
+		"empty":             {nil, "unexpected end"},
+		"lookup table":      {withLookup.serialize(t), "lookup tables"},
+		"legacy trailing":   {append(memoTransaction(false, signer, "hello").serialize(t), 0), "trailing bytes"},
+		"v0 trailing":       {append(memoTransaction(true, signer, "hello").serialize(t), 0, 0), "trailing bytes"},
+		"version 1":         {v1, "unsupported transaction version 1"},
+		"program index":     {badProgram.serialize(t), "program id index"},
+		"account index":     {badAccount.serialize(t), "account index"},
+		"signature count":   {extraSignature, "signature count"},
+		"bad signature len": {append([]byte{1}, make([]byte, 10)...), "unexpected end"},
+	}
+	for name, c := range cases {
+		_, err := DecodeTransaction(c.raw)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// 任何位置截断都无法解码
+	for _, versioned := range []bool{false, true} {
+		raw := memoTransaction(versioned, signer, "hello").serialize(t)
+		for n := 0; n < len(raw); n++ {
+			if _, err := DecodeTransaction(raw[:n]); err == nil {
+				t.Fatalf("versioned=%v: decoded a transaction truncated to %d of %d bytes", versioned, n, len(raw))
+			}
+		}
+	}
+}
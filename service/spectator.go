@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"os"
+	"singo/util"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultSpectatorLimit 每个奖池默认允许的观战连接数
+const defaultSpectatorLimit = 200
+
+// ErrSpectatorLimit 奖池观战人数已满
+var ErrSpectatorLimit = errors.New("spectator limit reached")
+
+// SpectatorLimit 获取每个奖池的观战连接上限
+func SpectatorLimit() int {
+	if limit, err := strconv.Atoi(os.Getenv("SPECTATOR_MAX_PER_POOL")); err == nil && limit > 0 {
+		return limit
+	}
+	return defaultSpectatorLimit
+}
+
+// SpectatorCount 获取奖池当前的观战人数
+func (m *WebSocketManager) SpectatorCount(poolID uint) int {
+	m.spectatorsMux.RLock()
+	defer m.spectatorsMux.RUnlock()
+	return len(m.spectators[poolID])
+}
+
+// RegisterSpectator 注册观战连接并发送奖池快照
+func (m *WebSocketManager) RegisterSpectator(poolID uint, conn *websocket.Conn, snapshot []map[string]interface{}) (*WSClient, error) {
+	m.spectatorsMux.Lock()
+	if len(m.spectators[poolID]) >= SpectatorLimit() {
+		m.spectatorsMux.Unlock()
+		return nil, ErrSpectatorLimit
+	}
+	client := newWSClient(conn)
+	client.poolID = poolID
+	if m.spectators[poolID] == nil {
+		m.spectators[poolID] = make(map[*WSClient]struct{})
+	}
+	m.spectators[poolID][client] = struct{}{}
+	count := len(m.spectators[poolID])
+	m.spectatorsMux.Unlock()
+
+	log.Printf("奖池 %d 新增观战连接，当前观战人数: %d", poolID, count)
+
+	for _, message := range snapshot {
+		if err := client.Send(message); err != nil {
+			log.Printf("向奖池 %d 观战者发送快照失败: %v", poolID, err)
+			return client, err
+		}
+	}
+	return client, nil
+}
+
+// UnregisterSpectator 注销观战连接
+func (m *WebSocketManager) UnregisterSpectator(client *WSClient, code int, reason string) {
+	client.Close(code, reason)
+
+	m.spectatorsMux.Lock()
+	defer m.spectatorsMux.Unlock()
+
+	if clients, exists := m.spectators[client.poolID]; exists {
+		if _, ok := clients[client]; ok {
+			delete(clients, client)
+			log.Printf("奖池 %d 观战连接已注销，当前观战人数: %d", client.poolID, len(clients))
+		}
+		if len(clients) == 0 {
+			delete(m.spectators, client.poolID)
+		}
+	}
+}
+
+// broadcastToSpectators 向奖池的观战者广播消息，钱包地址会被截断
+func (m *WebSocketManager) broadcastToSpectators(poolID uint, message map[string]interface{}) {
+	m.spectatorsMux.RLock()
+	defer m.spectatorsMux.RUnlock()
+
+	if len(m.spectators[poolID]) == 0 {
+		return
+	}
+
+	spectatorMsg := SpectatorMessage(message)
+	for client := range m.spectators[poolID] {
+		client.Send(spectatorMsg)
+	}
+}
+
+// closeSpectators 关闭奖池的所有观战连接
+func (m *WebSocketManager) closeSpectators(poolID uint, reason string) {
+	m.spectatorsMux.Lock()
+	clients := m.spectators[poolID]
+	delete(m.spectators, poolID)
+	m.spectatorsMux.Unlock()
+
+	for client := range clients {
+		client.Close(websocket.CloseNormalClosure, reason)
+	}
+	if len(clients) > 0 {
+		log.Printf("奖池 %d 的 %d 个观战连接已关闭: %s", poolID, len(clients), reason)
+	}
+}
+
+// SpectatorMessage 将玩家消息转换为观战者可见的消息，截断其中的钱包地址
+func SpectatorMessage(message map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(message))
+	for k, v := range message {
+		result[k] = v
+	}
+
+	for _, key := range []string{"walletAddress", "holderAddress", "winnerAddress"} {
+		if address, ok := result[key].(string); ok {
+			result[key] = util.TruncateWallet(address)
+		}
+	}
+
+	if participants, ok := result["participants"].([]map[string]interface{}); ok {
+		truncated := make([]map[string]interface{}, 0, len(participants))
+		for _, p := range participants {
+			truncated = append(truncated, SpectatorMessage(p))
+		}
+		result["participants"] = truncated
+	}
+
+	return result
+}
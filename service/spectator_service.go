@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"singo/model"
+	"singo/serializer"
+	"singo/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrPoolNotLive 奖池不存在或已结束，无法观战
+var ErrPoolNotLive = errors.New("pool is not live")
+
+// PoolSpectateService 奖池观战服务，无需登录
+type PoolSpectateService struct {
+	PoolID uint `uri:"id" binding:"required"`
+}
+
+// LivePool 获取可观战的奖池（收集中或活跃中）
+func (service *PoolSpectateService) LivePool() (*model.PrizePool, error) {
+	return GetPoolService().LivePool(service.PoolID)
+}
+
+// SnapshotMessages 构建观战者连接时收到的初始消息
+func (service *PoolSpectateService) SnapshotMessages(pool *model.PrizePool) ([]map[string]interface{}, error) {
+	messages, err := wsManager.buildPoolMessages(pool)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, message := range messages {
+		messages[i] = SpectatorMessage(message)
+	}
+	return messages, nil
+}
+
+// Snapshot 获取奖池的只读快照
+func (service *PoolSpectateService) Snapshot() serializer.Response {
+	pool, err := service.LivePool()
+	if err != nil {
+		if errors.Is(err, ErrPoolNotLive) {
+			return serializer.ParamErr("Pool is not live", err)
+		}
+		return serializer.DBErr("Failed to get pool", err)
+	}
+
+	participants, err := wsManager.buildParticipantsData(pool)
+	if err != nil {
+		return serializer.DBErr("Failed to get participants", err)
+	}
+
+	participantsData := make([]map[string]interface{}, 0, len(participants))
+	for _, p := range participants {
+		participantsData = append(participantsData, SpectatorMessage(p))
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"pool": gin.H{
+				"id":             pool.ID,
+				"status":         pool.Status,
+				"currentPlayers": pool.CurrentPlayers,
+				"prizeAmount":    pool.PrizeAmount,
+				"bigPrizeHolder": util.TruncateWallet(pool.CurrentBigPrizeHolder),
+				"spectatorCount": wsManager.SpectatorCount(pool.ID),
+			},
+			"participants": participantsData,
+		},
+	}
+}
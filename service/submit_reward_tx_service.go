@@ -1,6 +1,7 @@
 package service
 
 import (
+	"log"
 	"singo/model"
 	"singo/serializer"
 )
@@ -27,11 +28,22 @@ func (service *SubmitRewardTxService) Submit(user *model.User) serializer.Respon
 		return serializer.Err(serializer.CodeDBError, "Failed to verify or submit transaction", err)
 	}
 
-	// 更新用户奖励数据
-	user.HistoryRewards += service.Amount
-	user.UnclaimedRewards = 0
-	if err := user.UpdateRewards(0, user.HistoryRewards); err != nil {
-		return serializer.DBErr("Failed to update rewards", err)
+	// 更新用户奖励数据；转账已经上链，记账失败时交给任务队列重试
+	if err := GetRewardService().Settle(user, service.Amount, txHash); err != nil {
+		log.Printf("用户 %d 的奖励结算失败，稍后重试: %v", user.ID, err)
+		if err := GetGameJobs().SchedulePayoutRetry(user.ID, service.Amount, txHash); err != nil {
+			return serializer.DBErr("Failed to update rewards", err)
+		}
+		return serializer.Response{
+			Code: 0,
+			Data: map[string]interface{}{
+				"success":         true,
+				"amount":          service.Amount,
+				"transactionHash": txHash,
+				"settlePending":   true,
+			},
+			Msg: "Transaction submitted, settlement pending",
+		}
 	}
 
 	return serializer.Response{
@@ -5,47 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"singo/model"
+	"singo/auth"
 	"singo/serializer"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/mr-tron/base58"
-	"gorm.io/gorm"
 )
 
 // UserLoginService 管理用户登录的服务
+// Message 必须是通过 auth/nonce 获取的SIWS结构化消息
 type UserLoginService struct {
 	WalletAddress string `form:"walletAddress" json:"walletAddress" binding:"required"`
 	Message       string `form:"message" json:"message" binding:"required"`
-	Timestamp     string `form:"timestamp" json:"timestamp" binding:"required"`
 	Signature     string `form:"signature" json:"signature" binding:"required"`
 }
 
-const (
-	jwtSecret = "your-secret-key" // TODO: Move to configuration
-	jwtExpiry = 24 * time.Hour
-)
-
-// generateToken 生成JWT token
-func generateToken(walletAddress string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"wallet_address": walletAddress,
-		"exp":            time.Now().Add(jwtExpiry).Unix(),
-	})
-	return token.SignedString([]byte(jwtSecret))
-}
-
-// verifySignature 验证Solana钱包签名
+// verifySignature 验证Solana钱包对原始消息的签名
 func (service *UserLoginService) verifySignature() bool {
-	// 构建签名消息，使用\r\n作为换行符以匹配前端
 	message := service.Message
-	if !strings.Contains(message, "Timestamp:") {
-		message = fmt.Sprintf("%s\r\n\r\nTimestamp: %s", service.Message, service.Timestamp)
-	}
 
 	// 解码签名（Base58格式）
 	sig, err := base58.Decode(service.Signature)
@@ -72,49 +50,58 @@ func (service *UserLoginService) verifySignature() bool {
 
 // Login 用户登录函数
 func (service *UserLoginService) Login(c *gin.Context) serializer.Response {
-	// 将timestamp字符串转换为int64
-	timestamp, err := strconv.ParseInt(service.Timestamp, 10, 64)
+	return loginWithSIWS(c, service.WalletAddress, service.Message, service.verifySignature)
+}
+
+// loginWithSIWS 校验SIWS消息并完成登录，verify负责校验钱包对消息的签名
+// 普通消息签名与备忘录交易签名两种登录方式共用
+func loginWithSIWS(c *gin.Context, walletAddress string, message string, verify func() bool) serializer.Response {
+	// 解析SIWS消息
+	siws, err := ParseSIWSMessage(message)
 	if err != nil {
-		return serializer.ParamErr("Invalid timestamp format", err)
+		return serializer.ParamErr(fmt.Sprintf("Invalid sign-in message: %v", err), err)
 	}
 
-	// 验证签名时效性（5分钟内）
-	if time.Now().Unix()-timestamp > 300 {
-		return serializer.ParamErr("Signature expired", nil)
+	// 校验域名、URI、链ID、nonce与有效期等字段
+	if err := siws.Validate(loadSIWSConfig(), walletAddress, time.Now()); err != nil {
+		return serializer.ParamErr(fmt.Sprintf("Invalid sign-in message: %v", err), err)
 	}
 
 	// 验证签名
-	if !service.verifySignature() {
-		return serializer.ParamErr(fmt.Sprintf("Invalid signature for wallet: %s", service.WalletAddress), nil)
+	if !verify() {
+		return serializer.ParamErr(fmt.Sprintf("Invalid signature for wallet: %s", walletAddress), nil)
 	}
 
-	// 获取或创建用户
-	user, err := model.GetUserByWallet(service.WalletAddress)
-	isNewUser := false
+	// 签名有效后再消费nonce，防止重放
+	if err := consumeNonce(siws.Nonce, walletAddress); err != nil {
+		if errors.Is(err, ErrInvalidNonce) {
+			return serializer.ParamErr("Invalid or used nonce", err)
+		}
+		return serializer.Err(serializer.CodeDBError, "Failed to verify nonce", err)
+	}
+
+	return completeLogin(c, walletAddress)
+}
 
+// completeLogin 获取或创建用户，创建登录会话并签发令牌
+func completeLogin(c *gin.Context, walletAddress string) serializer.Response {
+	// 获取或创建用户
+	user, isNewUser, err := GetUserService().GetOrCreate(walletAddress)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// 用户不存在，创建新用户
-			user, err = model.CreateUser(service.WalletAddress)
-			if err != nil {
-				return serializer.DBErr("Failed to create user", err)
-			}
-			isNewUser = true
-		} else {
-			// 其他数据库错误
-			return serializer.DBErr("Database error", err)
-		}
+		return serializer.DBErr("Failed to get or create user", err)
 	}
 
 	// 获取用户的青蛙状态
-	frog, err := model.GetFrogByUserID(user.ID)
-	isActive := false
-	if err == nil || model.IsRecordNotFoundError(err) {
-		isActive = frog != nil && frog.IsActive
+	isActive, _ := GetGameService().HasActiveFrog(user.ID)
+
+	// 记录登录会话
+	session, err := createUserSession(c, user)
+	if err != nil {
+		return serializer.DBErr("Failed to create session", err)
 	}
 
-	// 生成JWT token
-	token, err := generateToken(service.WalletAddress)
+	// 签发访问令牌与刷新令牌
+	tokens, err := auth.IssueTokens(walletAddress, session.SessionID)
 	if err != nil {
 		return serializer.Err(serializer.CodeEncryptError, "Failed to generate token", err)
 	}
@@ -122,8 +109,11 @@ func (service *UserLoginService) Login(c *gin.Context) serializer.Response {
 	return serializer.Response{
 		Code: 0,
 		Data: gin.H{
-			"token":     token,
-			"isNewUser": isNewUser,
+			"token":        tokens.AccessToken,
+			"refreshToken": tokens.RefreshToken,
+			"expiresIn":    tokens.ExpiresIn,
+			"sessionId":    tokens.SessionID,
+			"isNewUser":    isNewUser,
 			"user": gin.H{
 				"walletAddress":    user.WalletAddress,
 				"unclaimedRewards": user.UnclaimedRewards,
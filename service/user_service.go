@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"singo/model"
+	"singo/repository"
+)
+
+// UserService 用户与角色管理
+type UserService struct {
+	users repository.UserRepository
+	repos *repository.Repositories
+}
+
+// NewUserService 创建用户服务
+func NewUserService(repos *repository.Repositories) *UserService {
+	return &UserService{
+		users: repos.Users,
+		repos: repos,
+	}
+}
+
+// Get 用ID获取用户
+func (s *UserService) Get(id uint) (*model.User, error) {
+	return s.users.Get(id)
+}
+
+// GetByWallet 通过钱包地址获取用户
+func (s *UserService) GetByWallet(walletAddress string) (*model.User, error) {
+	return s.users.GetByWallet(walletAddress)
+}
+
+// GetOrCreate 通过钱包地址获取用户，不存在时创建玩家角色的新用户
+// created表示本次是否新建了用户
+func (s *UserService) GetOrCreate(walletAddress string) (user *model.User, created bool, err error) {
+	user, err = s.users.GetByWallet(walletAddress)
+	if err == nil {
+		return user, false, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, false, err
+	}
+
+	newUser := model.NewUser(walletAddress)
+	if err := s.users.Create(&newUser); err != nil {
+		return nil, false, err
+	}
+	return &newUser, true, nil
+}
+
+// ChangeRole 修改用户角色并在同一事务中记录权限变更审计
+// actor为nil表示系统操作（如启动时根据配置引导管理员）
+func (s *UserService) ChangeRole(user *model.User, newRole string, actor *model.User, reason string) error {
+	audit := model.NewRoleAuditLog(user, newRole, actor, reason)
+	err := s.repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Users.UpdateRole(user.ID, newRole); err != nil {
+			return err
+		}
+		return tx.RoleAudits.Create(&audit)
+	})
+	if err != nil {
+		return err
+	}
+
+	user.Role = newRole
+	return nil
+}
+
+// RoleAudits 获取权限变更记录，targetUserID大于0时只获取该用户的记录
+func (s *UserService) RoleAudits(targetUserID uint, limit int) ([]model.RoleAuditLog, error) {
+	return s.repos.RoleAudits.List(targetUserID, limit)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"singo/auth"
+	"testing"
+)
+
+// 不存在的钱包首次获取时创建玩家；修改角色与审计记录在同一事务中写入
+func TestUserServiceChangeRole(t *testing.T) {
+	users := GetUserService()
+
+	user, created, err := users.GetOrCreate("change-role-wallet")
+	if err != nil || !created || user.Role != string(auth.RolePlayer) {
+		t.Fatalf("create: %+v created=%v (%v)", user, created, err)
+	}
+	if again, created, err := users.GetOrCreate("change-role-wallet"); err != nil || created || again.ID != user.ID {
+		t.Fatalf("get existing: %+v created=%v (%v)", again, created, err)
+	}
+
+	actor, _, _ := users.GetOrCreate("change-role-actor")
+	if err := users.ChangeRole(user, string(auth.RoleFinance), actor, "quarter close"); err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != string(auth.RoleFinance) {
+		t.Fatalf("role not updated in place: %s", user.Role)
+	}
+	if stored, _ := users.Get(user.ID); stored.Role != string(auth.RoleFinance) {
+		t.Fatalf("stored role = %s", stored.Role)
+	}
+
+	logs, err := users.RoleAudits(user.ID, 10)
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("audit logs = %+v (%v)", logs, err)
+	}
+	if l := logs[0]; l.OldRole != string(auth.RolePlayer) || l.NewRole != string(auth.RoleFinance) || l.ActorWallet != actor.WalletAddress || l.Reason != "quarter close" {
+		t.Fatalf("audit = %+v", l)
+	}
+}
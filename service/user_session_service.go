@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"singo/auth"
+	"singo/model"
+	"singo/serializer"
+	"singo/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UserSessionService 查询与吊销登录会话的服务
+type UserSessionService struct {
+	ExceptCurrent bool `form:"exceptCurrent" json:"exceptCurrent"` // 吊销全部会话时保留当前会话
+}
+
+// createUserSession 为登录请求创建会话记录
+func createUserSession(c *gin.Context, user *model.User) (model.UserSession, error) {
+	sessionID, err := auth.NewSessionID()
+	if err != nil {
+		return model.UserSession{}, err
+	}
+
+	userAgent := c.GetHeader("User-Agent")
+	return model.CreateUserSession(user.ID, sessionID, util.DeviceFromUserAgent(userAgent), userAgent, c.ClientIP())
+}
+
+// revokeUserSession 吊销会话的令牌并关闭其WebSocket连接
+func revokeUserSession(session *model.UserSession) error {
+	if session.RevokedAt == nil {
+		if err := session.Revoke(); err != nil {
+			return err
+		}
+	}
+	if err := auth.RevokeSession(session.SessionID); err != nil {
+		return err
+	}
+	GetWebSocketManager().CloseSession(session.UserID, session.SessionID)
+	return nil
+}
+
+// List 列出用户当前有效的登录会话
+func (service *UserSessionService) List(user *model.User, currentSessionID string) serializer.Response {
+	sessions, err := model.GetActiveUserSessions(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to get sessions", err)
+	}
+
+	data := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		data = append(data, gin.H{
+			"id":         s.SessionID,
+			"device":     s.Device,
+			"userAgent":  s.UserAgent,
+			"ip":         s.IP,
+			"createdAt":  s.CreatedAt.Unix(),
+			"lastSeenAt": s.LastSeenAt.Unix(),
+			"current":    s.SessionID == currentSessionID,
+		})
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"sessions": data,
+		},
+	}
+}
+
+// Revoke 吊销用户的指定会话
+func (service *UserSessionService) Revoke(user *model.User, sessionID string) serializer.Response {
+	session, err := model.GetUserSession(user.ID, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return serializer.ParamErr("Session not found", err)
+		}
+		return serializer.DBErr("Failed to get session", err)
+	}
+
+	if err := revokeUserSession(&session); err != nil {
+		return serializer.DBErr("Failed to revoke session", err)
+	}
+	log.Printf("用户 %d 吊销了会话 %s", user.ID, session.SessionID)
+
+	return serializer.Response{
+		Code: 0,
+		Msg:  "Session revoked",
+	}
+}
+
+// RevokeAll 吊销用户的全部会话，可选择保留当前会话
+func (service *UserSessionService) RevokeAll(user *model.User, currentSessionID string) serializer.Response {
+	sessions, err := model.GetActiveUserSessions(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to get sessions", err)
+	}
+
+	revoked := 0
+	for i := range sessions {
+		if service.ExceptCurrent && sessions[i].SessionID == currentSessionID {
+			continue
+		}
+		if err := revokeUserSession(&sessions[i]); err != nil {
+			return serializer.DBErr("Failed to revoke session", err)
+		}
+		revoked++
+	}
+	log.Printf("用户 %d 吊销了 %d 个会话", user.ID, revoked)
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"revoked": revoked,
+		},
+		Msg: "Sessions revoked",
+	}
+}
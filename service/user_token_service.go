@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"singo/auth"
+	"singo/model"
+	"singo/serializer"
+
+	"gorm.io/gorm"
+)
+
+// TokenRefreshService 刷新访问令牌的服务
+type TokenRefreshService struct {
+	RefreshToken string `form:"refreshToken" json:"refreshToken" binding:"required"`
+}
+
+// UserLogoutService 用户登出服务
+type UserLogoutService struct {
+	RefreshToken string `form:"refreshToken" json:"refreshToken"`
+}
+
+// Refresh 用刷新令牌换取新的令牌对
+func (service *TokenRefreshService) Refresh() serializer.Response {
+	tokens, err := auth.Refresh(service.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return serializer.Err(serializer.CodeCheckLogin, "Invalid refresh token", err)
+		}
+		return serializer.Err(serializer.CodeEncryptError, "Failed to refresh token", err)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: tokens,
+	}
+}
+
+// Logout 吊销当前访问令牌及其刷新令牌，并结束所属的登录会话
+func (service *UserLogoutService) Logout(user *model.User, claims *auth.Claims) serializer.Response {
+	if err := auth.Revoke(claims); err != nil {
+		return serializer.Err(serializer.CodeDBError, "Failed to revoke token", err)
+	}
+
+	if claims.SessionID != "" {
+		session, err := model.GetUserSession(user.ID, claims.SessionID)
+		if err == nil {
+			err = revokeUserSession(&session)
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return serializer.DBErr("Failed to end session", err)
+		}
+	}
+
+	if service.RefreshToken != "" {
+		if err := auth.RevokeRefreshToken(service.RefreshToken); err != nil {
+			return serializer.Err(serializer.CodeDBError, "Failed to revoke refresh token", err)
+		}
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Msg:  "Logout successful",
+	}
+}
@@ -1,10 +1,13 @@
 package service
 
 import (
+	"errors"
 	"log"
-	"math/rand"
 	"singo/event"
 	"singo/model"
+	"singo/repository"
+	"singo/serializer"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,29 +16,114 @@ import (
 
 // WebSocketManager 管理所有WebSocket连接
 type WebSocketManager struct {
-	clients    map[uint]*websocket.Conn // userID -> connection
+	clients    map[uint]*WSClient // userID -> client
 	clientsMux sync.RWMutex
+
+	// 玩家在线状态，walletAddress -> presence
+	presence    map[string]*presenceState
+	presenceMux sync.RWMutex
+
+	// SSE订阅者与事件历史，用于断线续传
+	streams    map[*EventStream]struct{}
+	history    []StreamEvent
+	eventSeq   uint64
+	streamsMux sync.Mutex
+
+	// 观战连接，poolID -> clients
+	spectators    map[uint]map[*WSClient]struct{}
+	spectatorsMux sync.RWMutex
+
+	frogs        repository.FrogRepository
+	pools        repository.PoolRepository
+	participants repository.ParticipantRepository
+	rewards      *RewardService
+	repos        *repository.Repositories
+	engine       *PoolEngine
+
+	subscriptions []*event.Subscription
 }
 
-var (
-	wsManager = &WebSocketManager{
-		clients: make(map[uint]*websocket.Conn),
+// NewWebSocketManager 创建WebSocket管理器
+func NewWebSocketManager(repos *repository.Repositories, rewards *RewardService, engine *PoolEngine) *WebSocketManager {
+	return &WebSocketManager{
+		clients:    make(map[uint]*WSClient),
+		presence:   make(map[string]*presenceState),
+		streams:    make(map[*EventStream]struct{}),
+		spectators: make(map[uint]map[*WSClient]struct{}),
+		// 以启动时间作为事件ID起点，避免重启后旧的Last-Event-ID被误认为有效
+		eventSeq:     uint64(time.Now().UnixNano()),
+		frogs:        repos.Frogs,
+		pools:        repos.Pools,
+		participants: repos.Participants,
+		rewards:      rewards,
+		repos:        repos,
+		engine:       engine,
 	}
-)
+}
 
-func init() {
-	// 初始化随机数生成器
-	rand.Seed(time.Now().UnixNano())
+// SubscribeEvents 订阅需要推送给客户端的领域事件
+func (m *WebSocketManager) SubscribeEvents() {
+	m.subscriptions = append(m.subscriptions,
+		event.Subscribe(m.handleParticipantsChanged),
+		event.Subscribe(m.handlePoolCompleted),
+		event.Subscribe(func(e event.FrogFed) {
+			m.BroadcastHungerUpdate(e.UserID, e.FrogID, e.HungerLevel)
+		}),
+		event.Subscribe(func(e event.HungerTicked) {
+			m.BroadcastHungerUpdate(e.UserID, e.FrogID, e.HungerLevel)
+		}),
+		event.Subscribe(func(e event.FrogStarved) {
+			m.BroadcastHungerUpdate(e.UserID, e.FrogID, 0)
+		}),
+		event.Subscribe(func(e event.PrizeMoved) {
+			m.BroadcastBigPrizeLocation(e.PoolID, e.Holder)
+		}),
+	)
+}
 
-	// 订阅奖池激活事件
-	event.Subscribe(event.PoolBecameActive, func(e event.PoolEvent) {
-		GetPrizeUpdaterService().StartUpdater(e.PoolID)
-	})
+// UnsubscribeEvents 取消全部事件订阅
+func (m *WebSocketManager) UnsubscribeEvents() {
+	for _, sub := range m.subscriptions {
+		sub.Unsubscribe()
+	}
+	m.subscriptions = nil
+}
 
-	// 订阅奖池参与者变化事件
-	event.Subscribe(event.PoolParticipantsChanged, func(e event.PoolEvent) {
-		GetWebSocketManager().BroadcastPoolUpdate(e.PoolID, e.Participants)
-	})
+// handleParticipantsChanged 广播奖池参与者，经发件箱投递的事件不带参与者数据，按提交后的状态查询
+func (m *WebSocketManager) handleParticipantsChanged(e event.PoolParticipantsChanged) {
+	participants := e.Participants
+	if participants == nil {
+		pool, err := m.pools.Get(e.PoolID)
+		if err != nil {
+			log.Printf("获取奖池 %d 信息失败: %v", e.PoolID, err)
+			return
+		}
+		if participants, err = m.buildParticipantsData(pool); err != nil {
+			return
+		}
+	} else {
+		participants = m.withPresence(participants)
+	}
+	m.BroadcastPoolUpdate(e.PoolID, participants)
+}
+
+// handlePoolCompleted 奖池结束后广播参与者青蛙的饥饿值与游戏结束消息
+func (m *WebSocketManager) handlePoolCompleted(e event.PoolCompleted) {
+	if e.Winner != "" {
+		participants, err := m.participants.ListByPool(e.PoolID)
+		if err != nil {
+			log.Printf("获取奖池 %d 参与者失败: %v", e.PoolID, err)
+		}
+		for _, participant := range participants {
+			frog, err := m.frogs.Get(participant.FrogID)
+			if err != nil {
+				continue
+			}
+			m.BroadcastHungerUpdate(frog.UserID, frog.ID, frog.HungerLevel)
+		}
+	}
+
+	m.BroadcastGameOver(e.PoolID, e.Winner, e.PrizeAmount)
 }
 
 // GetWebSocketManager 获取WebSocket管理器实例
@@ -44,167 +132,189 @@ func GetWebSocketManager() *WebSocketManager {
 }
 
 // RegisterClient 注册新的WebSocket客户端并发送初始状态
-func (m *WebSocketManager) RegisterClient(userID uint, conn *websocket.Conn) error {
-	// 先获取旧连接（如果存在）
-	m.clientsMux.RLock()
-	oldConn, exists := m.clients[userID]
-	m.clientsMux.RUnlock()
+func (m *WebSocketManager) RegisterClient(userID uint, walletAddress string, sessionID string, conn *websocket.Conn) (*WSClient, error) {
+	client := newWSClient(conn)
+	client.userID = userID
+	client.walletAddress = walletAddress
+	client.sessionID = sessionID
+
+	// 替换旧连接（如果存在）
+	m.clientsMux.Lock()
+	oldClient, exists := m.clients[userID]
+	m.clients[userID] = client
+	m.clientsMux.Unlock()
+	log.Printf("用户 %d 的WebSocket连接已保存", userID)
 
-	// 如果存在旧连接，先关闭它
+	// 如果存在旧连接，以关闭帧告知原因后关闭
 	if exists {
 		log.Printf("用户 %d 存在旧连接，正在关闭", userID)
-		oldConn.Close()
-		// 等待一小段时间确保旧连接完全关闭
-		time.Sleep(100 * time.Millisecond)
+		oldClient.Close(CloseReplaced, "replaced by new connection")
 	}
 
-	// 注册新连接
-	m.clientsMux.Lock()
-	m.clients[userID] = conn
-	m.clientsMux.Unlock()
-	log.Printf("用户 %d 的WebSocket连接已保存", userID)
+	// 标记在线并通知奖池内其他玩家
+	m.setOnline(userID, walletAddress, true)
 
 	// 发送初始状态
-	if err := m.sendInitialState(userID, conn); err != nil {
+	if err := m.sendInitialState(client); err != nil {
 		log.Printf("用户 %d 发送初始状态失败: %v", userID, err)
 		// 不要因为发送失败就中断连接
 	} else {
 		log.Printf("用户 %d 初始状态发送成功", userID)
 	}
 
-	return nil
+	return client, nil
 }
 
 // sendInitialState 发送初始状态给客户端
-func (m *WebSocketManager) sendInitialState(userID uint, conn *websocket.Conn) error {
-	log.Printf("开始获取用户 %d 的青蛙状态", userID)
+func (m *WebSocketManager) sendInitialState(client *WSClient) error {
+	messages, err := m.buildInitialState(client.userID)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		if err := client.Send(message); err != nil {
+			log.Printf("用户 %d 发送初始状态消息 %v 失败: %v", client.userID, message["type"], err)
+			return err
+		}
+	}
 
-	// 设置较长的写超时时间用于发送初始状态
-	conn.SetWriteDeadline(time.Now().Add(60 * time.Second))
-	defer conn.SetWriteDeadline(time.Now().Add(60 * time.Second)) // 恢复默认写超时
+	return nil
+}
+
+// buildInitialState 构建用户的初始状态消息，WebSocket与SSE共用
+func (m *WebSocketManager) buildInitialState(userID uint) ([]map[string]interface{}, error) {
+	log.Printf("开始获取用户 %d 的青蛙状态", userID)
 
 	// 获取用户当前的青蛙状态
-	frog, err := model.GetFrogByUserID(userID)
+	frog, err := m.frogs.GetActiveByUser(userID)
 	if err != nil {
-		if model.IsRecordNotFoundError(err) {
-			log.Printf("用户 %d 没有找到青蛙记录", userID)
-			return nil
+		if errors.Is(err, repository.ErrNotFound) {
+			log.Printf("用户 %d 没有激活的青蛙，跳过发送状态", userID)
+			return nil, nil
 		}
 		log.Printf("获取用户 %d 青蛙状态失败: %v", userID, err)
-		return err
+		return nil, err
 	}
 
-	// 如果找到激活的青蛙，发送状态
-	if frog != nil && frog.IsActive {
-		log.Printf("用户 %d 的青蛙处于激活状态，准备发送状态更新", userID)
+	log.Printf("用户 %d 的青蛙处于激活状态，准备发送状态更新", userID)
 
-		// 发送饥饿值更新
-		hungerMessage := map[string]interface{}{
+	// 饥饿值更新
+	messages := []map[string]interface{}{
+		{
 			"type":           "hunger-update",
 			"frogId":         frog.ID,
-			"newHungerLevel": frog.HungerLevel,
-		}
-
-		// 重置写超时并发送消息
-		conn.SetWriteDeadline(time.Now().Add(60 * time.Second))
-		if err := conn.WriteJSON(hungerMessage); err != nil {
-			log.Printf("用户 %d 发送饥饿值更新失败: %v", userID, err)
-			return err
-		}
-		log.Printf("用户 %d 饥饿值更新发送成功", userID)
+			"newHungerLevel": frog.CurrentHunger(time.Now()),
+		},
+	}
 
-		// 获取并发送奖池信息
-		if err := m.sendPoolInfo(userID, conn); err != nil {
-			log.Printf("用户 %d 发送奖池信息失败: %v", userID, err)
-			return err
-		}
-		log.Printf("用户 %d 奖池信息发送成功", userID)
-	} else {
-		log.Printf("用户 %d 的青蛙未激活或不存在，跳过发送状态", userID)
+	// 奖池信息
+	poolMessages, err := m.buildPoolInfo(userID)
+	if err != nil {
+		log.Printf("用户 %d 获取奖池信息失败: %v", userID, err)
+		return nil, err
 	}
 
-	return nil
+	return append(messages, poolMessages...), nil
 }
 
-// sendPoolInfo 发送奖池信息
-func (m *WebSocketManager) sendPoolInfo(userID uint, conn *websocket.Conn) error {
+// buildPoolInfo 构建用户当前奖池的信息消息
+func (m *WebSocketManager) buildPoolInfo(userID uint) ([]map[string]interface{}, error) {
 	log.Printf("开始获取用户 %d 的奖池信息", userID)
 
-	// 设置写超时
-	conn.SetWriteDeadline(time.Now().Add(60 * time.Second))
-
-	pool, err := model.GetCurrentActivePool(userID)
+	pool, err := m.currentPool(userID)
 	if err != nil {
-		if model.IsRecordNotFoundError(err) {
-			log.Printf("用户 %d 没有找到活跃奖池", userID)
-			return nil
-		}
 		log.Printf("获取用户 %d 奖池信息失败: %v", userID, err)
-		return err
+		return nil, err
 	}
 
-	if pool != nil {
-		log.Printf("找到用户 %d 的活跃奖池，ID: %d", userID, pool.ID)
-		participants, err := model.GetParticipantsByPoolID(pool.ID)
-		if err != nil {
-			log.Printf("获取奖池 %d 参与者信息失败: %v", pool.ID, err)
-			return err
-		}
+	if pool == nil {
+		log.Printf("用户 %d 没有活跃奖池", userID)
+		return nil, nil
+	}
 
-		var participantsData []map[string]interface{}
-		for _, p := range participants {
-			// 获取青蛙的状态
-			var frog model.Frog
-			if err := model.DB.First(&frog, p.FrogID).Error; err != nil {
-				log.Printf("获取青蛙 %d 状态失败: %v", p.FrogID, err)
-				continue
-			}
+	log.Printf("找到用户 %d 的活跃奖池，ID: %d", userID, pool.ID)
+	return m.buildPoolMessages(pool)
+}
 
-			participantsData = append(participantsData, map[string]interface{}{
-				"walletAddress":  p.WalletAddress,
-				"serialNumber":   p.SerialNumber,
-				"canSeeBigPrize": p.WalletAddress == pool.CurrentBigPrizeHolder,
-				"isActive":       frog.IsActive,
-			})
+// currentPool 获取用户激活青蛙所在的未完成奖池，没有时返回nil
+func (m *WebSocketManager) currentPool(userID uint) (*model.PrizePool, error) {
+	frog, err := m.frogs.GetActiveByUser(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
 		}
+		return nil, err
+	}
 
-		// 重置写超时并发送奖池更新
-		conn.SetWriteDeadline(time.Now().Add(60 * time.Second))
-		poolMessage := map[string]interface{}{
-			"type":         "pool-update",
-			"poolId":       pool.ID,
-			"participants": participantsData,
-		}
-		if err := conn.WriteJSON(poolMessage); err != nil {
-			log.Printf("发送奖池 %d 更新消息失败: %v", pool.ID, err)
-			return err
+	pool, err := m.pools.GetCurrentByFrog(frog.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
 		}
-		log.Printf("奖池 %d 更新消息发送成功", pool.ID)
-
-		if pool.CurrentBigPrizeHolder != "" {
-			// 重置写超时并发送大奖位置信息
-			conn.SetWriteDeadline(time.Now().Add(60 * time.Second))
-			bigPrizeMessage := map[string]interface{}{
-				"type":          "big-prize-location",
-				"poolId":        pool.ID,
-				"holderAddress": pool.CurrentBigPrizeHolder,
-			}
-			if err := conn.WriteJSON(bigPrizeMessage); err != nil {
-				log.Printf("发送大奖位置信息失败: %v", err)
-				return err
-			}
-			log.Printf("大奖位置信息发送成功")
+		return nil, err
+	}
+	return pool, nil
+}
+
+// buildPoolMessages 构建奖池的pool-update与big-prize-location消息
+func (m *WebSocketManager) buildPoolMessages(pool *model.PrizePool) ([]map[string]interface{}, error) {
+	participantsData, err := m.buildParticipantsData(pool)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []map[string]interface{}{
+		{
+			"type":           "pool-update",
+			"poolId":         pool.ID,
+			"participants":   participantsData,
+			"spectatorCount": m.SpectatorCount(pool.ID),
+		},
+	}
+
+	if pool.CurrentBigPrizeHolder != "" {
+		messages = append(messages, map[string]interface{}{
+			"type":          "big-prize-location",
+			"poolId":        pool.ID,
+			"holderAddress": pool.CurrentBigPrizeHolder,
+		})
+	}
+
+	return messages, nil
+}
+
+// buildParticipantsData 构建奖池参与者数据
+func (m *WebSocketManager) buildParticipantsData(pool *model.PrizePool) ([]map[string]interface{}, error) {
+	participants, err := m.participants.ListByPool(pool.ID)
+	if err != nil {
+		log.Printf("获取奖池 %d 参与者信息失败: %v", pool.ID, err)
+		return nil, err
+	}
+
+	var participantsData []map[string]interface{}
+	for _, p := range participants {
+		// 获取青蛙的状态
+		frog, err := m.frogs.Get(p.FrogID)
+		if err != nil {
+			log.Printf("获取青蛙 %d 状态失败: %v", p.FrogID, err)
+			continue
 		}
-	} else {
-		log.Printf("用户 %d 没有活跃奖池", userID)
+
+		participantsData = append(participantsData, map[string]interface{}{
+			"walletAddress":  p.WalletAddress,
+			"serialNumber":   p.SerialNumber,
+			"canSeeBigPrize": p.WalletAddress == pool.CurrentBigPrizeHolder,
+			"isActive":       frog.IsActive,
+		})
 	}
 
-	return nil
+	return m.withPresence(participantsData), nil
 }
 
 // HandlePing 处理客户端的ping消息
-func (m *WebSocketManager) HandlePing(conn *websocket.Conn) error {
-	err := conn.WriteJSON(map[string]string{"type": "pong"})
+func (m *WebSocketManager) HandlePing(client *WSClient) error {
+	err := client.Send(map[string]string{"type": "pong"})
 	if err != nil {
 		log.Printf("发送pong响应失败: %v", err)
 	} else {
@@ -213,66 +323,142 @@ func (m *WebSocketManager) HandlePing(conn *websocket.Conn) error {
 	return err
 }
 
-// UnregisterClient 注销WebSocket客户端
-func (m *WebSocketManager) UnregisterClient(userID uint) {
+// SendCommandResult 发送游戏指令的处理结果
+// 成功时发送ack帧，失败时发送error帧，均携带客户端的requestId
+func (m *WebSocketManager) SendCommandResult(client *WSClient, requestID string, res serializer.Response) error {
+	var message map[string]interface{}
+	if res.Code == 0 {
+		message = map[string]interface{}{
+			"type":      "ack",
+			"requestId": requestID,
+			"data":      res.Data,
+		}
+	} else {
+		message = map[string]interface{}{
+			"type":      "error",
+			"requestId": requestID,
+			"code":      res.Code,
+			"msg":       res.Msg,
+		}
+		if res.Error != "" {
+			message["error"] = res.Error
+		}
+	}
+
+	return client.Send(message)
+}
+
+// UnregisterClient 注销WebSocket客户端并以指定关闭码关闭连接
+// 仅当client仍是该用户当前的连接时才从管理器移除，避免旧连接关闭时误删重连后的新连接
+func (m *WebSocketManager) UnregisterClient(client *WSClient, code int, reason string) {
+	client.Close(code, reason)
+
 	m.clientsMux.Lock()
-	defer m.clientsMux.Unlock()
+	current, exists := m.clients[client.userID]
+	if !exists || current != client {
+		m.clientsMux.Unlock()
+		return
+	}
+
+	log.Printf("开始注销用户 %d 的WebSocket客户端", client.userID)
+	delete(m.clients, client.userID)
+	m.clientsMux.Unlock()
+	log.Printf("用户 %d 的WebSocket客户端已注销", client.userID)
+
+	m.setOnline(client.userID, client.walletAddress, false)
+}
+
+// CloseSession 关闭属于指定登录会话的WebSocket连接
+// 连接的读循环随之退出，由其负责注销
+func (m *WebSocketManager) CloseSession(userID uint, sessionID string) {
+	m.clientsMux.RLock()
+	client, exists := m.clients[userID]
+	m.clientsMux.RUnlock()
 
-	if conn, exists := m.clients[userID]; exists {
-		log.Printf("开始注销用户 %d 的WebSocket客户端", userID)
-		delete(m.clients, userID) // 先从map中删除，避免其他goroutine继续使用
-		conn.Close()              // 然后关闭连接
-		log.Printf("用户 %d 的WebSocket客户端已注销", userID)
+	if exists && client.sessionID == sessionID {
+		log.Printf("用户 %d 的会话已吊销，关闭WebSocket连接", userID)
+		client.Close(CloseSessionRevoked, "session revoked")
 	}
 }
 
-// BroadcastHungerUpdate 广播饥饿值更新
-func (m *WebSocketManager) BroadcastHungerUpdate(userID uint, frogID uint, newHungerLevel int) {
+// Connections 获取所有WebSocket连接的诊断信息
+func (m *WebSocketManager) Connections() []ConnectionInfo {
+	var connections []ConnectionInfo
+
 	m.clientsMux.RLock()
-	conn, exists := m.clients[userID]
+	for _, client := range m.clients {
+		connections = append(connections, client.info())
+	}
 	m.clientsMux.RUnlock()
 
-	if !exists {
-		log.Printf("未找到用户 %d 的WebSocket连接", userID)
-		return
+	m.spectatorsMux.RLock()
+	for _, clients := range m.spectators {
+		for client := range clients {
+			connections = append(connections, client.info())
+		}
 	}
+	m.spectatorsMux.RUnlock()
 
-	log.Printf("准备广播用户 %d 的饥饿值更新: frogID=%d, newHungerLevel=%d", userID, frogID, newHungerLevel)
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].ConnectedAt.Before(connections[j].ConnectedAt)
+	})
+	return connections
+}
 
-	// 设置写入超时
-	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+// BroadcastHungerUpdate 广播饥饿值更新
+func (m *WebSocketManager) BroadcastHungerUpdate(userID uint, frogID uint, newHungerLevel int) {
+	m.sendHungerUpdate(userID, frogID, newHungerLevel, true)
+}
 
+// sendHungerUpdate 发送饥饿值更新，record为false时不写入SSE续传历史
+func (m *WebSocketManager) sendHungerUpdate(userID uint, frogID uint, newHungerLevel int, record bool) {
 	message := map[string]interface{}{
 		"type":           "hunger-update",
 		"frogId":         frogID,
 		"newHungerLevel": newHungerLevel,
 	}
+	if record {
+		m.publish(userID, message)
+	} else {
+		m.publishLive(userID, message)
+	}
+
+	m.clientsMux.RLock()
+	client, exists := m.clients[userID]
+	m.clientsMux.RUnlock()
 
-	if err := conn.WriteJSON(message); err != nil {
+	if !exists {
+		log.Printf("未找到用户 %d 的WebSocket连接", userID)
+		return
+	}
+
+	log.Printf("准备广播用户 %d 的饥饿值更新: frogID=%d, newHungerLevel=%d", userID, frogID, newHungerLevel)
+
+	if err := client.Send(message); err != nil {
 		log.Printf("发送用户 %d 的饥饿值更新失败: %v", userID, err)
-		// 如果是连接关闭错误，移除连接
-		if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-			m.UnregisterClient(userID)
-		}
 	} else {
 		log.Printf("成功发送用户 %d 的饥饿值更新", userID)
 	}
 }
 
-// BroadcastPoolUpdate 广播奖池更新
+// BroadcastPoolUpdate 广播奖池更新，participants 需已附加在线状态
 func (m *WebSocketManager) BroadcastPoolUpdate(poolID uint, participants []map[string]interface{}) {
 	m.clientsMux.RLock()
 	defer m.clientsMux.RUnlock()
 
 	message := map[string]interface{}{
-		"type":         "pool-update",
-		"poolId":       poolID,
-		"participants": participants,
+		"type":           "pool-update",
+		"poolId":         poolID,
+		"participants":   participants,
+		"spectatorCount": m.SpectatorCount(poolID),
 	}
 
+	m.publish(0, message)
+	m.broadcastToSpectators(poolID, message)
+
 	// 向所有连接的客户端广播
-	for _, conn := range m.clients {
-		conn.WriteJSON(message)
+	for _, client := range m.clients {
+		client.Send(message)
 	}
 }
 
@@ -287,9 +473,12 @@ func (m *WebSocketManager) BroadcastBigPrizeLocation(poolID uint, holderAddress
 		"holderAddress": holderAddress,
 	}
 
+	m.publish(0, message)
+	m.broadcastToSpectators(poolID, message)
+
 	// 向所有连接的客户端广播
-	for _, conn := range m.clients {
-		conn.WriteJSON(message)
+	for _, client := range m.clients {
+		client.Send(message)
 	}
 }
 
@@ -305,154 +494,203 @@ func (m *WebSocketManager) BroadcastGameOver(poolID uint, winnerAddress string,
 		"prizeAmount":   prizeAmount,
 	}
 
+	m.publish(0, message)
+	m.broadcastToSpectators(poolID, message)
+
 	// 向所有连接的客户端广播
-	for _, conn := range m.clients {
-		conn.WriteJSON(message)
+	for _, client := range m.clients {
+		client.Send(message)
+	}
+
+	// 游戏结束后关闭观战连接
+	m.closeSpectators(poolID, "pool completed")
+}
+
+// NotifyClaimExpired 通知玩家提取奖励的交易已过期，需要重新发起提取
+func (m *WebSocketManager) NotifyClaimExpired(userID uint, amount float64) {
+	message := map[string]interface{}{
+		"type":   "claim-expired",
+		"amount": amount,
+	}
+	m.publish(userID, message)
+
+	m.clientsMux.RLock()
+	client, exists := m.clients[userID]
+	m.clientsMux.RUnlock()
+	if !exists {
+		return
+	}
+	if err := client.Send(message); err != nil {
+		log.Printf("发送用户 %d 的提取过期通知失败: %v", userID, err)
 	}
 }
 
-// StartHungerUpdateWorker 启动饥饿值更新工作器
+// StartHungerUpdateWorker 启动饥饿值推送工作器
+//
+// 工作器只读取青蛙并推送按投喂时间计算的饥饿值，不修改任何数据：饥饿值由投喂时间推算，
+// 饿死由持久化的饿死任务处理。因此使用进程内的定时器是安全的——重启时丢失的只是几次推送，
+// 多实例部署时每个实例只向自己的连接推送。
 func (m *WebSocketManager) StartHungerUpdateWorker() {
-	ticker := time.NewTicker(3 * time.Second)
+	ticker := time.NewTicker(model.HungerDecayInterval)
 	go func() {
 		for range ticker.C {
-			m.updateAllFrogsHunger()
+			m.broadcastAllHunger()
 		}
 	}()
 }
 
-// updateAllFrogsHunger 更新所有激活的青蛙的饥饿值
-func (m *WebSocketManager) updateAllFrogsHunger() {
-	var frogs []model.Frog
-	result := model.DB.Where("is_active = ?", true).Find(&frogs)
-	if err := result.Error; err != nil {
+// broadcastAllHunger 推送所有激活的青蛙当前的饥饿值
+// 定时推送的只是按投喂时间计算的当前值，下一次推送即会更新，不写入续传历史，避免挤掉需要续传的事件
+func (m *WebSocketManager) broadcastAllHunger() {
+	frogs, err := m.frogs.ListActive()
+	if err != nil {
 		log.Printf("获取激活的青蛙失败: %v", err)
 		return
 	}
 
+	now := time.Now()
 	for _, frog := range frogs {
-		duration := time.Since(frog.LastFeedTime)
-		decreaseAmount := int(duration.Seconds() / 3)
-
-		if decreaseAmount > 0 {
-			newHungerLevel := frog.HungerLevel - decreaseAmount
-			if newHungerLevel < 0 {
-				newHungerLevel = 0
-			}
+		m.sendHungerUpdate(frog.UserID, frog.ID, frog.CurrentHunger(now), false)
+	}
+}
 
-			frog.HungerLevel = newHungerLevel
-			frog.LastFeedTime = time.Now()
+// decayHunger 按距上次投喂的时间降低青蛙的饥饿值，降至0时停用并检查所在奖池
+// 返回需要写入发件箱的事件
+func (m *WebSocketManager) decayHunger(tx *repository.Repositories, pool *model.PrizePool, frogIDs []uint) ([]event.Event, error) {
+	var events []event.Event
+	for _, frogID := range frogIDs {
+		// 执行协程中重新读取，期间可能已被投喂或停用
+		frog, err := tx.Frogs.Get(frogID)
+		if err != nil {
+			return nil, err
+		}
+		if !frog.IsActive {
+			continue
+		}
 
-			wasActive := frog.IsActive
-			if newHungerLevel == 0 {
-				frog.IsActive = false
-				log.Printf("青蛙 %d 因饥饿值降至0而停用", frog.ID)
+		duration := time.Since(frog.LastFeedTime)
+		decreaseAmount := int(duration / model.HungerDecayInterval)
+		if decreaseAmount <= 0 {
+			continue
+		}
 
-				// 检查并更新奖池状态
-				if err := m.checkAndUpdatePoolStatus(frog.ID); err != nil {
-					log.Printf("检查并更新奖池状态失败: %v", err)
-				}
-			}
+		newHungerLevel := frog.HungerLevel - decreaseAmount
+		if newHungerLevel < 0 {
+			newHungerLevel = 0
+		}
+		frog.HungerLevel = newHungerLevel
+		frog.LastFeedTime = time.Now()
+		if newHungerLevel == 0 {
+			frog.IsActive = false
+			log.Printf("青蛙 %d 因饥饿值降至0而停用", frog.ID)
+		}
 
-			if err := model.DB.Save(&frog).Error; err != nil {
-				log.Printf("更新青蛙饥饿值失败: %v", err)
-				continue
+		if err := tx.Frogs.Save(frog); err != nil {
+			return nil, err
+		}
+		if frog.IsActive {
+			ticked := event.HungerTicked{UserID: frog.UserID, FrogID: frog.ID, HungerLevel: frog.HungerLevel}
+			if pool != nil {
+				ticked.PoolID = pool.ID
 			}
+			events = append(events, ticked)
+			continue
+		}
 
-			m.BroadcastHungerUpdate(frog.UserID, frog.ID, frog.HungerLevel)
-
-			// 如果青蛙的激活状态发生变化，广播奖池更新
-			if wasActive != frog.IsActive {
-				// 获取青蛙所在的奖池
-				var participant model.PoolParticipant
-				if err := model.DB.Where("frog_id = ?", frog.ID).First(&participant).Error; err != nil {
-					log.Printf("获取青蛙 %d 的奖池参与信息失败: %v", frog.ID, err)
-					continue
-				}
-
-				// 获取奖池所有参与者信息
-				participants, err := model.GetParticipantsByPoolID(participant.PoolID)
-				if err != nil {
-					log.Printf("获取奖池 %d 参与者失败: %v", participant.PoolID, err)
-					continue
-				}
-
-				// 获取奖池信息
-				var pool model.PrizePool
-				if err := model.DB.First(&pool, participant.PoolID).Error; err != nil {
-					log.Printf("获取奖池 %d 信息失败: %v", participant.PoolID, err)
-					continue
-				}
-
-				// 准备参与者数据
-				var participantsData []map[string]interface{}
-				for _, p := range participants {
-					// 获取青蛙的状态
-					var participantFrog model.Frog
-					if err := model.DB.First(&participantFrog, p.FrogID).Error; err != nil {
-						continue
-					}
-
-					participantsData = append(participantsData, map[string]interface{}{
-						"walletAddress":  p.WalletAddress,
-						"serialNumber":   p.SerialNumber,
-						"canSeeBigPrize": p.WalletAddress == pool.CurrentBigPrizeHolder,
-						"isActive":       participantFrog.IsActive,
-					})
-				}
-
-				// 发布奖池参与者变化事件
-				event.Publish(event.PoolEvent{
-					Type:         event.PoolParticipantsChanged,
-					PoolID:       participant.PoolID,
-					Participants: participantsData,
-				})
-			}
+		// 停用青蛙、结束没有活跃青蛙的奖池与写入事件在同一事务中完成
+		starved, err := m.checkAndUpdatePoolStatus(tx, frog, pool)
+		if err != nil {
+			return nil, err
 		}
+		events = append(events, starved...)
 	}
+	return events, nil
 }
 
-// checkAndUpdatePoolStatus 检查并更新奖池状态
-func (m *WebSocketManager) checkAndUpdatePoolStatus(frogID uint) error {
+// checkAndUpdatePoolStatus 青蛙停用后检查所在奖池，没有活跃的青蛙时结束奖池
+// current 为执行协程持有的奖池状态，是青蛙所在的奖池时直接修改它；返回需要写入发件箱的事件
+func (m *WebSocketManager) checkAndUpdatePoolStatus(tx *repository.Repositories, frog *model.Frog, current *model.PrizePool) ([]event.Event, error) {
+	starved := event.FrogStarved{UserID: frog.UserID, FrogID: frog.ID}
+
 	// 获取青蛙所在的奖池
-	var participant model.PoolParticipant
-	if err := model.DB.Where("frog_id = ?", frogID).First(&participant).Error; err != nil {
-		return err
+	participant, err := tx.Participants.GetLatestByFrog(frog.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return []event.Event{starved}, nil
+		}
+		return nil, err
 	}
+	starved.PoolID = participant.PoolID
 
 	// 获取奖池信息
-	var pool model.PrizePool
-	if err := model.DB.First(&pool, participant.PoolID).Error; err != nil {
-		return err
+	pool := current
+	if pool == nil || pool.ID != participant.PoolID {
+		if pool, err = tx.Pools.Get(participant.PoolID); err != nil {
+			return nil, err
+		}
 	}
 
-	// 如果奖池已经完成，不需要进一步处理
-	if pool.Status == model.PoolStatusCompleted {
-		return nil
+	events := []event.Event{starved, event.PoolParticipantsChanged{PoolID: pool.ID}}
+
+	// 游戏已经结束（结算中、已完成、已取消或有争议），不需要进一步处理
+	if !pool.Status.Live() {
+		return events, nil
 	}
 
 	// 检查奖池中是否还有活跃的青蛙
-	var activeCount int64
-	if err := model.DB.Model(&model.Frog{}).
-		Joins("JOIN pool_participants ON pool_participants.frog_id = frogs.id").
-		Where("pool_participants.pool_id = ? AND frogs.is_active = ?", pool.ID, true).
-		Count(&activeCount).Error; err != nil {
-		return err
+	activeCount, err := tx.Frogs.CountActiveInPool(pool.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 如果没有活跃的青蛙，将奖池标记为完成
+	// 如果没有活跃的青蛙，结束奖池：收集中的奖池取消，活跃的奖池无人获胜直接完成
 	if activeCount == 0 {
-		now := time.Now()
-		pool.Status = model.PoolStatusCompleted
-		pool.CompletedAt = &now
-		if err := model.DB.Save(&pool).Error; err != nil {
-			return err
+		to := model.PoolStatusCompleted
+		if pool.Status == model.PoolStatusCollecting {
+			to = model.PoolStatusCancelled
+		}
+		changed, err := transitionPool(tx, pool, to, "no active frogs", 0)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, changed)
+
+		if to == model.PoolStatusCancelled {
+			// 奖池未开始，与等待超时相同，激活费全部退还给参与者
+			userIDs, err := participantUserIDs(tx, pool.ID)
+			if err != nil {
+				return nil, err
+			}
+			if err := m.rewards.using(tx).RefundPool(pool.ID, userIDs); err != nil {
+				return nil, err
+			}
+		} else {
+			// 活跃的奖池无人获胜，奖金归平台
+			if err := m.rewards.using(tx).ForfeitPool(pool.ID); err != nil {
+				return nil, err
+			}
 		}
 
-		// 广播游戏结束消息
-		m.BroadcastGameOver(pool.ID, "", 0) // 没有赢家，奖金为0
 		log.Printf("奖池 %d 因没有活跃青蛙而结束", pool.ID)
+		events = append(events, event.PoolCompleted{PoolID: pool.ID})
 	}
 
-	return nil
+	return events, nil
+}
+
+// participantUserIDs 奖池所有参与者的用户ID
+func participantUserIDs(tx *repository.Repositories, poolID uint) ([]uint, error) {
+	participants, err := tx.Participants.ListByPool(poolID)
+	if err != nil {
+		return nil, err
+	}
+	var userIDs []uint
+	for _, participant := range participants {
+		frog, err := tx.Frogs.Get(participant.FrogID)
+		if err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, frog.UserID)
+	}
+	return userIDs, nil
 }
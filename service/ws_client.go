@@ -0,0 +1,185 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait 单条消息的写超时
+	writeWait = 10 * time.Second
+	// defaultPongWait 等待客户端pong（或任意消息）的最长时间，超时即视为断线，可由WS_PONG_WAIT配置
+	defaultPongWait = 60 * time.Second
+	// defaultPingInterval 服务端发送ping控制帧的间隔，必须小于pongWait，可由WS_PING_INTERVAL配置
+	defaultPingInterval = 25 * time.Second
+	// sendQueueSize 每个连接的发送队列长度
+	sendQueueSize = 256
+)
+
+// 应用自定义的WebSocket关闭码（4000-4999）
+const (
+	// CloseReplaced 同一用户建立了新连接
+	CloseReplaced = 4001
+	// CloseSlowConsumer 客户端消费过慢，发送队列已满
+	CloseSlowConsumer = 4002
+	// CloseHeartbeatTimeout 心跳超时
+	CloseHeartbeatTimeout = 4003
+	// CloseSessionRevoked 登录会话已被吊销
+	CloseSessionRevoked = 4004
+)
+
+// ErrClientClosed 连接已关闭，消息无法发送
+var ErrClientClosed = errors.New("websocket client closed")
+
+// WSClient WebSocket客户端连接
+// 所有写操作通过发送队列由单独的goroutine完成，避免并发写同一连接
+type WSClient struct {
+	conn      *websocket.Conn
+	send      chan interface{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	userID        uint   // 玩家连接的用户ID，观战连接为0
+	walletAddress string // 玩家钱包地址
+	sessionID     string // 玩家连接所属的登录会话
+	pongWait      time.Duration
+	pingInterval  time.Duration
+	poolID        uint // 观战连接所观看的奖池ID
+	remoteAddr    string
+	connectedAt   time.Time
+
+	messagesSent uint64 // 已发送的消息数
+	rtt          int64  // 最近一次ping往返时间（纳秒）
+}
+
+// ConnectionInfo WebSocket连接诊断信息
+type ConnectionInfo struct {
+	UserID        uint      `json:"userId,omitempty"`
+	WalletAddress string    `json:"walletAddress,omitempty"`
+	PoolID        uint      `json:"poolId,omitempty"`
+	Spectator     bool      `json:"spectator"`
+	RemoteAddr    string    `json:"remoteAddr"`
+	ConnectedAt   time.Time `json:"connectedAt"`
+	RTTMillis     float64   `json:"rttMs"`
+	QueueDepth    int       `json:"queueDepth"`
+	MessagesSent  uint64    `json:"messagesSent"`
+}
+
+// newWSClient 创建客户端并启动写协程
+func newWSClient(conn *websocket.Conn) *WSClient {
+	client := &WSClient{
+		conn:        conn,
+		send:        make(chan interface{}, sendQueueSize),
+		done:        make(chan struct{}),
+		remoteAddr:  conn.RemoteAddr().String(),
+		connectedAt: time.Now(),
+	}
+	client.pongWait, client.pingInterval = heartbeatConfig()
+
+	// pong携带ping时写入的时间戳，用于计算往返时间
+	conn.SetReadDeadline(time.Now().Add(client.pongWait))
+	conn.SetPongHandler(func(appData string) error {
+		if sentAt, err := strconv.ParseInt(appData, 10, 64); err == nil {
+			atomic.StoreInt64(&client.rtt, time.Now().UnixNano()-sentAt)
+		}
+		return conn.SetReadDeadline(time.Now().Add(client.pongWait))
+	})
+
+	go client.writePump()
+	return client
+}
+
+// heartbeatConfig 读取心跳配置；ping间隔不小于pongWait时每个连接都会在读超时时断开，此时使用默认值
+func heartbeatConfig() (pongWait, pingInterval time.Duration) {
+	pongWait = durationEnv("WS_PONG_WAIT", defaultPongWait)
+	pingInterval = durationEnv("WS_PING_INTERVAL", defaultPingInterval)
+	if pingInterval >= pongWait {
+		log.Printf("WS_PING_INTERVAL(%v) 必须小于 WS_PONG_WAIT(%v)，使用默认值 %v / %v",
+			pingInterval, pongWait, defaultPingInterval, defaultPongWait)
+		return defaultPongWait, defaultPingInterval
+	}
+	return pongWait, pingInterval
+}
+
+// Send 将消息加入发送队列，队列已满时断开连接
+func (c *WSClient) Send(message interface{}) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+
+	select {
+	case c.send <- message:
+		return nil
+	default:
+		log.Printf("连接 %s 发送队列已满，断开连接", c.remoteAddr)
+		c.Close(CloseSlowConsumer, "send queue full")
+		return ErrClientClosed
+	}
+}
+
+// Close 发送带原因的关闭帧并关闭连接，可重复调用
+func (c *WSClient) Close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(code, reason),
+			time.Now().Add(time.Second))
+		c.conn.Close()
+	})
+}
+
+// ExtendReadDeadline 收到客户端消息后延长读超时
+func (c *WSClient) ExtendReadDeadline() {
+	c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
+}
+
+// writePump 依次写出队列中的消息并定期发送ping控制帧
+func (c *WSClient) writePump() {
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteJSON(message); err != nil {
+				log.Printf("连接 %s 写入消息失败: %v", c.remoteAddr, err)
+				c.Close(websocket.CloseInternalServerErr, "write failed")
+				return
+			}
+			atomic.AddUint64(&c.messagesSent, 1)
+		case <-ticker.C:
+			payload := strconv.FormatInt(time.Now().UnixNano(), 10)
+			if err := c.conn.WriteControl(websocket.PingMessage, []byte(payload), time.Now().Add(writeWait)); err != nil {
+				log.Printf("连接 %s 发送ping失败: %v", c.remoteAddr, err)
+				c.Close(CloseHeartbeatTimeout, "ping failed")
+				return
+			}
+		}
+	}
+}
+
+// info 获取连接诊断信息
+func (c *WSClient) info() ConnectionInfo {
+	return ConnectionInfo{
+		UserID:        c.userID,
+		WalletAddress: c.walletAddress,
+		PoolID:        c.poolID,
+		Spectator:     c.userID == 0,
+		RemoteAddr:    c.remoteAddr,
+		ConnectedAt:   c.connectedAt,
+		RTTMillis:     float64(atomic.LoadInt64(&c.rtt)) / float64(time.Millisecond),
+		QueueDepth:    len(c.send),
+		MessagesSent:  atomic.LoadUint64(&c.messagesSent),
+	}
+}
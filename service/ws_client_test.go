@@ -0,0 +1,19 @@
+package service
+
+import "testing"
+
+// ping间隔不小于pongWait时回退到默认的心跳配置
+func TestHeartbeatConfig(t *testing.T) {
+	t.Setenv("WS_PONG_WAIT", "10s")
+	t.Setenv("WS_PING_INTERVAL", "4s")
+	if pongWait, pingInterval := heartbeatConfig(); pongWait.String() != "10s" || pingInterval.String() != "4s" {
+		t.Fatalf("valid config = %v / %v", pongWait, pingInterval)
+	}
+
+	for _, ping := range []string{"10s", "1m"} {
+		t.Setenv("WS_PING_INTERVAL", ping)
+		if pongWait, pingInterval := heartbeatConfig(); pongWait != defaultPongWait || pingInterval != defaultPingInterval {
+			t.Fatalf("ping %s: config = %v / %v", ping, pongWait, pingInterval)
+		}
+	}
+}
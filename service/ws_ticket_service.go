@@ -0,0 +1,28 @@
+package service
+
+import (
+	"singo/auth"
+	"singo/model"
+	"singo/serializer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WSTicketService 签发WebSocket连接票据的服务
+type WSTicketService struct{}
+
+// Issue 为用户签发绑定来源的一次性连接票据
+func (service *WSTicketService) Issue(user *model.User, sessionID string, origin string) serializer.Response {
+	ticket, err := auth.IssueWSTicket(user.ID, user.WalletAddress, sessionID, origin)
+	if err != nil {
+		return serializer.Err(serializer.CodeEncryptError, "Failed to issue ticket", err)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"ticket":    ticket,
+			"expiresIn": int64(auth.WSTicketTTL.Seconds()),
+		},
+	}
+}
@@ -0,0 +1,166 @@
+package test
+
+import (
+	"net/http"
+	"singo/auth"
+	"singo/model"
+	"singo/serializer"
+	"strings"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+)
+
+// 管理员创建、使用与吊销API密钥
+func TestAPIKeys(t *testing.T) {
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	token := loginToken(e, wallet)
+
+	// 普通玩家无权管理API密钥
+	e.POST("/api/v1/admin/api-keys").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(map[string]interface{}{"name": "bot", "scopes": []string{auth.ScopePoolsRead}}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(403)
+
+	bootstrapAdmin(t, wallet.address)
+
+	data := e.POST("/api/v1/admin/api-keys").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(map[string]interface{}{"name": "bot", "scopes": []string{auth.ScopePoolsRead}, "rateLimit": 3}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object()
+	key := data.Value("key").String().Raw()
+	keyID := data.Value("id").Number().Raw()
+
+	// 未携带密钥或授权范围不足
+	e.GET("/api/v1/integrations/pools").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(401)
+	e.GET("/api/v1/integrations/leaderboard").
+		WithHeader("X-API-Key", key).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(403)
+
+	// API密钥不能访问需要用户登录的接口
+	e.GET("/api/v1/users/me").
+		WithHeader("X-API-Key", key).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(401)
+
+	// 授权范围内可以访问，超过每分钟次数后限流
+	e.GET("/api/v1/integrations/pools").
+		WithHeader("X-API-Key", key).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(0)
+	e.GET("/api/v1/integrations/pools").
+		WithHeader("X-API-Key", key).
+		Expect().
+		Status(http.StatusTooManyRequests)
+
+	// 吊销后立即失效
+	e.DELETE("/api/v1/admin/api-keys/"+formatID(keyID)).
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("revoked").Equal(true)
+	e.GET("/api/v1/integrations/pools").
+		WithHeader("X-API-Key", key).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(401)
+}
+
+// 密钥只以哈希保存，列表中只展示前缀；授权范围必须有效，吊销可重复调用
+func TestAPIKeyStorage(t *testing.T) {
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	token := loginToken(e, wallet)
+	bootstrapAdmin(t, wallet.address)
+
+	e.POST("/api/v1/admin/api-keys").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(map[string]interface{}{"name": "bot", "scopes": []string{"write:pools"}}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", serializer.CodeParamErr)
+
+	data := e.POST("/api/v1/admin/api-keys").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(map[string]interface{}{"name": "stats", "scopes": []string{auth.ScopeLeaderboardsRead}}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object()
+	data.ValueEqual("rateLimit", auth.DefaultAPIKeyRateLimit)
+	key := data.Value("key").String().Raw()
+	keyID := data.Value("id").Number().Raw()
+	if !strings.HasPrefix(key, auth.APIKeyPrefix) {
+		t.Fatalf("key = %q", key)
+	}
+
+	var stored model.APIKey
+	if err := model.DB.First(&stored, uint(keyID)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.KeyHash != auth.HashAPIKey(key) || stored.Prefix != key[:auth.APIKeyDisplayLength] || strings.Contains(stored.KeyHash, key[len(auth.APIKeyPrefix):]) {
+		t.Fatalf("stored key = %+v", stored)
+	}
+
+	// 使用后记录最近使用时间
+	e.GET("/api/v1/integrations/leaderboard").
+		WithHeader("X-API-Key", key).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", 0)
+
+	var listed *httpexpect.Object
+	for _, v := range e.GET("/api/v1/admin/api-keys").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("keys").Array().Iter() {
+		if v.Object().Value("id").Number().Raw() == keyID {
+			listed = v.Object()
+		}
+	}
+	if listed == nil {
+		t.Fatal("key not listed")
+	}
+	listed.ValueEqual("prefix", key[:auth.APIKeyDisplayLength]).
+		ValueEqual("scopes", []string{auth.ScopeLeaderboardsRead}).
+		ValueEqual("revoked", false).
+		NotContainsKey("key").
+		NotContainsKey("keyHash").
+		ContainsKey("lastUsedAt")
+
+	// 前缀正确但未签发的密钥无效
+	e.GET("/api/v1/integrations/leaderboard").
+		WithHeader("X-API-Key", auth.APIKeyPrefix+"0000").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", 401)
+
+	for i := 0; i < 2; i++ {
+		e.DELETE("/api/v1/admin/api-keys/"+formatID(keyID)).
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().
+			Status(http.StatusOK).JSON().Object().
+			Value("data").Object().
+			ValueEqual("revoked", true).
+			ContainsKey("revokedAt")
+	}
+	e.DELETE("/api/v1/admin/api-keys/999999").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", serializer.CodeParamErr)
+}
@@ -0,0 +1,58 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"singo/model"
+	"singo/repository"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 并发为同一用户创建青蛙：唯一索引保证只有一只激活的青蛙
+func TestOneActiveFrogPerUser(t *testing.T) {
+	const attempts = 20
+	repos := repository.NewGorm(model.DB)
+
+	user := model.NewUser(fmt.Sprintf("frog-%d", time.Now().UnixNano()))
+	if err := repos.Users.Create(&user); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	created := make(chan uint, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			frog := model.NewFrog(user.ID)
+			err := repos.Frogs.Create(&frog)
+			if err == nil {
+				created <- frog.ID
+			} else if !errors.Is(err, repository.ErrDuplicate) {
+				t.Errorf("create frog: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(created)
+
+	if len(created) != 1 {
+		t.Fatalf("created %d active frogs, want 1", len(created))
+	}
+
+	// 停用后可以再次激活
+	frog, err := repos.Frogs.GetActiveByUser(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frog.IsActive = false
+	if err := repos.Frogs.Save(frog); err != nil {
+		t.Fatal(err)
+	}
+	next := model.NewFrog(user.ID)
+	if err := repos.Frogs.Create(&next); err != nil {
+		t.Fatalf("create after deactivation: %v", err)
+	}
+}
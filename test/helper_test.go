@@ -0,0 +1,114 @@
+package test
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+	"github.com/gorilla/websocket"
+	"github.com/mr-tron/base58"
+)
+
+// testWallet 测试用的Solana钱包
+type testWallet struct {
+	address string
+	key     ed25519.PrivateKey
+}
+
+// newTestWallet 生成随机钱包
+func newTestWallet(t *testing.T) *testWallet {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testWallet{address: base58.Encode(pub), key: priv}
+}
+
+// sign 对消息签名，返回Base58编码的签名
+func (w *testWallet) sign(message string) string {
+	return base58.Encode(ed25519.Sign(w.key, []byte(message)))
+}
+
+// login 获取nonce、签名并登录，返回登录响应的data
+func login(e *httpexpect.Expect, wallet *testWallet) *httpexpect.Object {
+	message := e.GET("/api/v1/auth/nonce").
+		WithQuery("walletAddress", wallet.address).
+		Expect().
+		JSON().Object().
+		Value("data").Object().
+		Value("message").String().Raw()
+
+	obj := e.POST("/api/v1/auth/login").
+		WithJSON(map[string]interface{}{
+			"walletAddress": wallet.address,
+			"message":       message,
+			"signature":     wallet.sign(message),
+		}).
+		Expect().
+		JSON().Object()
+	obj.Value("code").Equal(0)
+	return obj.Value("data").Object()
+}
+
+// loginToken 登录并返回访问令牌
+func loginToken(e *httpexpect.Expect, wallet *testWallet) string {
+	return login(e, wallet).Value("token").String().Raw()
+}
+
+// formatID 将JSON中的数字ID转为路径参数
+func formatID(id float64) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// wsTicket 用访问令牌换取WebSocket连接票据，origin为空时不带Origin请求头
+func wsTicket(e *httpexpect.Expect, token string, origin string) string {
+	req := e.POST("/api/v1/game/ws-ticket").
+		WithHeader("Authorization", "Bearer "+token)
+	if origin != "" {
+		req = req.WithHeader("Origin", origin)
+	}
+	return req.Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("ticket").String().Raw()
+}
+
+// wsURL 测试服务器上指定路径的WebSocket地址
+func wsURL(server *httptest.Server, path string) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + path
+}
+
+// dialGame 使用票据连接游戏WebSocket
+func dialGame(t *testing.T, server *httptest.Server, ticket string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(server, "/api/v1/game/ws?ticket="+ticket), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// readUntil 读取消息直到match返回true，跳过初始状态等其他消息
+func readUntil(t *testing.T, conn *websocket.Conn, match func(msg map[string]interface{}) bool) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read websocket: %v", err)
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		if match(msg) {
+			return msg
+		}
+	}
+}
@@ -2,12 +2,18 @@ package test
 
 import (
 	"os"
+	"path/filepath"
+	"singo/auth"
 	"singo/cache"
 	"singo/conf"
+	"singo/migrate"
 	"singo/model"
+	"singo/repository"
 	"singo/server"
+	"singo/service"
 	"singo/util"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
@@ -25,8 +31,9 @@ func init() {
 
 // Init 初始化配置项
 func confInit() {
-	// 从本地读取环境变量
+	// 从本地读取环境变量，未配置的项使用无外部依赖的默认值
 	godotenv.Load()
+	setDefaultEnv()
 
 	// 设置日志级别
 	util.BuildLogger(os.Getenv("LOG_LEVEL"))
@@ -37,6 +44,57 @@ func confInit() {
 	}
 
 	// 连接数据库
-	model.Database(os.Getenv("MYSQL_DSN"))
+	model.Database(conf.DatabaseConfig())
 	cache.Redis()
+
+	// 执行数据库迁移
+	migrator, err := migrate.New(model.DB)
+	if err != nil {
+		util.Log().Panic("加载迁移文件失败", err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		util.Log().Panic("执行迁移失败", err)
+	}
+
+	// 构造服务实例
+	service.Init(repository.NewGorm(model.DB))
+
+	// 加载JWT签名密钥
+	if err := auth.LoadKeys(); err != nil {
+		util.Log().Panic("JWT签名密钥加载失败", err)
+	}
+}
+
+// testDefaults 集成测试的默认配置：SQLite临时库 + 进程内Redis
+var testDefaults = map[string]string{
+	"JWT_SIGNING_KEYS": "test:testSecret",
+	"SIWS_DOMAIN":      "localhost:3000",
+	"SIWS_URI":         "http://localhost:3000",
+	"RATE_LIMIT_AUTH":  "1000/1m",
+	"LOG_LEVEL":        "error",
+}
+
+// setDefaultEnv 为未设置的环境变量填充测试默认值
+func setDefaultEnv() {
+	if os.Getenv("DATABASE_DSN") == "" && os.Getenv("MYSQL_DSN") == "" {
+		dir, err := os.MkdirTemp("", "singo-test-")
+		if err != nil {
+			panic(err)
+		}
+		os.Setenv("DATABASE_DSN", "sqlite://"+filepath.Join(dir, "test.db"))
+	}
+	for key, value := range testDefaults {
+		if os.Getenv(key) == "" {
+			os.Setenv(key, value)
+		}
+	}
+
+	// 未配置Redis时使用进程内的miniredis，miniredis只在测试中引入
+	if addr := os.Getenv("REDIS_ADDR"); addr == "" || addr == "memory://" {
+		server, err := miniredis.Run()
+		if err != nil {
+			panic(err)
+		}
+		os.Setenv("REDIS_ADDR", server.Addr())
+	}
 }
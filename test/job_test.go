@@ -0,0 +1,130 @@
+package test
+
+import (
+	"errors"
+	"net/http"
+	"singo/auth"
+	"singo/model"
+	"singo/repository"
+	"singo/serializer"
+	"singo/service"
+	"testing"
+	"time"
+)
+
+// 去重键相同的未结束任务只写入一次，到期的任务只能被认领一次，死信可由管理员查看并重新排队
+func TestAdminJobDeadLetter(t *testing.T) {
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	token := loginToken(e, wallet)
+
+	// 普通玩家不能查看任务队列
+	e.GET("/api/v1/admin/jobs").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(403)
+
+	user, err := service.GetUserService().GetByWallet(wallet.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.GetUserService().ChangeRole(user, string(auth.RoleAdmin), nil, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := repository.NewGorm(model.DB).Jobs
+	for i := 0; i < 2; i++ {
+		job, err := model.NewJob("test.dead_letter", map[string]string{"wallet": wallet.address}, time.Now().Add(-time.Second), 3, "dead-letter:"+wallet.address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scheduled, err := jobs.Schedule(&job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scheduled != (i == 0) {
+			t.Fatalf("schedule %d: scheduled = %v", i, scheduled)
+		}
+	}
+
+	var id uint
+	var lockedUntil time.Time
+	claimed, err := jobs.Claim(50, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range claimed {
+		if job.Type == "test.dead_letter" {
+			id, lockedUntil = job.ID, *job.LockedUntil
+		}
+	}
+	if id == 0 {
+		t.Fatalf("job not claimed: %+v", claimed)
+	}
+	again, err := jobs.Claim(50, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range again {
+		if job.ID == id {
+			t.Fatal("job claimed twice within its lease")
+		}
+	}
+	// 租约不匹配时不能记录结果
+	if err := jobs.Bury(id, lockedUntil.Add(time.Second), 3, "stale"); !errors.Is(err, repository.ErrLeaseLost) {
+		t.Fatalf("bury with a stale lease: %v", err)
+	}
+	if err := jobs.Bury(id, lockedUntil, 3, "gave up"); err != nil {
+		t.Fatal(err)
+	}
+	if err := jobs.Finish(id, lockedUntil); !errors.Is(err, repository.ErrLeaseLost) {
+		t.Fatalf("finish after bury: %v", err)
+	}
+
+	data := e.GET("/api/v1/admin/jobs").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object()
+	data.ValueEqual("status", "dead")
+	data.Value("counts").Object().Value("dead").Number().Ge(1)
+	dead := data.Value("jobs").Array()
+	dead.Element(0).Object().
+		ValueEqual("id", id).
+		ValueEqual("type", "test.dead_letter").
+		ValueEqual("attempts", 3).
+		ValueEqual("lastError", "gave up").
+		Value("payload").Object().ValueEqual("wallet", wallet.address)
+
+	path := "/api/v1/admin/jobs/" + formatID(float64(id)) + "/retry"
+	e.POST(path).
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		ValueEqual("status", "pending")
+
+	// 已重新排队的任务不是死信
+	e.POST(path).
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(serializer.CodeParamErr)
+
+	e.GET("/api/v1/admin/jobs").
+		WithHeader("Authorization", "Bearer "+token).
+		WithQuery("status", "pending").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("jobs").Array().Element(0).Object().
+		ValueEqual("id", id).
+		ValueEqual("attempts", 0)
+
+	// 死信的去重键已释放，可以再次安排同一任务
+	job, _ := model.NewJob("test.dead_letter", nil, time.Now().Add(time.Hour), 3, "dead-letter:"+wallet.address)
+	if scheduled, err := jobs.Schedule(&job); err != nil || !scheduled {
+		t.Fatalf("schedule after bury: %v (%v)", scheduled, err)
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"net/http"
+	"singo/auth"
+	"singo/model"
+	"singo/serializer"
+	"singo/service"
+	"testing"
+)
+
+// 财务人员核对账本：正常记账无偏差，直接修改余额会被报告
+func TestLedgerReconcile(t *testing.T) {
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	token := loginToken(e, wallet)
+
+	user, err := service.GetUserService().GetByWallet(wallet.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.GetUserService().ChangeRole(user, string(auth.RoleFinance), nil, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 通过账本发放并提取奖励
+	rewards := service.GetRewardService()
+	pool := model.NewPool()
+	if err := model.DB.Create(&pool).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < model.MaxPoolPlayers; i++ {
+		if err := rewards.RecordActivation(pool.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reward, err := rewards.AwardPrize(user, &pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rewards.Settle(user, reward/2, "ledger-settle-tx"); err != nil {
+		t.Fatal(err)
+	}
+
+	result := e.GET("/api/v1/admin/ledger/reconcile").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object()
+	result.Value("reconciliation").Object().Value("imbalanceLamports").Equal(0)
+	for _, v := range result.Value("reconciliation").Object().Value("drifts").Array().Iter() {
+		if uint(v.Object().Value("userId").Number().Raw()) == user.ID {
+			t.Fatalf("unexpected drift: %v", v.Raw())
+		}
+	}
+
+	// 绕过账本修改余额
+	if err := model.DB.Model(user).Update("unclaimed_rewards", 5).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	drifts := e.GET("/api/v1/admin/ledger/reconcile").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("reconciliation").Object().
+		Value("drifts").Array()
+
+	found := false
+	for _, v := range drifts.Iter() {
+		d := v.Object()
+		if uint(d.Value("userId").Number().Raw()) == user.ID && d.Value("account").String().Raw() == string(model.AccountUserUnclaimed) {
+			d.Value("cachedLamports").Equal(5 * model.LamportsPerSOL)
+			d.Value("ledgerLamports").Equal(model.ToLamports(reward - reward/2))
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("drift not reported")
+	}
+}
+
+// 绕过账本写入的不平衡分录在对账时逐条报告，手动对账返回错误码
+func TestLedgerReconcileUnbalancedEntry(t *testing.T) {
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	token := loginToken(e, wallet)
+	user, err := service.GetUserService().GetByWallet(wallet.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.GetUserService().ChangeRole(user, string(auth.RoleFinance), nil, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := model.LedgerEntry{Kind: model.EntryForfeit, Reference: "test:unbalanced"}
+	if err := model.DB.Create(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	posting := model.LedgerPosting{EntryID: entry.ID, Account: model.AccountHouseRake, Amount: 7}
+	if err := model.DB.Create(&posting).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 测试共用数据库，结束后删除，避免影响其他对账测试
+	defer func() {
+		model.DB.Delete(&posting)
+		model.DB.Delete(&entry)
+	}()
+
+	res := e.GET("/api/v1/admin/ledger/reconcile").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	res.ValueEqual("code", serializer.CodeLedgerMismatch)
+	data := res.Value("data").Object()
+	data.ValueEqual("ok", false)
+	reconciliation := data.Value("reconciliation").Object()
+	reconciliation.Value("imbalanceLamports").Number().NotEqual(0)
+	reconciliation.Value("unbalancedEntries").Array().Contains(entry.ID)
+}
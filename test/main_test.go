@@ -2,7 +2,7 @@ package test
 
 import (
 	"net/http"
-	"singo/util"
+	"singo/serializer"
 	"testing"
 
 	"github.com/gavv/httpexpect"
@@ -35,53 +35,89 @@ func TestQngUserLogin(t *testing.T) {
 	obj.Value("msg").Equal("Pong")
 }
 
-// 登录和注册
+// 钱包登录、获取用户信息、刷新令牌与登出
 func TestUserAPI(t *testing.T) {
 	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
 
-	nickName := util.RandStringRunes(8)
-	userName := util.RandStringRunes(8)
-	pwd := "12345678"
-	data := map[string]interface{}{
-		"nickname":         nickName,
-		"user_name":        userName,
-		"password":         pwd,
-		"password_confirm": pwd,
-	}
-
-	// 注册验证
-	obj := e.POST("/api/v1/user/register").
-		WithHeader("Content-Type", "application/json").
-		WithJSON(data).
+	// 错误的签名无法登录
+	nonce := e.GET("/api/v1/auth/nonce").
+		WithQuery("walletAddress", wallet.address).
 		Expect().
 		Status(http.StatusOK).JSON().Object()
-	obj.Value("code").Equal(0)
-	resData := obj.Value("data").Object()
-	resData.Value("user_name").Equal(userName)
-	resData.Value("nickname").Equal(nickName)
+	nonce.Value("code").Equal(0)
+	message := nonce.Value("data").Object().Value("message").String().Raw()
 
-	// 验证错误的密码无法登陆
-	obj = e.POST("/api/v1/user/login").
-		WithHeader("Content-Type", "application/json").
+	other := newTestWallet(t)
+	obj := e.POST("/api/v1/auth/login").
 		WithJSON(map[string]interface{}{
-			"user_name": userName,
-			"password":  "66666666",
+			"walletAddress": wallet.address,
+			"message":       message,
+			"signature":     other.sign(message),
 		}).
 		Expect().
 		Status(http.StatusOK).JSON().Object()
-	obj.Value("code").Equal(40001)
+	obj.Value("code").Equal(serializer.CodeParamErr)
 
-	// 验证正确的密码无法登陆
-	obj = e.POST("/api/v1/user/login").
-		WithHeader("Content-Type", "application/json").
-		WithJSON(map[string]interface{}{
-			"user_name": userName,
-			"password":  pwd,
-		}).
+	// 正确的签名可以登录，nonce只能使用一次
+	loginData := map[string]interface{}{
+		"walletAddress": wallet.address,
+		"message":       message,
+		"signature":     wallet.sign(message),
+	}
+	data := e.POST("/api/v1/auth/login").
+		WithJSON(loginData).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object()
+	data.Value("isNewUser").Equal(true)
+	data.Value("user").Object().Value("walletAddress").Equal(wallet.address)
+	token := data.Value("token").String().Raw()
+	refreshToken := data.Value("refreshToken").String().Raw()
+
+	obj = e.POST("/api/v1/auth/login").
+		WithJSON(loginData).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("code").Equal(serializer.CodeParamErr)
+
+	// 未登录无法访问
+	e.GET("/api/v1/users/me").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(401)
+
+	obj = e.GET("/api/v1/users/me").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	obj.Value("code").Equal(0)
+	obj.Value("data").Object().Value("user").Object().Value("role").Equal("player")
+
+	// 刷新令牌只能使用一次
+	obj = e.POST("/api/v1/auth/refresh").
+		WithJSON(map[string]interface{}{"refreshToken": refreshToken}).
 		Expect().
 		Status(http.StatusOK).JSON().Object()
 	obj.Value("code").Equal(0)
-	resData = obj.Value("data").Object()
-	resData.Value("user_name").Equal(userName)
-	resData.Value("nickname").Equal(nickName)
+	newToken := obj.Value("data").Object().Value("token").String().Raw()
+
+	e.POST("/api/v1/auth/refresh").
+		WithJSON(map[string]interface{}{"refreshToken": refreshToken}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(serializer.CodeCheckLogin)
+
+	// 登出后令牌失效
+	e.POST("/api/v1/auth/logout").
+		WithHeader("Authorization", "Bearer "+newToken).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(0)
+
+	e.GET("/api/v1/users/me").
+		WithHeader("Authorization", "Bearer "+newToken).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(401)
 }
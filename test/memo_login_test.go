@@ -0,0 +1,83 @@
+package test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"singo/serializer"
+	"singo/service"
+	"testing"
+
+	"github.com/mr-tron/base58"
+)
+
+// memoTransaction 构造由钱包签名、只包含一条Memo指令的legacy交易，返回base64编码
+func (w *testWallet) memoTransaction(t *testing.T, memo string) string {
+	program, err := base58.Decode(service.MemoProgramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 消息头：1个签名账户，0个只读签名账户，1个只读非签名账户（Memo程序）
+	message := []byte{1, 0, 1, 2}
+	message = append(message, w.key.Public().(ed25519.PublicKey)...)
+	message = append(message, program...)
+	message = append(message, make([]byte, 32)...) // recent blockhash
+	// 1条指令：程序下标1，账户[0]，数据为备忘录
+	message = append(message, 1, 1, 1, 0)
+	message = append(message, compactU16(len(memo))...)
+	message = append(message, memo...)
+
+	raw := append([]byte{1}, ed25519.Sign(w.key, message)...)
+	return base64.StdEncoding.EncodeToString(append(raw, message...))
+}
+
+// compactU16 编码Solana的compact-u16变长整数
+func compactU16(n int) []byte {
+	var out []byte
+	for n >= 0x80 {
+		out = append(out, byte(n&0x7f)|0x80)
+		n >>= 7
+	}
+	return append(out, byte(n))
+}
+
+// 硬件钱包以签名的备忘录交易登录，nonce同样只能使用一次
+func TestMemoLogin(t *testing.T) {
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+
+	message := e.GET("/api/v1/auth/nonce").
+		WithQuery("walletAddress", wallet.address).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("message").String().Raw()
+
+	request := map[string]interface{}{
+		"walletAddress": wallet.address,
+		"transaction":   wallet.memoTransaction(t, message),
+	}
+	token := e.POST("/api/v1/auth/login-tx").
+		WithJSON(request).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", 0).
+		Value("data").Object().
+		Value("token").String().Raw()
+
+	e.GET("/api/v1/users/me").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("user").Object().
+		ValueEqual("walletAddress", wallet.address)
+
+	e.POST("/api/v1/auth/login-tx").
+		WithJSON(request).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", serializer.CodeParamErr).
+		ValueEqual("msg", "Invalid or used nonce")
+}
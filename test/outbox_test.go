@@ -0,0 +1,89 @@
+package test
+
+import (
+	"errors"
+	"singo/model"
+	"singo/repository"
+	"testing"
+	"time"
+)
+
+// 发件箱与状态变更同一事务：回滚时都不保留，提交后可被认领且租约内不会重复认领
+func TestOutboxTransaction(t *testing.T) {
+	repos := repository.NewGorm(model.DB)
+	errAbort := errors.New("abort")
+
+	var poolID uint
+	err := repos.Transaction(func(tx *repository.Repositories) error {
+		pool := model.NewPool()
+		if err := tx.Pools.Create(&pool); err != nil {
+			return err
+		}
+		poolID = pool.ID
+		message, err := model.NewOutboxMessage("test", pool.ID, map[string]uint{"poolId": pool.ID})
+		if err != nil {
+			return err
+		}
+		if err := tx.Outbox.Add(message); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := repos.Pools.Get(poolID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("pool survived rollback: %v", err)
+	}
+	var count int64
+	model.DB.Model(&model.OutboxMessage{}).Where("pool_id = ?", poolID).Count(&count)
+	if count != 0 {
+		t.Fatalf("outbox kept %d messages after rollback", count)
+	}
+
+	// 提交
+	message, err := model.NewOutboxMessage("test", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repos.Transaction(func(tx *repository.Repositories) error {
+		return tx.Outbox.Add(message)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := repos.Outbox.Claim(1000, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found *model.OutboxMessage
+	for i := range claimed {
+		if claimed[i].EventID == message.EventID {
+			found = &claimed[i]
+		}
+	}
+	if found == nil {
+		t.Fatal("committed message was not claimed")
+	}
+
+	// 租约期内不会被再次认领
+	again, err := repos.Outbox.Claim(1000, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range again {
+		if m.ID == found.ID {
+			t.Fatal("message claimed twice within the lease")
+		}
+	}
+
+	if err := repos.Outbox.MarkDispatched(found.ID); err != nil {
+		t.Fatal(err)
+	}
+	var saved model.OutboxMessage
+	model.DB.First(&saved, found.ID)
+	if saved.DispatchedAt == nil || saved.Attempts != 1 {
+		t.Fatalf("message not marked dispatched: %+v", saved)
+	}
+}
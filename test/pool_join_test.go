@@ -0,0 +1,97 @@
+package test
+
+import (
+	"fmt"
+	"singo/model"
+	"singo/repository"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 100个并发加入：奖池不超员、序号不重复、同时只有一个收集中的奖池
+func TestConcurrentPoolJoin(t *testing.T) {
+	const joins = 100
+	repos := repository.NewGorm(model.DB)
+
+	frogs := make([]model.Frog, joins)
+	prefix := fmt.Sprintf("join-%d", time.Now().UnixNano())
+	for i := range frogs {
+		user := model.NewUser(fmt.Sprintf("%s-%d", prefix, i))
+		if err := repos.Users.Create(&user); err != nil {
+			t.Fatal(err)
+		}
+		frogs[i] = model.NewFrog(user.ID)
+		if err := repos.Frogs.Create(&frogs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, joins)
+	for i := range frogs {
+		wg.Add(1)
+		go func(frog model.Frog) {
+			defer wg.Done()
+			if _, _, err := repos.Pools.Join(frog.ID, fmt.Sprintf("%s-%d", prefix, frog.UserID)); err != nil {
+				errs <- err
+			}
+		}(frogs[i])
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("join failed: %v", err)
+	}
+
+	frogIDs := make([]uint, 0, joins)
+	for _, frog := range frogs {
+		frogIDs = append(frogIDs, frog.ID)
+	}
+	var poolIDs []uint
+	if err := model.DB.Model(&model.PoolParticipant{}).Where("frog_id IN ?", frogIDs).
+		Distinct().Pluck("pool_id", &poolIDs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	joined := 0
+	for _, poolID := range poolIDs {
+		pool, err := repos.Pools.Get(poolID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		participants, err := repos.Participants.ListByPool(poolID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(participants) > model.MaxPoolPlayers {
+			t.Fatalf("pool %d overfilled with %d participants", poolID, len(participants))
+		}
+		if pool.CurrentPlayers != len(participants) {
+			t.Fatalf("pool %d current players %d, participants %d", poolID, pool.CurrentPlayers, len(participants))
+		}
+		for i, p := range participants {
+			if p.SerialNumber != i+1 {
+				t.Fatalf("pool %d serial numbers not contiguous: %d at %d", poolID, p.SerialNumber, i)
+			}
+			for _, id := range frogIDs {
+				if p.FrogID == id {
+					joined++
+				}
+			}
+		}
+		if pool.CurrentPlayers == model.MaxPoolPlayers && pool.Status != model.PoolStatusActive {
+			t.Fatalf("full pool %d is %s", poolID, pool.Status)
+		}
+	}
+	if joined != joins {
+		t.Fatalf("%d of %d frogs joined a pool", joined, joins)
+	}
+
+	var collecting int64
+	model.DB.Model(&model.PrizePool{}).Where("collecting_slot IS NOT NULL").Count(&collecting)
+	if collecting > 1 {
+		t.Fatalf("%d pools marked as collecting", collecting)
+	}
+}
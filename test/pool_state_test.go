@@ -0,0 +1,67 @@
+package test
+
+import (
+	"errors"
+	"singo/model"
+	"singo/repository"
+	"sync"
+	"testing"
+)
+
+// 并发切换同一奖池：只有一个成功，其余返回 ErrInvalidTransition，历史中只有一条记录
+func TestConcurrentPoolTransition(t *testing.T) {
+	const workers = 10
+	repos := repository.NewGorm(model.DB)
+
+	pool := model.NewPool()
+	pool.Status = model.PoolStatusActive
+	pool.CollectingSlot = nil
+	if err := repos.Pools.Create(&pool); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(to model.PoolStatus) {
+			defer wg.Done()
+			// 每个协程持有读取时的副本，模拟并发请求
+			stale := pool
+			results <- repos.Transaction(func(tx *repository.Repositories) error {
+				_, err := tx.Pools.Transition(&stale, to, "concurrent", 0)
+				return err
+			})
+		}([]model.PoolStatus{model.PoolStatusCompleted, model.PoolStatusCancelled}[i%2])
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+		} else if !errors.Is(err, model.ErrInvalidTransition) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d transitions succeeded, want 1", succeeded)
+	}
+
+	saved, err := repos.Pools.Get(pool.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status.Live() || saved.CompletedAt == nil {
+		t.Fatalf("pool = %+v", saved)
+	}
+
+	history, err := repos.Pools.ListTransitions(pool.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].FromStatus != model.PoolStatusActive || history[0].ToStatus != saved.Status {
+		t.Fatalf("history = %+v", history)
+	}
+}
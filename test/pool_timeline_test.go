@@ -0,0 +1,78 @@
+package test
+
+import (
+	"net/http"
+	"singo/auth"
+	"singo/model"
+	"singo/repository"
+	"singo/serializer"
+	"singo/service"
+	"testing"
+)
+
+// 管理员切换奖池状态后，状态历史与事件时间线都记录该变更
+func TestAdminPoolTimeline(t *testing.T) {
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	token := loginToken(e, wallet)
+
+	user, err := service.GetUserService().GetByWallet(wallet.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.GetUserService().ChangeRole(user, string(auth.RoleAdmin), nil, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	pool := model.NewPool()
+	pool.Status = model.PoolStatusActive
+	pool.CollectingSlot = nil
+	if err := repository.NewGorm(model.DB).Pools.Create(&pool); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/v1/admin/pools/" + formatID(float64(pool.ID))
+
+	e.POST(path+"/status").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(map[string]string{"status": "disputed", "reason": "support ticket"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("status").Equal("disputed")
+
+	// 没有获胜者的奖池不能进入结算
+	e.POST(path+"/status").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(map[string]string{"status": "settling", "reason": "no winner"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(serializer.CodeInvalidTransition)
+
+	transitions := e.GET(path+"/history").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("transitions").Array()
+	transitions.Length().Equal(1)
+	transitions.Element(0).Object().ValueEqual("to", "disputed").ValueEqual("actorUserId", user.ID)
+
+	data := e.GET(path+"/timeline").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object()
+	timeline := data.Value("timeline").Array()
+	timeline.Length().Equal(1)
+	timeline.Element(0).Object().ValueEqual("seq", 1).ValueEqual("type", "pool_status_changed")
+	data.Value("state").Object().ValueEqual("status", "disputed").ValueEqual("lastSeq", 1)
+
+	// 在第一个事件之前的时刻重放得到空状态
+	e.GET(path+"/timeline").
+		WithHeader("Authorization", "Bearer "+token).
+		WithQuery("at", "1").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("state").Object().ValueEqual("status", "").ValueEqual("lastSeq", 0)
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"singo/auth"
+	"singo/serializer"
+	"singo/service"
+	"testing"
+
+	"github.com/gavv/httpexpect"
+	"github.com/gorilla/websocket"
+)
+
+// adminRoutes 管理接口及所需权限
+var adminRoutes = []struct {
+	method, path, permission string
+}{
+	{"GET", "/api/v1/admin/connections", auth.PermConnectionsRead},
+	{"PUT", "/api/v1/admin/users/some-wallet/role", auth.PermRolesManage},
+	{"GET", "/api/v1/admin/role-audit", auth.PermRolesManage},
+	{"GET", "/api/v1/admin/api-keys", auth.PermAPIKeysManage},
+	{"POST", "/api/v1/admin/api-keys", auth.PermAPIKeysManage},
+	{"DELETE", "/api/v1/admin/api-keys/1", auth.PermAPIKeysManage},
+	{"GET", "/api/v1/admin/ledger/reconcile", auth.PermRewardsRead},
+	{"POST", "/api/v1/admin/pools/1/status", auth.PermPoolsManage},
+	{"GET", "/api/v1/admin/pools/1/history", auth.PermPoolsRead},
+	{"GET", "/api/v1/admin/pools/1/timeline", auth.PermPoolsRead},
+	{"GET", "/api/v1/admin/jobs", auth.PermJobsManage},
+	{"POST", "/api/v1/admin/jobs/1/retry", auth.PermJobsManage},
+	{"GET", "/api/v1/admin/metrics", auth.PermMetricsRead},
+}
+
+// bootstrapAdmin 通过ADMIN_WALLETS将钱包设为管理员
+func bootstrapAdmin(t *testing.T, wallet string) {
+	t.Setenv("ADMIN_WALLETS", " ,"+wallet+" ")
+	service.BootstrapAdminWallets()
+}
+
+// expectDenied 请求被权限中间件拒绝
+func expectDenied(e *httpexpect.Expect, token, method, path string) {
+	e.Request(method, path).
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", serializer.CodeNoRightErr).
+		ValueEqual("msg", "Permission denied")
+}
+
+// 普通玩家访问任何管理接口都被拒绝，未登录时要求登录
+func TestAdminRoutesDenyPlayer(t *testing.T) {
+	e := getHttpExpect(t)
+	token := loginToken(e, newTestWallet(t))
+
+	for _, route := range adminRoutes {
+		if auth.HasPermission(string(auth.RolePlayer), route.permission) {
+			t.Fatalf("player has %s", route.permission)
+		}
+		expectDenied(e, token, route.method, route.path)
+
+		e.Request(route.method, route.path).
+			Expect().
+			Status(http.StatusOK).JSON().Object().
+			ValueEqual("code", serializer.CodeCheckLogin)
+	}
+}
+
+// 游戏接口需要参与游戏的权限，所有角色都拥有；权限不足的WebSocket请求以403拒绝
+func TestGameRoutesRequirePlayPermission(t *testing.T) {
+	for _, role := range []auth.Role{auth.RolePlayer, auth.RoleModerator, auth.RoleFinance, auth.RoleAdmin} {
+		if !auth.HasPermission(string(role), auth.PermGamePlay) {
+			t.Fatalf("%s cannot play", role)
+		}
+	}
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := getHttpExpect(t)
+	token := loginToken(e, newTestWallet(t))
+	conn, status := dialGameStatus(server, "ticket="+wsTicket(e, token, ""), "")
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("player websocket: status %d", status)
+	}
+	conn.Close()
+
+	_, resp, _ := websocket.DefaultDialer.Dial(wsURL(server, "/api/v1/admin/connections?ticket="+wsTicket(e, token, "")), nil)
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("admin websocket: %+v", resp)
+	}
+}
+
+// 管理员修改角色后立即按新角色鉴权，每次变更都记录审计
+func TestRoleChangeAndAudit(t *testing.T) {
+	e := getHttpExpect(t)
+	admin := newTestWallet(t)
+	adminToken := loginToken(e, admin)
+	bootstrapAdmin(t, admin.address)
+
+	target := newTestWallet(t)
+	targetToken := loginToken(e, target)
+	changeRole := func(token, wallet, role string) *httpexpect.Object {
+		return e.PUT("/api/v1/admin/users/"+wallet+"/role").
+			WithHeader("Authorization", "Bearer "+token).
+			WithJSON(map[string]string{"role": role, "reason": "rbac test"}).
+			Expect().
+			Status(http.StatusOK).JSON().Object()
+	}
+
+	// 运营角色可以查看连接，不能分配角色或查看账目
+	changeRole(adminToken, target.address, string(auth.RoleModerator)).
+		ValueEqual("code", 0).
+		Value("data").Object().
+		ValueEqual("role", auth.RoleModerator).
+		ValueEqual("permissions", auth.Permissions(string(auth.RoleModerator)))
+	e.GET("/api/v1/admin/connections").
+		WithHeader("Authorization", "Bearer "+targetToken).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", 0)
+	expectDenied(e, targetToken, "GET", "/api/v1/admin/ledger/reconcile")
+	changeRole(targetToken, admin.address, string(auth.RolePlayer)).
+		ValueEqual("code", serializer.CodeNoRightErr)
+
+	// 降回普通玩家后失去运营权限
+	changeRole(adminToken, target.address, string(auth.RolePlayer)).ValueEqual("code", 0)
+	expectDenied(e, targetToken, "GET", "/api/v1/admin/connections")
+
+	// 不能修改自己的角色，角色与用户必须存在
+	changeRole(adminToken, admin.address, string(auth.RolePlayer)).
+		ValueEqual("code", serializer.CodeNoRightErr)
+	changeRole(adminToken, target.address, "root").
+		ValueEqual("code", serializer.CodeParamErr)
+	changeRole(adminToken, newTestWallet(t).address, string(auth.RoleAdmin)).
+		ValueEqual("code", serializer.CodeParamErr)
+
+	logs := e.GET("/api/v1/admin/role-audit").
+		WithHeader("Authorization", "Bearer "+adminToken).
+		WithQuery("walletAddress", target.address).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("logs").Array()
+	logs.Length().Equal(2)
+	logs.Element(0).Object().
+		ValueEqual("targetWallet", target.address).
+		ValueEqual("oldRole", auth.RoleModerator).
+		ValueEqual("newRole", auth.RolePlayer).
+		ValueEqual("actorWallet", admin.address).
+		ValueEqual("reason", "rbac test")
+	logs.Element(1).Object().
+		ValueEqual("oldRole", auth.RolePlayer).
+		ValueEqual("newRole", auth.RoleModerator)
+
+	// 引导管理员记录为系统操作
+	e.GET("/api/v1/admin/role-audit").
+		WithHeader("Authorization", "Bearer "+adminToken).
+		WithQuery("walletAddress", admin.address).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("logs").Array().Element(0).Object().
+		ValueEqual("newRole", auth.RoleAdmin).
+		ValueEqual("actorWallet", "").
+		ValueEqual("reason", "bootstrap from ADMIN_WALLETS")
+}
+
+// 配置的管理员钱包不存在时先创建用户，重复引导不会重复记录
+func TestBootstrapAdminWallets(t *testing.T) {
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	bootstrapAdmin(t, wallet.address)
+	bootstrapAdmin(t, wallet.address)
+
+	token := loginToken(e, wallet)
+	e.GET("/api/v1/admin/role-audit").
+		WithHeader("Authorization", "Bearer "+token).
+		WithQuery("walletAddress", wallet.address).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("logs").Array().Length().Equal(1)
+}
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"singo/serializer"
+	"singo/service"
+	"testing"
+)
+
+// 会话列表与吊销
+func TestUserSessions(t *testing.T) {
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+
+	first := loginToken(e, wallet)
+	second := loginToken(e, wallet)
+
+	sessions := e.GET("/api/v1/users/sessions").
+		WithHeader("Authorization", "Bearer "+first).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("sessions").Array()
+	sessions.Length().Equal(2)
+
+	// 找到第二个会话并从第一个会话中吊销
+	var secondID string
+	for _, v := range sessions.Iter() {
+		session := v.Object()
+		if !session.Value("current").Boolean().Raw() {
+			secondID = session.Value("id").String().Raw()
+		}
+	}
+	if secondID == "" {
+		t.Fatal("second session not found")
+	}
+
+	e.DELETE("/api/v1/users/sessions/"+secondID).
+		WithHeader("Authorization", "Bearer "+first).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(0)
+
+	e.GET("/api/v1/users/me").
+		WithHeader("Authorization", "Bearer "+second).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(401)
+
+	// 吊销全部会话（保留当前）
+	third := loginToken(e, wallet)
+	e.DELETE("/api/v1/users/sessions").
+		WithQuery("exceptCurrent", true).
+		WithHeader("Authorization", "Bearer "+first).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("revoked").Equal(1)
+
+	e.GET("/api/v1/users/me").
+		WithHeader("Authorization", "Bearer "+third).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(401)
+	e.GET("/api/v1/users/me").
+		WithHeader("Authorization", "Bearer "+first).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(0)
+}
+
+// 吊销会话时关闭该会话的WebSocket连接
+func TestSessionRevokeClosesWebSocket(t *testing.T) {
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	data := login(e, wallet)
+	token := data.Value("token").String().Raw()
+	sessionID := data.Value("sessionId").String().Raw()
+
+	conn := dialGame(t, server, wsTicket(e, token, ""))
+	defer conn.Close()
+
+	e.DELETE("/api/v1/users/sessions/"+sessionID).
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("code").Equal(0)
+
+	expectClose(t, conn, service.CloseSessionRevoked)
+}
+
+// 会话记录登录设备；吊销或登出后该会话的刷新令牌失效，不能吊销其他用户的会话
+func TestSessionLifecycle(t *testing.T) {
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	const userAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+
+	message := e.GET("/api/v1/auth/nonce").
+		WithQuery("walletAddress", wallet.address).
+		Expect().
+		JSON().Object().
+		Value("data").Object().
+		Value("message").String().Raw()
+	data := e.POST("/api/v1/auth/login").
+		WithHeader("User-Agent", userAgent).
+		WithJSON(map[string]interface{}{
+			"walletAddress": wallet.address,
+			"message":       message,
+			"signature":     wallet.sign(message),
+		}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object()
+	token := data.Value("token").String().Raw()
+	refreshToken := data.Value("refreshToken").String().Raw()
+	sessionID := data.Value("sessionId").String().Raw()
+
+	e.GET("/api/v1/users/sessions").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("sessions").Array().Element(0).Object().
+		ValueEqual("id", sessionID).
+		ValueEqual("device", "Chrome on macOS").
+		ValueEqual("userAgent", userAgent).
+		ValueEqual("current", true)
+
+	// 其他用户看不到也不能吊销该会话
+	other := loginToken(e, newTestWallet(t))
+	e.DELETE("/api/v1/users/sessions/"+sessionID).
+		WithHeader("Authorization", "Bearer "+other).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", serializer.CodeParamErr)
+	e.GET("/api/v1/users/me").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", 0)
+
+	// 刷新令牌沿用同一会话
+	refreshed := e.POST("/api/v1/auth/refresh").
+		WithJSON(map[string]interface{}{"refreshToken": refreshToken}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object()
+	refreshed.ValueEqual("sessionId", sessionID)
+	token = refreshed.Value("token").String().Raw()
+	refreshToken = refreshed.Value("refreshToken").String().Raw()
+
+	// 登出结束会话，会话列表中不再出现，刷新令牌不能再用
+	e.POST("/api/v1/auth/logout").
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", 0)
+	e.POST("/api/v1/auth/refresh").
+		WithJSON(map[string]interface{}{"refreshToken": refreshToken}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", serializer.CodeCheckLogin)
+
+	second := loginToken(e, wallet)
+	e.GET("/api/v1/users/sessions").
+		WithHeader("Authorization", "Bearer "+second).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("sessions").Array().Length().Equal(1)
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"singo/model"
+	"singo/repository"
+	"singo/service"
+	"singo/util"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialSpectator 连接奖池的观战WebSocket，返回握手响应的状态码
+func dialSpectator(server *httptest.Server, poolID uint) (*websocket.Conn, int, error) {
+	url := wsURL(server, "/api/v1/pools/"+formatID(float64(poolID))+"/spectate")
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	return conn, status, err
+}
+
+// waitSpectators 等待奖池的观战人数变为want，断开连接由服务端异步注销
+func waitSpectators(t *testing.T, poolID uint, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for service.GetWebSocketManager().SpectatorCount(poolID) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("spectators = %d, want %d", service.GetWebSocketManager().SpectatorCount(poolID), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 观战无需登录，快照中的钱包地址被截断；观战人数达到上限后拒绝新的连接
+func TestSpectatorLimit(t *testing.T) {
+	t.Setenv("SPECTATOR_MAX_PER_POOL", "2")
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	holder := newTestWallet(t)
+	pool := model.NewPool()
+	pool.Status = model.PoolStatusActive
+	pool.CollectingSlot = nil
+	pool.CurrentBigPrizeHolder = holder.address
+	if err := repository.NewGorm(model.DB).Pools.Create(&pool); err != nil {
+		t.Fatal(err)
+	}
+
+	e := getHttpExpect(t)
+	e.GET("/api/v1/pools/"+formatID(float64(pool.ID))+"/snapshot").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("data").Object().
+		Value("pool").Object().
+		ValueEqual("bigPrizeHolder", util.TruncateWallet(holder.address)).
+		ValueEqual("spectatorCount", 0)
+
+	first, _, err := dialSpectator(server, pool.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, _, err := dialSpectator(server, pool.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	waitSpectators(t, pool.ID, 2)
+
+	if _, status, err := dialSpectator(server, pool.ID); err == nil || status != http.StatusTooManyRequests {
+		t.Fatalf("third spectator: status %d (%v)", status, err)
+	}
+
+	// 有观战者离开后可以再次连接
+	first.Close()
+	waitSpectators(t, pool.ID, 1)
+	third, _, err := dialSpectator(server, pool.ID)
+	if err != nil {
+		t.Fatalf("spectator after one left: %v", err)
+	}
+	defer third.Close()
+	waitSpectators(t, pool.ID, 2)
+
+	// 已结束的奖池不能观战
+	ended := model.NewPool()
+	ended.Status = model.PoolStatusCompleted
+	ended.CollectingSlot = nil
+	if err := repository.NewGorm(model.DB).Pools.Create(&ended); err != nil {
+		t.Fatal(err)
+	}
+	if _, status, err := dialSpectator(server, ended.ID); err == nil || status != http.StatusNotFound {
+		t.Fatalf("ended pool: status %d (%v)", status, err)
+	}
+}
@@ -0,0 +1,184 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"singo/model"
+	"singo/repository"
+	"singo/service"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent 解析出的一条SSE事件
+type sseEvent struct {
+	id        string
+	eventType string
+	data      string
+}
+
+// openEvents 打开SSE事件流，lastEventID非空时携带Last-Event-ID请求头
+func openEvents(t *testing.T, server *httptest.Server, token string, lastEventID string) (<-chan sseEvent, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/game/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	events := make(chan sseEvent, 64)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		var e sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if e.eventType != "" {
+					events <- e
+				}
+				e = sseEvent{}
+			case strings.HasPrefix(line, "id:"):
+				e.id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+			case strings.HasPrefix(line, "event:"):
+				e.eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				e.data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			}
+		}
+	}()
+	return events, cancel
+}
+
+// nextEvent 等待下一条指定类型的事件
+func nextEvent(t *testing.T, events <-chan sseEvent, eventType string) sseEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("stream closed while waiting for %s", eventType)
+			}
+			if e.eventType == eventType {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", eventType)
+		}
+	}
+}
+
+// 断线后携带Last-Event-ID重连，只补发错过的事件，不再发送初始状态
+func TestGameEventsResume(t *testing.T) {
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	token := loginToken(e, wallet)
+	user, err := service.GetUserService().GetByWallet(wallet.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frog := model.NewFrog(user.ID)
+	frog.HungerLevel = 90
+	if err := repository.NewGorm(model.DB).Frogs.Create(&frog); err != nil {
+		t.Fatal(err)
+	}
+	manager := service.GetWebSocketManager()
+
+	events, cancel := openEvents(t, server, token, "")
+	// 新连接先收到不带ID的初始状态
+	if initial := nextEvent(t, events, "hunger-update"); initial.id != "" || !strings.Contains(initial.data, `"newHungerLevel":90`) {
+		t.Fatalf("initial state = %+v", initial)
+	}
+	manager.BroadcastHungerUpdate(user.ID, frog.ID, 80)
+	seen := nextEvent(t, events, "hunger-update")
+	if seen.id == "" || !strings.Contains(seen.data, `"newHungerLevel":80`) {
+		t.Fatalf("first event = %+v", seen)
+	}
+	cancel()
+
+	// 断线期间的事件
+	manager.BroadcastHungerUpdate(user.ID, frog.ID, 70)
+	manager.BroadcastHungerUpdate(user.ID, frog.ID, 60)
+
+	events, cancel = openEvents(t, server, token, seen.id)
+	defer cancel()
+	missed := nextEvent(t, events, "hunger-update")
+	if !strings.Contains(missed.data, `"newHungerLevel":70`) {
+		t.Fatalf("first missed event = %+v", missed)
+	}
+	if missed := nextEvent(t, events, "hunger-update"); !strings.Contains(missed.data, `"newHungerLevel":60`) {
+		t.Fatalf("second missed event = %+v", missed)
+	}
+}
+
+// getEvents 以EventSource的方式请求事件流：不带Authorization头，通过 ?ticket= 传递票据
+func getEvents(t *testing.T, server *httptest.Server, ticket string, origin string) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/game/events?ticket="+ticket, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// 浏览器的EventSource以一次性连接票据认证；同源请求不带Origin，跨源请求的来源需与签发时一致
+func TestGameEventsTicket(t *testing.T) {
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := getHttpExpect(t)
+	token := loginToken(e, newTestWallet(t))
+	const origin = "http://localhost:3000"
+
+	isStream := func(resp *http.Response) bool {
+		defer resp.Body.Close()
+		return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	}
+
+	ticket := wsTicket(e, token, origin)
+	if !isStream(getEvents(t, server, ticket, origin)) {
+		t.Fatal("cross-origin ticket rejected")
+	}
+	if isStream(getEvents(t, server, ticket, origin)) {
+		t.Fatal("reused ticket accepted")
+	}
+
+	ticket = wsTicket(e, token, origin)
+	if !isStream(getEvents(t, server, ticket, "")) {
+		t.Fatal("same-origin ticket rejected")
+	}
+
+	ticket = wsTicket(e, token, origin)
+	if isStream(getEvents(t, server, ticket, "http://localhost:5173")) {
+		t.Fatal("ticket accepted from another origin")
+	}
+	if isStream(getEvents(t, server, "unknown", "")) {
+		t.Fatal("unknown ticket accepted")
+	}
+}
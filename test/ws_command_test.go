@@ -0,0 +1,96 @@
+package test
+
+import (
+	"net/http/httptest"
+	"singo/model"
+	"singo/repository"
+	"singo/serializer"
+	"singo/service"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// sendCommand 发送游戏指令并等待携带相同requestId的ack或error帧
+func sendCommand(t *testing.T, conn *websocket.Conn, command map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	if err := conn.WriteJSON(command); err != nil {
+		t.Fatal(err)
+	}
+	return readUntil(t, conn, func(msg map[string]interface{}) bool {
+		if msg["type"] != "ack" && msg["type"] != "error" {
+			return false
+		}
+		return msg["requestId"] == command["requestId"]
+	})
+}
+
+// WebSocket上的投喂与抓取大奖指令：成功返回ack帧，参数错误返回带错误码的error帧
+func TestWebSocketCommands(t *testing.T) {
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := getHttpExpect(t)
+	wallet := newTestWallet(t)
+	token := loginToken(e, wallet)
+
+	user, err := service.GetUserService().GetByWallet(wallet.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := dialGame(t, server, wsTicket(e, token, ""))
+	defer conn.Close()
+
+	// 没有激活的青蛙时投喂失败
+	res := sendCommand(t, conn, map[string]interface{}{"type": "feed", "requestId": "feed-0", "pizzaValue": 10})
+	if res["type"] != "error" || res["code"] != float64(serializer.CodeParamErr) {
+		t.Fatalf("feed without frog: %v", res)
+	}
+
+	frog := model.NewFrog(user.ID)
+	frog.HungerLevel = 50
+	if err := repository.NewGorm(model.DB).Frogs.Create(&frog); err != nil {
+		t.Fatal(err)
+	}
+
+	res = sendCommand(t, conn, map[string]interface{}{"type": "feed", "requestId": "feed-1", "pizzaValue": 10})
+	if res["type"] != "ack" {
+		t.Fatalf("feed: %v", res)
+	}
+	if data, _ := res["data"].(map[string]interface{}); data["newHungerLevel"] != float64(60) {
+		t.Fatalf("feed ack data: %v", res)
+	}
+
+	// 投喂值须为正数且不超过饥饿值上限
+	for i, value := range []interface{}{-5, 0, 101, 1e12, "10"} {
+		requestID := "bad-feed-" + formatID(float64(i))
+		res := sendCommand(t, conn, map[string]interface{}{"type": "feed", "requestId": requestID, "pizzaValue": value})
+		if res["type"] != "error" || res["code"] != float64(serializer.CodeParamErr) {
+			t.Fatalf("feed %v: %v", value, res)
+		}
+	}
+
+	// 抓取大奖须指定奖池，且青蛙须在奖池中
+	res = sendCommand(t, conn, map[string]interface{}{"type": "catch-prize", "requestId": "catch-0"})
+	if res["type"] != "error" || res["code"] != float64(serializer.CodeParamErr) {
+		t.Fatalf("catch without pool: %v", res)
+	}
+
+	res = sendCommand(t, conn, map[string]interface{}{"type": "jump", "requestId": "jump-0"})
+	if res["type"] != "error" || res["msg"] != "Unknown message type: jump" {
+		t.Fatalf("unknown command: %v", res)
+	}
+
+	// 缺少requestId的指令返回error帧，requestId为空
+	res = sendCommand(t, conn, map[string]interface{}{"type": "feed", "requestId": "", "pizzaValue": 10})
+	if res["type"] != "error" || res["msg"] != "requestId is required" {
+		t.Fatalf("missing requestId: %v", res)
+	}
+
+	// 失败的指令不影响后续指令
+	res = sendCommand(t, conn, map[string]interface{}{"type": "feed", "requestId": "feed-2", "pizzaValue": 100})
+	if data, _ := res["data"].(map[string]interface{}); res["type"] != "ack" || data["newHungerLevel"] != float64(model.MaxHungerLevel) {
+		t.Fatalf("feed after errors: %v", res)
+	}
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"net/http/httptest"
+	"singo/service"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// expectClose 读取消息直到连接关闭，校验关闭码
+func expectClose(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, code) {
+				t.Fatalf("expected close %d, got %v", code, err)
+			}
+			return
+		}
+	}
+}
+
+// 服务端响应ping控制帧与应用层ping消息
+func TestWebSocketPing(t *testing.T) {
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := getHttpExpect(t)
+	conn := dialGame(t, server, wsTicket(e, loginToken(e, newTestWallet(t)), ""))
+	defer conn.Close()
+
+	pongs := make(chan string, 1)
+	conn.SetPongHandler(func(appData string) error {
+		pongs <- appData
+		return nil
+	})
+	if err := conn.WriteControl(websocket.PingMessage, []byte("probe"), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	// 控制帧的pong在读取后续消息时由pong处理器接收
+	if err := conn.WriteJSON(map[string]string{"type": "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, conn, func(msg map[string]interface{}) bool { return msg["type"] == "pong" })
+
+	select {
+	case data := <-pongs:
+		if data != "probe" {
+			t.Fatalf("pong payload = %q", data)
+		}
+	default:
+		t.Fatal("no pong for the ping control frame")
+	}
+}
+
+// 超过心跳等待时间没有收到任何消息时以4003关闭，收到消息会延长等待时间
+func TestWebSocketHeartbeatTimeout(t *testing.T) {
+	t.Setenv("WS_PONG_WAIT", "500ms")
+	t.Setenv("WS_PING_INTERVAL", "400ms")
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := getHttpExpect(t)
+	conn := dialGame(t, server, wsTicket(e, loginToken(e, newTestWallet(t)), ""))
+	defer conn.Close()
+	// 客户端不回复ping控制帧，只由客户端消息维持连接
+	conn.SetPingHandler(func(string) error { return nil })
+
+	for i := 0; i < 5; i++ {
+		if err := conn.WriteJSON(map[string]string{"type": "ping"}); err != nil {
+			t.Fatal(err)
+		}
+		readUntil(t, conn, func(msg map[string]interface{}) bool { return msg["type"] == "pong" })
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	expectClose(t, conn, service.CloseHeartbeatTimeout)
+}
+
+// 同一用户建立新连接时旧连接以4001关闭，新连接不受影响
+func TestWebSocketReplaced(t *testing.T) {
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := getHttpExpect(t)
+	token := loginToken(e, newTestWallet(t))
+	first := dialGame(t, server, wsTicket(e, token, ""))
+	defer first.Close()
+	second := dialGame(t, server, wsTicket(e, token, ""))
+	defer second.Close()
+
+	expectClose(t, first, service.CloseReplaced)
+
+	if err := second.WriteJSON(map[string]string{"type": "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, second, func(msg map[string]interface{}) bool { return msg["type"] == "pong" })
+}
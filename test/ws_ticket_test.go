@@ -0,0 +1,74 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialGameStatus 使用票据与来源连接游戏WebSocket，返回握手响应的状态码
+func dialGameStatus(server *httptest.Server, query string, origin string) (*websocket.Conn, int) {
+	header := http.Header{}
+	if origin != "" {
+		header.Set("Origin", origin)
+	}
+	conn, resp, _ := websocket.DefaultDialer.Dial(wsURL(server, "/api/v1/game/ws?"+query), header)
+	if resp == nil {
+		return conn, 0
+	}
+	return conn, resp.StatusCode
+}
+
+// 票据只能使用一次，且只能从签发时的来源连接；URL中的访问令牌不被接受
+func TestWebSocketTicket(t *testing.T) {
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := getHttpExpect(t)
+	token := loginToken(e, newTestWallet(t))
+	const origin = "http://localhost:3000"
+
+	// 签发票据需要登录
+	e.POST("/api/v1/game/ws-ticket").
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		ValueEqual("code", 401)
+
+	ticket := wsTicket(e, token, origin)
+	conn, status := dialGameStatus(server, "ticket="+ticket, origin)
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("first use: status %d", status)
+	}
+	conn.Close()
+	if _, status := dialGameStatus(server, "ticket="+ticket, origin); status != http.StatusUnauthorized {
+		t.Fatalf("reused ticket: status %d", status)
+	}
+
+	// 来源不一致时拒绝，票据随之作废
+	ticket = wsTicket(e, token, origin)
+	if _, status := dialGameStatus(server, "ticket="+ticket, "http://localhost:5173"); status != http.StatusUnauthorized {
+		t.Fatalf("other origin: status %d", status)
+	}
+	if _, status := dialGameStatus(server, "ticket="+ticket, origin); status != http.StatusUnauthorized {
+		t.Fatalf("ticket after origin mismatch: status %d", status)
+	}
+	ticket = wsTicket(e, token, origin)
+	if _, status := dialGameStatus(server, "ticket="+ticket, ""); status != http.StatusUnauthorized {
+		t.Fatalf("missing origin: status %d", status)
+	}
+
+	// 不在白名单中的来源无法获取票据
+	e.POST("/api/v1/game/ws-ticket").
+		WithHeader("Authorization", "Bearer "+token).
+		WithHeader("Origin", "https://evil.example").
+		Expect().
+		Status(http.StatusForbidden)
+
+	for _, query := range []string{"ticket=unknown", "token=" + token, ""} {
+		if _, status := dialGameStatus(server, query, ""); status != http.StatusUnauthorized {
+			t.Fatalf("%q: status %d", query, status)
+		}
+	}
+}
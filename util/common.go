@@ -2,6 +2,7 @@ package util
 
 import (
 	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -16,3 +17,50 @@ func RandStringRunes(n int) string {
 	}
 	return string(b)
 }
+
+// TruncateWallet 截断钱包地址，仅保留首尾各4位，用于公开展示
+func TruncateWallet(address string) string {
+	if len(address) <= 8 {
+		return address
+	}
+	return address[:4] + "..." + address[len(address)-4:]
+}
+
+// DeviceFromUserAgent 从User-Agent粗略识别浏览器与操作系统，用于会话列表展示
+func DeviceFromUserAgent(userAgent string) string {
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	case userAgent != "":
+		browser = strings.SplitN(userAgent, "/", 2)[0]
+	}
+
+	os := "Unknown OS"
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		os = "iOS"
+	case strings.Contains(userAgent, "Android"):
+		os = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		os = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"):
+		os = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		os = "Linux"
+	}
+
+	device := browser + " on " + os
+	if len(device) > 64 {
+		device = device[:64]
+	}
+	return device
+}